@@ -419,6 +419,10 @@ func completionTaskTargets(project *projects.Project, contextName, toComplete st
 
 	for _, taskName := range project.Tasks.Keys() {
 		task, _ := project.Tasks.Get(taskName)
+		if task.Private {
+			continue
+		}
+
 		description := ""
 		if task.Desc != nil {
 			description = strings.TrimSpace(*task.Desc)
@@ -569,6 +573,9 @@ func knownProjectContexts(schema *types.Project) []string {
 			add(ctx)
 		}
 	}
+	for name := range schema.Contexts {
+		add(name)
+	}
 	sort.Strings(contexts)
 	return contexts
 }
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/frostyeti/cast/internal/errors"
+	"github.com/frostyeti/cast/internal/projects"
+	"github.com/frostyeti/go/env"
+	"github.com/spf13/cobra"
+)
+
+const maskedEnvValue = "****"
+
+var envCmd = &cobra.Command{
+	Use:   "env [task]",
+	Short: "Show the resolved environment for the project or a task",
+	Long: `Show the fully resolved environment a task would receive, applying the same
+layering as a run: process env, module and project paths, dotenv, env, then
+task dotenv and env. Each variable lists the layer and file that set it and
+any earlier layers it overrides. Secret values are masked.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: provideProjectCompletion,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		projectFile, err := resolveProjectFileFromFlagOrCwd(cmd)
+		if err != nil {
+			return err
+		}
+
+		contextName := resolveDefaultContextName(cmd, projectFile)
+
+		project := &projects.Project{}
+		if err := project.LoadFromYaml(projectFile); err != nil {
+			return errors.Newf("failed to load project file %s: %w", projectFile, err)
+		}
+		project.ContextName = contextName
+
+		taskName := ""
+		if len(args) > 0 {
+			taskName = args[0]
+		}
+
+		overrides, _ := cmd.Flags().GetStringToString("env")
+		entries, err := project.ResolveEnv(taskName, overrides)
+		if err != nil {
+			return errors.Newf("failed to resolve env for project %s: %w", projectFile, err)
+		}
+
+		for i := range entries {
+			entry := &entries[i]
+			if !entry.Secret && !projects.LooksSecret(entry.Name) {
+				continue
+			}
+
+			entry.Secret = true
+			entry.Value = maskedEnvValue
+			entry.Source.Value = maskedEnvValue
+			for j := range entry.Overridden {
+				entry.Overridden[j].Value = maskedEnvValue
+			}
+		}
+
+		asJSON, _ := cmd.Flags().GetBool("json")
+		if asJSON {
+			out := map[string]any{
+				"project": projectFile,
+				"context": contextName,
+				"task":    taskName,
+				"env":     entries,
+			}
+			data, err := json.MarshalIndent(out, "", "  ")
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintln(cmd.OutOrStdout(), string(data))
+			return nil
+		}
+
+		dir := filepath.Dir(projectFile)
+		for _, entry := range entries {
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s=%s  # %s\n", entry.Name, entry.Value, formatEnvSource(entry.Source, dir))
+			if len(entry.Overridden) == 0 {
+				continue
+			}
+
+			overridden := make([]string, 0, len(entry.Overridden))
+			for i := len(entry.Overridden) - 1; i >= 0; i-- {
+				source := entry.Overridden[i]
+				overridden = append(overridden, fmt.Sprintf("%s (%s)", formatEnvSource(source, dir), source.Value))
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "    overrides %s\n", strings.Join(overridden, "; "))
+		}
+
+		return nil
+	},
+}
+
+func formatEnvSource(source projects.EnvSource, dir string) string {
+	if source.File == "" {
+		return source.Layer
+	}
+
+	return source.Layer + " " + relativeToDir(source.File, dir)
+}
+
+func init() {
+	rootCmd.AddCommand(envCmd)
+	project := env.Get("CAST_PROJECT")
+	context := env.Get("CAST_CONTEXT")
+	envCmd.Flags().StringP("project", "p", project, "Path to the project file (castfile.yaml)")
+	envCmd.Flags().StringP("context", "c", context, "Context name to use from the project")
+	envCmd.Flags().StringToStringP("env", "e", map[string]string{}, "List of environment variables to set")
+	envCmd.Flags().Bool("json", false, "Print the resolved environment as JSON")
+	_ = envCmd.RegisterFlagCompletionFunc("project", provideProjectFlagCompletion)
+	_ = envCmd.RegisterFlagCompletionFunc("context", provideContextFlagCompletion)
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnvCommandShowsProvenanceAndMasksSecrets(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectFile := filepath.Join(tmpDir, "castfile")
+	content := `name: demo
+dotenv:
+  - .env
+env:
+  - API_URL=https://api.${APP_ENV}.example.com
+  - DB_PASSWORD:hunter2
+tasks:
+  deploy:
+    env:
+      APP_ENV: staging
+    run: echo deploy
+`
+	if err := os.WriteFile(projectFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("write castfile: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, ".env"), []byte("APP_ENV=dev\n"), 0o644); err != nil {
+		t.Fatalf("write dotenv: %v", err)
+	}
+
+	out, err := executeRootForTest([]string{"env", "-p", projectFile, "deploy"}, "")
+	if err != nil {
+		t.Fatalf("env command failed: %v\n%s", err, out)
+	}
+
+	for _, want := range []string{
+		"API_URL=https://api.dev.example.com  # env castfile",
+		"APP_ENV=staging  # task-env castfile",
+		"    overrides dotenv .env (dev)",
+		"DB_PASSWORD=****  # env castfile",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+
+	if strings.Contains(out, "hunter2") {
+		t.Fatalf("expected secret value to be masked, got:\n%s", out)
+	}
+}
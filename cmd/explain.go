@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/frostyeti/cast/internal/errors"
+	"github.com/frostyeti/cast/internal/projects"
+	"github.com/frostyeti/cast/internal/types"
+	"github.com/frostyeti/go/env"
+	"github.com/spf13/cobra"
+	"go.yaml.in/yaml/v4"
+)
+
+var explainCmd = &cobra.Command{
+	Use:   "explain <task>",
+	Short: "Show how a task name resolves to what will run",
+	Long: `Show the resolution chain for a task without running it: the file and line
+that defined it, the context variant selected, the module and namespace that
+imported it, the tasks it extends in the order they are applied, and the
+handler, fallback file, or remote cache path that will execute it.
+
+Use --merged to print the task definition after extends and project defaults
+have been merged, as YAML.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: provideProjectCompletion,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		projectFile, err := resolveProjectFileFromFlagOrCwd(cmd)
+		if err != nil {
+			return err
+		}
+
+		project := &projects.Project{}
+		if err := project.LoadFromYaml(projectFile); err != nil {
+			return errors.Newf("failed to load project file %s: %w", projectFile, err)
+		}
+		project.ContextName = resolveDefaultContextName(cmd, projectFile)
+
+		explanation, err := project.ExplainTask(args[0])
+		if err != nil {
+			return err
+		}
+
+		merged, _ := cmd.Flags().GetBool("merged")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		if merged && !asJSON {
+			return writeMergedTask(cmd.OutOrStdout(), explanation)
+		}
+
+		if asJSON {
+			data, err := json.MarshalIndent(explanation, "", "  ")
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintln(cmd.OutOrStdout(), string(data))
+			return nil
+		}
+
+		writeTaskExplanation(cmd.OutOrStdout(), explanation, filepath.Dir(projectFile))
+		return nil
+	},
+}
+
+func writeTaskExplanation(w io.Writer, ex *projects.TaskExplanation, dir string) {
+	_, _ = fmt.Fprintf(w, "task:     %s\n", ex.Target)
+	if ex.Context != "" {
+		_, _ = fmt.Fprintf(w, "context:  %s\n", ex.Context)
+	}
+	if ex.Variant != "" {
+		_, _ = fmt.Fprintf(w, "variant:  %s\n", ex.Variant)
+	}
+
+	_, _ = fmt.Fprintf(w, "defined:  %s\n", formatTaskOrigin(ex.Task, dir))
+
+	if ex.Module != nil {
+		module := relativeToDir(ex.Module.File, dir)
+		if ex.Module.From != "" {
+			module = ex.Module.From + " (" + module + ")"
+		}
+		_, _ = fmt.Fprintf(w, "module:   %s\n", module)
+		if ex.Module.Namespace != "" {
+			_, _ = fmt.Fprintf(w, "namespace: %s\n", ex.Module.Namespace)
+		}
+	}
+
+	for _, base := range ex.Extends {
+		_, _ = fmt.Fprintf(w, "extends:  %s %s\n", base.Name, formatTaskOrigin(base, dir))
+	}
+
+	handler := ex.Handler
+	_, _ = fmt.Fprintf(w, "uses:     %s\n", handler.Uses)
+	_, _ = fmt.Fprintf(w, "handler:  %s\n", handler.Kind)
+	if handler.Version != "" {
+		_, _ = fmt.Fprintf(w, "version:  %s\n", handler.Version)
+	}
+	if handler.Path != "" {
+		state := "cached"
+		if !handler.Cached {
+			state = "not cached"
+		}
+		if handler.Kind == "fallback" || handler.Kind == "local" {
+			state = "exists"
+			if !handler.Cached {
+				state = "missing"
+			}
+		}
+		_, _ = fmt.Fprintf(w, "path:     %s (%s)\n", relativeToDir(handler.Path, dir), state)
+	}
+}
+
+// writeMergedTask prints the merged definition as a castfile tasks entry.
+// Extends and merge strategies are left out because they are already applied.
+func writeMergedTask(w io.Writer, ex *projects.TaskExplanation) error {
+	def := ex.Definition
+	def.Id = ""
+	def.Name = ""
+	def.Extends = nil
+	def.Merge = nil
+	if def.Env.Len() == 0 {
+		def.Env = nil
+	}
+	if def.With.Len() == 0 {
+		def.With = nil
+	}
+	if def.Vars.Len() == 0 {
+		def.Vars = nil
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(map[string]types.Task{ex.Task.Name: def}); err != nil {
+		return err
+	}
+
+	return encoder.Close()
+}
+
+func formatTaskOrigin(origin projects.TaskOrigin, dir string) string {
+	if origin.File == "" {
+		return "(unknown)"
+	}
+
+	file := relativeToDir(origin.File, dir)
+	if origin.Line > 0 {
+		return fmt.Sprintf("%s:%d:%d", file, origin.Line, origin.Column)
+	}
+
+	return file
+}
+
+func relativeToDir(file, dir string) string {
+	if rel, err := filepath.Rel(dir, file); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+
+	return file
+}
+
+func init() {
+	rootCmd.AddCommand(explainCmd)
+	project := env.Get("CAST_PROJECT")
+	context := env.Get("CAST_CONTEXT")
+	explainCmd.Flags().StringP("project", "p", project, "Path to the project file (castfile.yaml)")
+	explainCmd.Flags().StringP("context", "c", context, "Context name to use from the project")
+	explainCmd.Flags().Bool("json", false, "Print the explanation as JSON")
+	explainCmd.Flags().Bool("merged", false, "Print the fully merged task definition as YAML")
+	_ = explainCmd.RegisterFlagCompletionFunc("project", provideProjectFlagCompletion)
+	_ = explainCmd.RegisterFlagCompletionFunc("context", provideContextFlagCompletion)
+}
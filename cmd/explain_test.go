@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExplainCommandShowsResolutionChain(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectFile := filepath.Join(tmpDir, "castfile")
+	content := `name: demo
+tasks:
+  base:
+    uses: lint-js
+  lint:
+    extends: base
+  lint:ci:
+    extends: lint
+`
+	if err := os.WriteFile(projectFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("write castfile: %v", err)
+	}
+
+	fallback := filepath.Join(tmpDir, ".cast", "tasks", "lint-js.yaml")
+	if err := os.MkdirAll(filepath.Dir(fallback), 0o755); err != nil {
+		t.Fatalf("mkdir fallback: %v", err)
+	}
+	if err := os.WriteFile(fallback, []byte("name: lint-js\n"), 0o644); err != nil {
+		t.Fatalf("write fallback: %v", err)
+	}
+
+	out, err := executeRootForTest([]string{"explain", "-p", projectFile, "-c", "ci", "lint"}, "")
+	if err != nil {
+		t.Fatalf("explain command failed: %v\n%s", err, out)
+	}
+
+	for _, want := range []string{
+		"task:     lint",
+		"context:  ci",
+		"variant:  lint:ci",
+		"defined:  castfile:7:3",
+		"extends:  lint castfile:5:3",
+		"extends:  base castfile:3:3",
+		"uses:     lint-js",
+		"handler:  fallback",
+		"path:     " + filepath.Join(".cast", "tasks", "lint-js.yaml") + " (exists)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestExplainCommandPrintsMergedDefinition(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectFile := filepath.Join(tmpDir, "castfile")
+	content := `name: demo
+tasks:
+  base:
+    uses: bash
+    timeout: 5m
+    env:
+      LEVEL: base
+  build:
+    extends: base
+    env:
+      EXTRA: "1"
+    run: make
+`
+	if err := os.WriteFile(projectFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("write castfile: %v", err)
+	}
+
+	out, err := executeRootForTest([]string{"explain", "-p", projectFile, "--merged", "build"}, "")
+	if err != nil {
+		t.Fatalf("explain command failed: %v\n%s", err, out)
+	}
+
+	for _, want := range []string{"build:", "timeout: 5m", "LEVEL: base", "EXTRA: \"1\"", "run: make", "uses: bash"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "extends:") {
+		t.Fatalf("expected extends to be omitted from the merged definition, got:\n%s", out)
+	}
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/frostyeti/cast/internal/errors"
+	"github.com/frostyeti/cast/internal/projects"
+	"github.com/frostyeti/cast/internal/types"
+	"github.com/frostyeti/go/env"
+	"github.com/spf13/cobra"
+)
+
+var fmtCmd = &cobra.Command{
+	Use:   "fmt [files...]",
+	Short: "Rewrite cast files into the canonical layout",
+	Long: `Rewrite castfiles, modules and inventories into a canonical layout: keys are
+sorted into a stable order, aliases such as deps and description are renamed to
+needs and desc, and indentation is normalized to two spaces. Comments are kept.
+
+Without arguments the project castfile is formatted along with the inventory
+files and module imports it references by a relative path.
+
+Use --check in CI to list files that are not formatted; the command exits
+non-zero when any file would change.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		files := args
+		dir, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+
+		if len(files) == 0 {
+			projectFile, err := resolveProjectFileFromFlagOrCwd(cmd)
+			if err != nil {
+				return err
+			}
+
+			files, err = projects.LocalFiles(projectFile)
+			if err != nil {
+				return errors.Newf("failed to load project file %s: %w", projectFile, err)
+			}
+			dir = filepath.Dir(projectFile)
+		}
+
+		check, _ := cmd.Flags().GetBool("check")
+		unformatted := 0
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+
+			formatted, err := types.Format(data)
+			if err != nil {
+				return errors.Newf("failed to format %s: %w", file, err)
+			}
+
+			if bytes.Equal(data, formatted) {
+				continue
+			}
+
+			unformatted++
+			_, _ = fmt.Fprintln(cmd.OutOrStdout(), relativeToDir(file, dir))
+			if check {
+				continue
+			}
+
+			info, err := os.Stat(file)
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(file, formatted, info.Mode().Perm()); err != nil {
+				return err
+			}
+		}
+
+		if check && unformatted > 0 {
+			return errors.Newf("%d file(s) are not formatted", unformatted)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(fmtCmd)
+	project := env.Get("CAST_PROJECT")
+	fmtCmd.Flags().StringP("project", "p", project, "Path to the project file (castfile.yaml)")
+	fmtCmd.Flags().Bool("check", false, "List unformatted files and exit non-zero instead of rewriting them")
+	_ = fmtCmd.RegisterFlagCompletionFunc("project", provideProjectFlagCompletion)
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFmtCommandCheckAndRewrite(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectFile := filepath.Join(tmpDir, "castfile")
+	inventoryFile := filepath.Join(tmpDir, "hosts.yaml")
+	content := `tasks:
+  build:
+    run: echo build
+    deps: [lint]
+  lint: echo lint
+name: demo
+inventories:
+  - ./hosts.yaml
+`
+	if err := os.WriteFile(projectFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("write castfile: %v", err)
+	}
+	if err := os.WriteFile(inventoryFile, []byte("hosts:\n  web:\n    host: web.local\n"), 0o644); err != nil {
+		t.Fatalf("write inventory: %v", err)
+	}
+
+	out, err := executeRootForTest([]string{"fmt", "-p", projectFile, "--check"}, "")
+	if err == nil {
+		t.Fatalf("expected fmt --check to fail, got:\n%s", out)
+	}
+	if !strings.Contains(out, "castfile") || strings.Contains(out, "hosts.yaml") {
+		t.Fatalf("expected only castfile to be listed, got:\n%s", out)
+	}
+
+	data, _ := os.ReadFile(projectFile)
+	if string(data) != content {
+		t.Fatalf("--check must not rewrite files, got:\n%s", data)
+	}
+
+	if out, err := executeRootForTest([]string{"fmt", "-p", projectFile, "--check=false"}, ""); err != nil {
+		t.Fatalf("fmt failed: %v\n%s", err, out)
+	}
+
+	data, _ = os.ReadFile(projectFile)
+	want := `name: demo
+inventories:
+  - ./hosts.yaml
+tasks:
+  build:
+    needs: [lint]
+    run: echo build
+  lint: echo lint
+`
+	if string(data) != want {
+		t.Fatalf("unexpected formatted castfile:\n%s", data)
+	}
+
+	if out, err := executeRootForTest([]string{"fmt", "-p", projectFile, "--check"}, ""); err != nil {
+		t.Fatalf("expected formatted project to pass --check: %v\n%s", err, out)
+	}
+}
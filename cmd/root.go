@@ -8,6 +8,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/frostyeti/cast/internal/offline"
 	"github.com/frostyeti/cast/internal/projects"
 	"github.com/frostyeti/go/env"
 	"github.com/spf13/cobra"
@@ -16,12 +17,18 @@ import (
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:               "cast",
-	Version:           "0.2.0-alpha.4",
+	Version:           projects.CastVersion,
 	Short:             "Cast is a task runner and automation tool",
 	Long:              "Cast is a task runner and automation tool",
 	Args:              cobra.ArbitraryArgs,
 	ValidArgsFunction: provideProjectCompletion,
 	RunE:              tasksRunCmd.RunE,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if enabled, _ := cmd.Flags().GetBool("offline"); enabled {
+			return offline.Enable()
+		}
+		return nil
+	},
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
@@ -58,6 +65,8 @@ func init() {
 	rootCmd.Flags().StringP("context", "c", context, "Context name to use from the project")
 	rootCmd.Flags().StringArrayP("dotenv", "E", []string{}, "List of dotenv files to load")
 	rootCmd.Flags().StringToStringP("env", "e", map[string]string{}, "List of environment variables to set")
+	rootCmd.Flags().Bool("frozen", false, "Fail when remote tasks or modules differ from cast.lock")
+	rootCmd.PersistentFlags().Bool("offline", false, "Resolve remote tasks, modules and tools only from local caches (or set CAST_OFFLINE=1)")
 	_ = rootCmd.RegisterFlagCompletionFunc("project", provideProjectFlagCompletion)
 	_ = rootCmd.RegisterFlagCompletionFunc("context", provideContextFlagCompletion)
 }
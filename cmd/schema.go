@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/frostyeti/cast/internal/types"
+	"github.com/spf13/cobra"
+)
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema [castfile|task|module|inventory]",
+	Short: "Print the JSON schema for cast files",
+	Long: `Print the JSON schema for a castfile, a cast.task remote task, a module, or a
+standalone inventory file. The schema is generated from the same field and
+alias declarations the parser uses, including scalar shorthands, so editor
+validation matches what cast accepts.
+
+Use --write <dir> to write every schema into a directory such as schemas/.`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: types.SchemaKinds,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, _ := cmd.Flags().GetString("write")
+		if dir != "" {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return err
+			}
+
+			for _, kind := range types.SchemaKinds {
+				data, err := marshalSchema(kind)
+				if err != nil {
+					return err
+				}
+
+				file := filepath.Join(dir, types.SchemaFile(kind))
+				if err := os.WriteFile(file, data, 0o644); err != nil {
+					return err
+				}
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "wrote %s\n", file)
+			}
+			return nil
+		}
+
+		kind := "castfile"
+		if len(args) > 0 {
+			kind = strings.ToLower(args[0])
+		}
+
+		data, err := marshalSchema(kind)
+		if err != nil {
+			return err
+		}
+
+		_, _ = cmd.OutOrStdout().Write(data)
+		return nil
+	},
+}
+
+func marshalSchema(kind string) ([]byte, error) {
+	schema, err := types.JSONSchema(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(data, '\n'), nil
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+	schemaCmd.Flags().String("write", "", "Write every schema into this directory instead of printing one")
+}
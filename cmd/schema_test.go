@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSchemaCommandPrintsRequestedKind(t *testing.T) {
+	out, err := executeRootForTest([]string{"schema", "module"}, "")
+	if err != nil {
+		t.Fatalf("schema command failed: %v\n%s", err, out)
+	}
+
+	var schema map[string]any
+	if err := json.Unmarshal([]byte(out), &schema); err != nil {
+		t.Fatalf("expected json schema, got %v:\n%s", err, out)
+	}
+
+	if schema["$id"] != "cast.module.schema.json" {
+		t.Fatalf("expected module schema id, got %v", schema["$id"])
+	}
+
+	properties, _ := schema["properties"].(map[string]any)
+	if _, ok := properties["desc"]; !ok {
+		t.Fatalf("expected module schema to include the desc alias, got %v", properties)
+	}
+}
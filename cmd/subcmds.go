@@ -150,6 +150,10 @@ func buildSubcmdTree(schema *types.Project) *subcmdNode {
 	}
 
 	for _, taskName := range schema.Tasks.Keys() {
+		if task, _ := schema.Tasks.Get(taskName); task.Private {
+			continue
+		}
+
 		matched := false
 		for _, raw := range schema.Subcmds {
 			normalized := normalizeSubcmdPath(raw)
@@ -433,6 +437,10 @@ func runTaskByID(cmd *cobra.Command, projectFile, taskID string, taskArgs []stri
 		return errors.Newf("failed to initialize project %s: %w", projectFile, err)
 	}
 
+	if err := project.CheckPublicTargets([]string{taskID}, contextName); err != nil {
+		return err
+	}
+
 	if shellTaskNeedsCastContext(project, taskID) {
 		oldCtx, hadCtx := os.LookupEnv("CAST_CONTEXT")
 		_ = os.Setenv("CAST_CONTEXT", contextName)
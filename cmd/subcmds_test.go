@@ -25,6 +25,7 @@ func TestBuildSubcmdTree(t *testing.T) {
 	schema.Tasks.Set(&types.Task{Id: "dn:publish", Name: "dn:publish", Uses: ptrString("shell"), Run: ptrString("echo publish")})
 	schema.Tasks.Set(&types.Task{Id: "dn:help", Name: "dn:help", Help: ptrString("dn help text"), Uses: ptrString("shell"), Run: ptrString("echo ignored")})
 	schema.Tasks.Set(&types.Task{Id: "dn:nuget:pack", Name: "dn:nuget:pack", Uses: ptrString("shell"), Run: ptrString("echo pack")})
+	schema.Tasks.Set(&types.Task{Id: "dn:restore", Name: "dn:restore", Private: true, Uses: ptrString("shell"), Run: ptrString("echo restore")})
 
 	rootNode := buildSubcmdTree(schema)
 	if rootNode == nil {
@@ -42,6 +43,9 @@ func TestBuildSubcmdTree(t *testing.T) {
 	if dnNode.tasks["publish"] != "dn:publish" {
 		t.Fatalf("expected dn:publish task mapping")
 	}
+	if _, ok := dnNode.tasks["restore"]; ok {
+		t.Fatalf("expected private dn:restore task to be hidden")
+	}
 
 	nugetNode, ok := dnNode.children["nuget"]
 	if !ok {
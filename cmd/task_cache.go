@@ -0,0 +1,276 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/frostyeti/cast/internal/errors"
+	"github.com/frostyeti/cast/internal/projects"
+	"github.com/spf13/cobra"
+)
+
+var taskCacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Inspect and prune cached remote tasks and modules",
+}
+
+var taskCacheLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List cached remote tasks and modules",
+	Long: `List the remote tasks and modules in the stable and volatile caches with
+their size, ref, resolved version, commit and the time they were last used,
+least recently used first. Entries fetched by an older cast show their path
+and modification time until they are used again.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		projectDir, err := cacheProjectDirForCmd(cmd)
+		if err != nil {
+			return err
+		}
+
+		entries, err := projects.ListCache(projectDir)
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		asJSON, _ := cmd.Flags().GetBool("json")
+		if asJSON {
+			data, err := json.MarshalIndent(entries, "", "  ")
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintln(out, string(data))
+			return nil
+		}
+
+		if len(entries) == 0 {
+			_, _ = fmt.Fprintln(out, "No cached remote tasks or modules")
+			return nil
+		}
+
+		var total int64
+		rows := [][]string{{"SCOPE", "KIND", "REF", "RESOLVED", "COMMIT", "SIZE", "LAST USED"}}
+		for _, entry := range entries {
+			total += entry.Size
+			rows = append(rows, []string{
+				entry.Scope,
+				entry.Kind,
+				cacheEntryName(entry),
+				valueOrDash(entry.Resolved),
+				valueOrDash(shortCommit(entry.Commit)),
+				formatByteSize(entry.Size),
+				entry.LastUsed.Local().Format("2006-01-02 15:04"),
+			})
+		}
+
+		writeTable(out, rows)
+		_, _ = fmt.Fprintf(out, "\n%d entries, %s\n", len(entries), formatByteSize(total))
+		return nil
+	},
+}
+
+var taskCachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Evict least recently used remote tasks and modules",
+	Long: `Remove cached remote tasks and modules from the stable and volatile caches.
+Entries not used within --older-than are removed first, then the least
+recently used entries until the caches fit in --max-size. Sizes take a
+B, K, M, G or T suffix in powers of 1024, and ages a d (days) or w (weeks)
+suffix or any Go duration such as 12h.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := projects.PruneCacheOptions{}
+
+		maxSize, _ := cmd.Flags().GetString("max-size")
+		if maxSize != "" {
+			size, err := parseByteSize(maxSize)
+			if err != nil {
+				return err
+			}
+			opts.MaxSize = size
+		}
+
+		olderThan, _ := cmd.Flags().GetString("older-than")
+		if olderThan != "" {
+			age, err := parseAge(olderThan)
+			if err != nil {
+				return err
+			}
+			opts.OlderThan = age
+		}
+
+		if opts.MaxSize <= 0 && opts.OlderThan <= 0 {
+			return errors.New("pass --max-size or --older-than to bound the cache; use `cast task clear-cache` to remove everything")
+		}
+
+		opts.DryRun, _ = cmd.Flags().GetBool("dry-run")
+
+		projectDir, err := cacheProjectDirForCmd(cmd)
+		if err != nil {
+			return err
+		}
+
+		evicted, err := projects.PruneCache(projectDir, opts)
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		verb := "Removed"
+		if opts.DryRun {
+			verb = "Would remove"
+		}
+
+		var freed int64
+		for _, entry := range evicted {
+			freed += entry.Size
+			_, _ = fmt.Fprintf(out, "%s %s %s (%s, last used %s)\n", verb, entry.Scope, cacheEntryName(entry), formatByteSize(entry.Size), entry.LastUsed.Local().Format("2006-01-02"))
+		}
+
+		if len(evicted) == 0 {
+			_, _ = fmt.Fprintln(out, "Nothing to prune")
+			return nil
+		}
+
+		_, _ = fmt.Fprintf(out, "%s %d entries, %s\n", verb, len(evicted), formatByteSize(freed))
+		return nil
+	},
+}
+
+func cacheProjectDirForCmd(cmd *cobra.Command) (string, error) {
+	projectFile, err := resolveProjectFileFromFlagOrCwd(cmd)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Dir(projectFile), nil
+}
+
+// cacheEntryName returns the ref of entry, or its path when no fetch has
+// recorded one yet.
+func cacheEntryName(entry projects.CacheEntry) string {
+	if entry.Ref != "" {
+		return entry.Ref
+	}
+
+	return entry.Dir
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+
+	return commit
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
+
+// writeTable writes rows as left aligned columns separated by two spaces.
+func writeTable(out io.Writer, rows [][]string) {
+	if len(rows) == 0 {
+		return
+	}
+
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(row)-1 {
+				_, _ = fmt.Fprintln(out, cell)
+				continue
+			}
+			_, _ = fmt.Fprintf(out, "%-*s  ", widths[i], cell)
+		}
+	}
+}
+
+var byteSizeUnits = []string{"B", "K", "M", "G", "T"}
+
+// parseByteSize parses sizes such as 500M, 2G or 1.5GiB in powers of 1024.
+// A number without a unit is a count of bytes.
+func parseByteSize(value string) (int64, error) {
+	text := strings.ToUpper(strings.TrimSpace(value))
+	text = strings.TrimSuffix(strings.TrimSuffix(text, "IB"), "B")
+
+	multiplier := int64(1)
+	for i, unit := range byteSizeUnits[1:] {
+		if trimmed, ok := strings.CutSuffix(text, unit); ok {
+			text = trimmed
+			multiplier = int64(1) << (10 * (i + 1))
+			break
+		}
+	}
+
+	number, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil || number < 0 {
+		return 0, errors.Newf("invalid size %q; use a number with an optional B, K, M, G or T suffix", value)
+	}
+
+	return int64(number * float64(multiplier)), nil
+}
+
+// parseAge parses durations such as 30d or 2w, or any Go duration.
+func parseAge(value string) (time.Duration, error) {
+	text := strings.TrimSpace(value)
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if trimmed, ok := strings.CutSuffix(text, suffix); ok {
+			number, err := strconv.ParseFloat(trimmed, 64)
+			if err != nil || number < 0 {
+				return 0, errors.Newf("invalid age %q; use a number of days (30d), weeks (2w) or a duration such as 12h", value)
+			}
+			return time.Duration(number * float64(unit)), nil
+		}
+	}
+
+	age, err := time.ParseDuration(text)
+	if err != nil || age < 0 {
+		return 0, errors.Newf("invalid age %q; use a number of days (30d), weeks (2w) or a duration such as 12h", value)
+	}
+
+	return age, nil
+}
+
+// formatByteSize writes size with the largest unit that keeps it at or above
+// one, in powers of 1024.
+func formatByteSize(size int64) string {
+	value := float64(size)
+	unit := 0
+	for value >= 1024 && unit < len(byteSizeUnits)-1 {
+		value /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%dB", size)
+	}
+
+	return fmt.Sprintf("%.1f%s", value, byteSizeUnits[unit])
+}
+
+func init() {
+	taskCmd.AddCommand(taskCacheCmd)
+	taskCacheCmd.AddCommand(taskCacheLsCmd)
+	taskCacheCmd.AddCommand(taskCachePruneCmd)
+
+	taskCacheLsCmd.Flags().Bool("json", false, "Print the cache entries as JSON")
+	taskCachePruneCmd.Flags().String("max-size", "", "Evict least recently used entries until the caches fit in this size, such as 2G")
+	taskCachePruneCmd.Flags().String("older-than", "", "Evict entries not used within this age, such as 30d")
+	taskCachePruneCmd.Flags().Bool("dry-run", false, "Print the entries that would be evicted without removing them")
+}
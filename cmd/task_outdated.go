@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/frostyeti/cast/internal/errors"
+	"github.com/frostyeti/cast/internal/integrity"
+	"github.com/frostyeti/cast/internal/projects"
+	"github.com/spf13/cobra"
+)
+
+var taskOutdatedCmd = &cobra.Command{
+	Use:   "outdated",
+	Short: "List remote task and module refs with newer versions",
+	Long: `Scan the uses values and imports of the castfile, its includes and its local
+modules for refs pinned to a version, and report the newest version within
+the same major (wanted) and overall (latest). Versions come from the
+repository tags, through git ls-remote, or from the spell registry.
+Branch, commit and HEAD refs are skipped.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		project, updates, err := findRefUpdatesForCmd(cmd)
+		if err != nil {
+			return err
+		}
+
+		asJSON, _ := cmd.Flags().GetBool("json")
+		if asJSON {
+			type entry struct {
+				projects.RefUpdate
+				Outdated bool `json:"outdated"`
+			}
+			entries := make([]entry, 0, len(updates))
+			for _, update := range updates {
+				entries = append(entries, entry{RefUpdate: update, Outdated: update.Outdated()})
+			}
+			data, err := json.MarshalIndent(entries, "", "  ")
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintln(cmd.OutOrStdout(), string(data))
+			return nil
+		}
+
+		all, _ := cmd.Flags().GetBool("all")
+		rows := [][]string{{"REF", "CURRENT", "WANTED", "LATEST", "LOCATION"}}
+		failed := 0
+		for _, update := range updates {
+			location := refUpdateLocation(project, update)
+			switch {
+			case update.Error != "":
+				failed++
+				rows = append(rows, []string{update.Ref, update.Current, "?", "?", location})
+			case update.Outdated() || all:
+				rows = append(rows, []string{update.Ref, update.Current, update.Wanted, update.Latest, location})
+			}
+		}
+
+		out := cmd.OutOrStdout()
+		if len(rows) == 1 {
+			_, _ = fmt.Fprintf(out, "All %d versioned refs are up to date\n", len(updates))
+			return nil
+		}
+
+		writeTable(out, rows)
+
+		if failed > 0 {
+			_, _ = fmt.Fprintln(out)
+			for _, update := range updates {
+				if update.Error != "" {
+					_, _ = fmt.Fprintf(out, "%s: %s\n", update.Ref, update.Error)
+				}
+			}
+		}
+
+		return nil
+	},
+}
+
+var taskUpgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Rewrite remote task and module refs to newer versions",
+	Long: `Move every ref reported by ` + "`cast task outdated`" + ` to the newest version in its
+major, or to the newest version overall with --major. Only the ref text is
+rewritten, so comments and formatting are kept, and a version written as
+@v1.2 stays at that precision. Integrity pins are dropped because they cannot
+match the new version; re-pin after reviewing it.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		project, updates, err := findRefUpdatesForCmd(cmd)
+		if err != nil {
+			return err
+		}
+
+		major, _ := cmd.Flags().GetBool("major")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		applied := []projects.RefUpdate{}
+		if dryRun {
+			for _, update := range updates {
+				if update.Outdated() && update.Target(major) != update.Current {
+					applied = append(applied, update)
+				}
+			}
+		} else {
+			applied, err = projects.ApplyRefUpdates(updates, major)
+			if err != nil {
+				return err
+			}
+		}
+
+		out := cmd.OutOrStdout()
+		if len(applied) == 0 {
+			_, _ = fmt.Fprintln(out, "No refs to upgrade")
+			if !major {
+				for _, update := range updates {
+					if update.Outdated() {
+						_, _ = fmt.Fprintln(out, "Newer major versions are available; pass --major to upgrade to them")
+						break
+					}
+				}
+			}
+			return nil
+		}
+
+		for _, update := range applied {
+			_, _ = fmt.Fprintf(out, "%s: %s -> %s\n", refUpdateLocation(project, update), update.Ref, update.UpgradedRef(major))
+			if _, pin := integrity.Split(update.Ref); pin != "" {
+				_, _ = fmt.Fprintln(out, "  dropped its integrity pin; re-pin after reviewing the new version")
+			}
+		}
+
+		if dryRun {
+			return nil
+		}
+		if _, err := os.Stat(filepath.Join(project.Dir, projects.LockfileName)); err == nil {
+			_, _ = fmt.Fprintf(out, "Run `cast task install` to update %s\n", projects.LockfileName)
+		}
+
+		return nil
+	},
+}
+
+func findRefUpdatesForCmd(cmd *cobra.Command) (*projects.Project, []projects.RefUpdate, error) {
+	projectFile, err := resolveProjectFileFromFlagOrCwd(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	project := &projects.Project{}
+	if err := project.LoadFromYaml(projectFile); err != nil {
+		return nil, nil, errors.Newf("failed to load project file %s: %w", projectFile, err)
+	}
+
+	updates, err := projects.FindRefUpdates(project)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return project, updates, nil
+}
+
+// refUpdateLocation returns file:line relative to the project directory.
+func refUpdateLocation(project *projects.Project, update projects.RefUpdate) string {
+	file := update.File
+	if rel, err := filepath.Rel(project.Dir, file); err == nil {
+		file = rel
+	}
+
+	return fmt.Sprintf("%s:%d", file, update.Line)
+}
+
+func init() {
+	taskCmd.AddCommand(taskOutdatedCmd)
+	taskCmd.AddCommand(taskUpgradeCmd)
+
+	taskOutdatedCmd.Flags().Bool("json", false, "Print every versioned ref as JSON")
+	taskOutdatedCmd.Flags().Bool("all", false, "Also list refs that are up to date")
+	taskUpgradeCmd.Flags().Bool("major", false, "Upgrade to the newest version even across a major version")
+	taskUpgradeCmd.Flags().Bool("dry-run", false, "Print the upgrades without rewriting any file")
+}
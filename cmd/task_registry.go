@@ -0,0 +1,311 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/frostyeti/cast/internal/errors"
+	"github.com/frostyeti/cast/internal/integrity"
+	"github.com/frostyeti/cast/internal/projects"
+	"github.com/spf13/cobra"
+)
+
+var taskSearchCmd = &cobra.Command{
+	Use:   "search [query]",
+	Short: "Search the spell registry",
+	Long: `List the spells in the configured registry whose name or description
+contains the query. Without a query every spell is listed.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		index, err := loadRegistryIndexForCmd(cmd)
+		if err != nil {
+			return err
+		}
+
+		query := ""
+		if len(args) > 0 {
+			query = args[0]
+		}
+		spells := index.Search(query)
+
+		asJSON, _ := cmd.Flags().GetBool("json")
+		if asJSON {
+			data, err := json.MarshalIndent(spells, "", "  ")
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintln(cmd.OutOrStdout(), string(data))
+			return nil
+		}
+
+		if len(spells) == 0 {
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "No spells match %q\n", query)
+			return nil
+		}
+
+		width := 0
+		for _, spell := range spells {
+			width = max(width, len(spell.Name))
+		}
+		for _, spell := range spells {
+			latest := "-"
+			if release, ok := spell.Latest(); ok {
+				latest = release.Version
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%-*s  %-10s  %s\n", width, spell.Name, latest, spell.Description)
+		}
+
+		return nil
+	},
+}
+
+var taskInfoCmd = &cobra.Command{
+	Use:   "info <name>",
+	Short: "Show a spell from the registry",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		index, err := loadRegistryIndexForCmd(cmd)
+		if err != nil {
+			return err
+		}
+
+		spell, ok := index.Find(args[0])
+		if !ok {
+			return errors.Newf("spell %q is not in the registry", args[0])
+		}
+
+		asJSON, _ := cmd.Flags().GetBool("json")
+		if asJSON {
+			data, err := json.MarshalIndent(spell, "", "  ")
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintln(cmd.OutOrStdout(), string(data))
+			return nil
+		}
+
+		out := cmd.OutOrStdout()
+		_, _ = fmt.Fprintf(out, "name: %s\n", spell.Name)
+		if spell.Description != "" {
+			_, _ = fmt.Fprintf(out, "desc: %s\n", spell.Description)
+		}
+		if latest, ok := spell.Latest(); ok {
+			_, _ = fmt.Fprintf(out, "uses: reg:%s@%s\n", spell.Name, latest.Version)
+		}
+		_, _ = fmt.Fprintln(out, "versions:")
+		for _, release := range spell.Versions {
+			_, _ = fmt.Fprintf(out, "  %s  sha256:%s  %s\n", release.Version, release.Sha256, index.ReleaseURL(release))
+		}
+
+		return nil
+	},
+}
+
+var taskPackCmd = &cobra.Command{
+	Use:   "pack [dir]",
+	Short: "Validate a cast.task and pack it into a versioned tarball",
+	Long: `Validate the cast.task definition in dir (default: the current directory)
+and write <name>-<version>.tar.gz with a cast.manifest.json and a .sha256
+checksum file, ready for ` + "`cast task publish`" + `.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := "."
+		if len(args) > 0 {
+			dir = args[0]
+		}
+
+		opts, err := packOptionsForCmd(cmd)
+		if err != nil {
+			return err
+		}
+
+		result, err := projects.PackCastTask(dir, opts)
+		if err != nil {
+			return err
+		}
+
+		printPackResult(cmd, result)
+		return nil
+	},
+}
+
+var taskPublishCmd = &cobra.Command{
+	Use:   "publish <tarball|dir>",
+	Short: "Publish a packed spell to a registry",
+	Long: `Copy a tarball written by ` + "`cast task pack`" + ` into a registry and add it to
+the registry index.json. Passing a directory packs it first. Directory and
+file:// registries are written in place; HTTP registries receive PUT requests
+for the tarball and index, authenticated with ` + projects.CastRegistryTokenEnv + ` when set.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		registry, _, err := resolveRegistryForCmd(cmd)
+		if err != nil {
+			return err
+		}
+
+		tarball := args[0]
+		if info, err := os.Stat(tarball); err == nil && info.IsDir() {
+			opts, err := packOptionsForCmd(cmd)
+			if err != nil {
+				return err
+			}
+			if opts.OutDir == "" {
+				// keep the tarball out of the working tree unless asked for.
+				opts.OutDir, err = os.MkdirTemp("", "cast-publish-*")
+				if err != nil {
+					return err
+				}
+				defer func() {
+					_ = os.RemoveAll(opts.OutDir)
+				}()
+			}
+
+			result, err := projects.PackCastTask(tarball, opts)
+			if err != nil {
+				return err
+			}
+			printPackResult(cmd, result)
+			tarball = result.Tarball
+		}
+
+		force, _ := cmd.Flags().GetBool("force")
+		result, err := projects.PublishSpell(registry, tarball, projects.PublishOptions{Force: force})
+		if err != nil {
+			return err
+		}
+
+		if result.Unchanged {
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s %s is already published\n", result.Name, result.Release.Version)
+			return nil
+		}
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Published %s %s to %s\n", result.Name, result.Release.Version, registry)
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "uses: reg:%s@%s\n", result.Name, result.Release.Version)
+		return nil
+	},
+}
+
+var taskSignCmd = &cobra.Command{
+	Use:   "sign <dir|tarball>",
+	Short: "Sign a spell for trusted_sources entries that require keys",
+	Long: `Sign the content of a spell with an ed25519 private key, such as one made by
+` + "`ssh-keygen -t ed25519`" + `. A directory gets a cast.sig at its root to commit
+and tag with the spell; a tarball written by ` + "`cast task pack`" + ` gets a detached
+<tarball>.sig that ` + "`cast task publish`" + ` uploads with it. Signatures by other
+keys over the same content are kept.
+
+The key defaults to ` + projects.CastSigningKeyEnv + `, and ` + projects.CastSigningKeyPassphraseEnv + `
+decrypts a protected key.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keyFile, _ := cmd.Flags().GetString("key")
+		key, err := projects.LoadSigningKey(keyFile)
+		if err != nil {
+			return err
+		}
+
+		sigPath, err := projects.SignCastTask(args[0], key)
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		_, _ = fmt.Fprintf(out, "Signed %s: %s\n", args[0], sigPath)
+		_, _ = fmt.Fprintf(out, "key: %s\n", integrity.FormatPublicKey(key.Public().(ed25519.PublicKey)))
+		return nil
+	},
+}
+
+// packOptionsForCmd reads the flags shared by pack and publish.
+func packOptionsForCmd(cmd *cobra.Command) (projects.PackOptions, error) {
+	version, _ := cmd.Flags().GetString("version")
+	outDir, _ := cmd.Flags().GetString("out")
+	opts := projects.PackOptions{Version: version, OutDir: outDir}
+
+	if keyFile, _ := cmd.Flags().GetString("key"); keyFile != "" {
+		key, err := projects.LoadSigningKey(keyFile)
+		if err != nil {
+			return opts, err
+		}
+		opts.Key = key
+	}
+
+	return opts, nil
+}
+
+func printPackResult(cmd *cobra.Command, result *projects.PackResult) {
+	out := cmd.OutOrStdout()
+	_, _ = fmt.Fprintf(out, "Packed %s %s: %s\n", result.Manifest.Name, result.Manifest.Version, result.Tarball)
+	_, _ = fmt.Fprintf(out, "sha256: %s\n", result.Sha256)
+	_, _ = fmt.Fprintf(out, "integrity: %s\n", result.Integrity)
+	if result.Signature != "" {
+		_, _ = fmt.Fprintf(out, "signature: %s\n", result.Signature)
+	}
+}
+
+// resolveRegistryForCmd returns the registry named by --registry, the
+// castfile `registry:` key or CAST_REGISTRY, in that order, and the
+// directory used for the registry cache.
+func resolveRegistryForCmd(cmd *cobra.Command) (string, string, error) {
+	registry, _ := cmd.Flags().GetString("registry")
+	registry = strings.TrimSpace(registry)
+
+	projectDir, err := os.Getwd()
+	if err != nil {
+		return "", "", err
+	}
+
+	if projectFile, err := resolveProjectFileFromFlagOrCwd(cmd); err == nil {
+		projectDir = filepath.Dir(projectFile)
+		if registry == "" {
+			project := &projects.Project{}
+			if err := project.LoadFromYaml(projectFile); err != nil {
+				return "", "", errors.Newf("failed to load project file %s: %w", projectFile, err)
+			}
+			registry = projects.ResolveRegistryURL(project)
+		}
+	}
+
+	if registry == "" {
+		registry = projects.ResolveRegistryURL(nil)
+	}
+
+	return registry, projectDir, nil
+}
+
+// loadRegistryIndexForCmd loads the index of the registry chosen by
+// resolveRegistryForCmd.
+func loadRegistryIndexForCmd(cmd *cobra.Command) (*projects.RegistryIndex, error) {
+	registry, projectDir, err := resolveRegistryForCmd(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return projects.LoadRegistryIndex(projectDir, registry)
+}
+
+func init() {
+	taskCmd.AddCommand(taskSearchCmd)
+	taskCmd.AddCommand(taskInfoCmd)
+
+	taskCmd.AddCommand(taskPackCmd)
+	taskCmd.AddCommand(taskPublishCmd)
+	taskCmd.AddCommand(taskSignCmd)
+
+	for _, c := range []*cobra.Command{taskSearchCmd, taskInfoCmd} {
+		c.Flags().String("registry", "", "Registry URL, overriding the castfile and "+projects.CastRegistryEnv)
+		c.Flags().Bool("json", false, "Print the result as JSON")
+	}
+
+	for _, c := range []*cobra.Command{taskPackCmd, taskPublishCmd} {
+		c.Flags().String("version", "", "Version of the packed spell, such as 1.2.0")
+		c.Flags().StringP("out", "o", "", "Directory the tarball is written to (default: the current directory)")
+		c.Flags().String("key", "", "ed25519 private key file used to sign the tarball")
+	}
+	taskSignCmd.Flags().String("key", "", "ed25519 private key file (default: "+projects.CastSigningKeyEnv+")")
+	taskPublishCmd.Flags().String("registry", "", "Registry directory or URL, overriding the castfile and "+projects.CastRegistryEnv)
+	taskPublishCmd.Flags().Bool("force", false, "Replace a published version whose tarball differs")
+}
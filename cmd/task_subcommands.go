@@ -18,96 +18,80 @@ import (
 
 var taskInstallCmd = &cobra.Command{
 	Use:   "install",
-	Short: "Install remote tasks from a castfile",
-	RunE: func(cmd *cobra.Command, args []string) error {
-		projectFile, err := resolveProjectFileFromFlagOrCwd(cmd)
-		if err != nil {
-			return err
-		}
+	Short: "Install remote tasks from a castfile and write cast.lock",
+	Long: `Fetch every remote task and module referenced by the castfile and record
+the commit and content hash each ref resolved to in cast.lock. Commit the
+lockfile so ` + "`cast run --frozen`" + ` and CI runs can detect drift.
 
-		project := &projects.Project{}
-		if err := project.LoadFromYaml(projectFile); err != nil {
-			return errors.Newf("failed to load project file %s: %w", projectFile, err)
-		}
-
-		if err := project.Init(); err != nil {
-			return errors.Newf("failed to initialize project %s: %w", projectFile, err)
-		}
-
-		seen := map[string]struct{}{}
-		for _, task := range project.Tasks.Values() {
-			if task.Uses == nil {
-				continue
-			}
-			uses := strings.TrimSpace(*task.Uses)
-			if uses == "" || !projects.IsRemoteTask(uses) {
-				continue
-			}
-			if _, ok := seen[uses]; ok {
-				continue
-			}
-			seen[uses] = struct{}{}
-
-			_, err := projects.FetchRemoteTaskWithOptions(project, uses, project.Schema.TrustedSources, projects.FetchRemoteTaskOptions{
-				Stdout: cmd.OutOrStdout(),
-			})
-			if err != nil {
-				return err
-			}
-		}
-
-		return nil
+Remote steps of composite tasks are fetched too, so a later
+` + "`cast run --offline`" + ` resolves everything from the local caches.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return installRemoteTasks(cmd, false)
 	},
 }
 
 var taskUpdateCmd = &cobra.Command{
 	Use:   "update",
-	Short: "Update branch/head remote tasks from a castfile",
+	Short: "Update branch/head remote tasks from a castfile and refresh cast.lock",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		projectFile, err := resolveProjectFileFromFlagOrCwd(cmd)
-		if err != nil {
-			return err
-		}
+		return installRemoteTasks(cmd, true)
+	},
+}
 
-		project := &projects.Project{}
-		if err := project.LoadFromYaml(projectFile); err != nil {
-			return errors.Newf("failed to load project file %s: %w", projectFile, err)
-		}
+// installRemoteTasks fetches the remote tasks of the project, including the
+// remote steps of composite tasks, and rewrites cast.lock. When refresh is
+// set, branch and head refs are fetched again so the lockfile picks up their
+// latest commits.
+func installRemoteTasks(cmd *cobra.Command, refresh bool) error {
+	projectFile, err := resolveProjectFileFromFlagOrCwd(cmd)
+	if err != nil {
+		return err
+	}
 
-		if err := project.Init(); err != nil {
-			return errors.Newf("failed to initialize project %s: %w", projectFile, err)
+	project := &projects.Project{}
+	if err := project.LoadFromYaml(projectFile); err != nil {
+		return errors.Newf("failed to load project file %s: %w", projectFile, err)
+	}
+
+	project.LockMode = projects.LockWrite
+	if refresh {
+		// branch and head module imports are fetched again during init.
+		if err := os.RemoveAll(projects.ResolveVolatileRemoteModulesDir(project.Dir)); err != nil {
+			return err
 		}
+	}
 
-		seen := map[string]struct{}{}
-		for _, task := range project.Tasks.Values() {
-			if task.Uses == nil {
-				continue
-			}
-			uses := strings.TrimSpace(*task.Uses)
-			if uses == "" || !projects.IsRemoteTask(uses) || !projects.IsVolatileRemoteTaskRef(uses) {
-				continue
-			}
-			if _, ok := seen[uses]; ok {
-				continue
-			}
-			seen[uses] = struct{}{}
+	if err := project.Init(); err != nil {
+		return errors.Newf("failed to initialize project %s: %w", projectFile, err)
+	}
 
-			_, err := projects.FetchRemoteTaskWithOptions(project, uses, project.Schema.TrustedSources, projects.FetchRemoteTaskOptions{
-				Stdout:       cmd.OutOrStdout(),
-				ForceRefresh: true,
-			})
-			if err != nil {
-				return err
-			}
+	refs := []string{}
+	seen := map[string]struct{}{}
+	for _, task := range project.Tasks.Values() {
+		if task.Uses == nil {
+			continue
 		}
+		uses := strings.TrimSpace(*task.Uses)
+		if uses == "" || !projects.IsRemoteTask(uses) {
+			continue
+		}
+		if _, ok := seen[uses]; ok {
+			continue
+		}
+		seen[uses] = struct{}{}
+		refs = append(refs, uses)
+	}
 
-		return nil
-	},
+	if err := projects.PrefetchRemoteTasks(project, refs, cmd.OutOrStdout(), refresh); err != nil {
+		return err
+	}
+
+	return project.WriteLockfile()
 }
 
 var taskClearCacheCmd = &cobra.Command{
 	Use:   "clear-cache",
-	Short: "Clear cached remote tasks",
+	Short: "Clear cached remote tasks and modules",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		global, _ := cmd.Flags().GetBool("global")
 		projectFile, err := resolveProjectFileFromFlagOrCwd(cmd)
@@ -116,20 +100,20 @@ var taskClearCacheCmd = &cobra.Command{
 		}
 
 		projectDir := filepath.Dir(projectFile)
-		cacheDir := projects.ResolveVolatileRemoteTasksDir(projectDir)
-		if !global {
+		cacheDirs := []string{projects.ResolveVolatileRemoteTasksDir(projectDir), projects.ResolveVolatileRemoteModulesDir(projectDir)}
+		label := "local"
+		if global {
+			cacheDirs = []string{projects.ResolveRemoteTasksDir(projectDir), projects.ResolveRemoteModulesDir(projectDir)}
+			label = "global"
+		}
+
+		for _, cacheDir := range cacheDirs {
 			if err := os.RemoveAll(cacheDir); err != nil {
 				return err
 			}
-			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Cleared local task cache: %s\n", cacheDir)
-			return nil
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Cleared %s cache: %s\n", label, cacheDir)
 		}
 
-		globalDir := projects.ResolveRemoteTasksDir(projectDir)
-		if err := os.RemoveAll(globalDir); err != nil {
-			return err
-		}
-		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Cleared global task cache: %s\n", globalDir)
 		return nil
 	},
 }
@@ -290,7 +274,7 @@ func promptValue(reader *bufio.Reader, out io.Writer, label, defaultValue string
 }
 
 func defaultTaskNameFromUses(uses string) string {
-	uses = strings.TrimSpace(uses)
+	uses = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(uses), "reg:"))
 	if uses == "" {
 		return ""
 	}
@@ -396,7 +380,7 @@ func init() {
 		taskCmd.AddCommand(c)
 	}
 
-	taskClearCacheCmd.Flags().Bool("global", false, "Clear global stable task cache")
+	taskClearCacheCmd.Flags().Bool("global", false, "Clear the global stable task and module caches")
 	taskAddCmd.Flags().StringP("uses", "u", "", "Task uses value")
 	taskAddCmd.Flags().StringP("name", "n", "", "Task name")
 	taskAddCmd.Flags().StringP("run", "r", "", "Task run command")
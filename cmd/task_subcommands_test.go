@@ -3,12 +3,19 @@ package cmd
 import (
 	"bytes"
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/frostyeti/cast/internal/integrity"
 	"github.com/frostyeti/cast/internal/projects"
+	"golang.org/x/crypto/ssh"
 )
 
 func TestRootHelpIncludesTaskCommand(t *testing.T) {
@@ -40,7 +47,7 @@ func TestTaskHelpIncludesSubcommands(t *testing.T) {
 		t.Fatalf("expected no error from task help, got %v", err)
 	}
 
-	for _, sub := range []string{"add", "install", "update", "clear-cache", "run", "list", "exec"} {
+	for _, sub := range []string{"add", "install", "update", "clear-cache", "cache", "search", "info", "pack", "publish", "sign", "outdated", "upgrade", "run", "list", "exec"} {
 		if !strings.Contains(out, "\n  "+sub+" ") {
 			t.Fatalf("expected %s subcommand in task help output, got: %s", sub, out)
 		}
@@ -235,6 +242,100 @@ func TestTaskClearCacheRemovesLocalCache(t *testing.T) {
 	}
 }
 
+func TestTaskCacheLsAndPrune(t *testing.T) {
+	resetRootForTest()
+	tmpDir := t.TempDir()
+	t.Setenv(projects.CastRemoteTasksDirEnv, filepath.Join(tmpDir, "stable-tasks"))
+	t.Setenv(projects.CastRemoteModulesDirEnv, filepath.Join(tmpDir, "stable-modules"))
+	projectDir := filepath.Join(tmpDir, "project")
+	if err := os.MkdirAll(projectDir, 0o755); err != nil {
+		t.Fatalf("mkdir project: %v", err)
+	}
+	projectFile := filepath.Join(projectDir, "castfile")
+	if err := os.WriteFile(projectFile, []byte("name: test\n"), 0o644); err != nil {
+		t.Fatalf("write castfile: %v", err)
+	}
+
+	modulesDir := projects.ResolveVolatileRemoteModulesDir(projectDir)
+	for i, name := range []string{"old-0000aaaa", "new-0000bbbb"} {
+		dir := filepath.Join(modulesDir, name)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir module: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "mod.ts"), []byte(strings.Repeat("x", 2048)), 0o644); err != nil {
+			t.Fatalf("write module: %v", err)
+		}
+		used := time.Now().Add(time.Duration(i-90) * 24 * time.Hour)
+		if err := os.Chtimes(dir, used, used); err != nil {
+			t.Fatalf("chtimes: %v", err)
+		}
+	}
+
+	out, err := executeRootForTest([]string{"task", "-p", projectFile, "cache", "ls"}, "")
+	if err != nil {
+		t.Fatalf("task cache ls failed: %v", err)
+	}
+	if !strings.Contains(out, "old-0000aaaa") || !strings.Contains(out, "2.0K") || !strings.Contains(out, "2 entries, 4.0K") {
+		t.Fatalf("unexpected cache ls output: %s", out)
+	}
+	if strings.Index(out, "old-0000aaaa") > strings.Index(out, "new-0000bbbb") {
+		t.Fatalf("expected least recently used entries first, got: %s", out)
+	}
+
+	if _, err := executeRootForTest([]string{"task", "-p", projectFile, "cache", "prune"}, ""); err == nil {
+		t.Fatalf("expected prune without bounds to fail")
+	}
+
+	out, err = executeRootForTest([]string{"task", "-p", projectFile, "cache", "prune", "--max-size", "3K", "--dry-run"}, "")
+	if err != nil {
+		t.Fatalf("task cache prune --dry-run failed: %v", err)
+	}
+	if !strings.Contains(out, "Would remove volatile "+filepath.Join(modulesDir, "old-0000aaaa")) {
+		t.Fatalf("unexpected dry run output: %s", out)
+	}
+	if _, err := os.Stat(filepath.Join(modulesDir, "old-0000aaaa")); err != nil {
+		t.Fatalf("dry run removed the module: %v", err)
+	}
+
+	out, err = executeRootForTest([]string{"task", "-p", projectFile, "cache", "prune", "--max-size", "3K", "--dry-run=false"}, "")
+	if err != nil {
+		t.Fatalf("task cache prune failed: %v", err)
+	}
+	if !strings.Contains(out, "Removed 1 entries, 2.0K") {
+		t.Fatalf("unexpected prune output: %s", out)
+	}
+	if _, err := os.Stat(filepath.Join(modulesDir, "old-0000aaaa")); !os.IsNotExist(err) {
+		t.Fatalf("expected the least recently used module to be removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(modulesDir, "new-0000bbbb")); err != nil {
+		t.Fatalf("expected the newer module to be kept: %v", err)
+	}
+}
+
+func TestParseCacheBounds(t *testing.T) {
+	sizes := map[string]int64{"512": 512, "2G": 2 << 30, "500MB": 500 << 20, "1.5KiB": 1536}
+	for value, want := range sizes {
+		got, err := parseByteSize(value)
+		if err != nil || got != want {
+			t.Fatalf("parseByteSize(%q) = %d, %v; want %d", value, got, err, want)
+		}
+	}
+	if _, err := parseByteSize("lots"); err == nil {
+		t.Fatalf("expected an invalid size to fail")
+	}
+
+	ages := map[string]time.Duration{"30d": 30 * 24 * time.Hour, "2w": 14 * 24 * time.Hour, "12h": 12 * time.Hour}
+	for value, want := range ages {
+		got, err := parseAge(value)
+		if err != nil || got != want {
+			t.Fatalf("parseAge(%q) = %s, %v; want %s", value, got, err, want)
+		}
+	}
+	if _, err := parseAge("soon"); err == nil {
+		t.Fatalf("expected an invalid age to fail")
+	}
+}
+
 func resetRootForTest() {
 	rootCmd.SetArgs(nil)
 	rootCmd.SetIn(strings.NewReader(""))
@@ -278,3 +379,215 @@ func executeRootForTest(args []string, stdin string) (string, error) {
 	err := cmd.ExecuteContext(context.Background())
 	return buf.String(), err
 }
+
+func TestTaskInstallWritesLockfileAndFrozenRunParses(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectFile := filepath.Join(tmpDir, "castfile")
+	content := `
+name: lockfile
+tasks:
+  hello:
+    uses: bash
+    run: echo frozen-hello
+`
+	if err := os.WriteFile(projectFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("write castfile: %v", err)
+	}
+
+	if _, err := executeRootForTest([]string{"task", "install", "-p", projectFile}, ""); err != nil {
+		t.Fatalf("task install failed: %v", err)
+	}
+
+	lock, err := os.ReadFile(filepath.Join(tmpDir, "cast.lock"))
+	if err != nil {
+		t.Fatalf("expected cast.lock to be written: %v", err)
+	}
+	if !strings.Contains(string(lock), "version: 1") {
+		t.Fatalf("unexpected lockfile content: %s", lock)
+	}
+
+	out, err := executeRootForTest([]string{"run", "-p", projectFile, "--frozen", "hello"}, "")
+	if err != nil {
+		t.Fatalf("frozen run failed: %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "frozen-hello") {
+		t.Fatalf("expected --frozen to leave the task target intact, got: %s", out)
+	}
+}
+
+func TestTaskSearchAndInfoReadRegistryIndex(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv(projects.CastRemoteTasksDirEnv, filepath.Join(tmpDir, "stable"))
+
+	registryDir := filepath.Join(tmpDir, "registry")
+	if err := os.MkdirAll(registryDir, 0o755); err != nil {
+		t.Fatalf("mkdir registry: %v", err)
+	}
+	index := `{"version": 1, "spells": [
+  {"name": "deploy", "description": "Deploy a service", "versions": [
+    {"version": "1.2.0", "url": "deploy-1.2.0.tar.gz", "sha256": "aa"},
+    {"version": "1.10.0", "url": "deploy-1.10.0.tar.gz", "sha256": "bb"}]},
+  {"name": "lint", "description": "Lint sources", "versions": [
+    {"version": "0.3.0", "url": "lint-0.3.0.tar.gz", "sha256": "cc"}]}
+]}`
+	if err := os.WriteFile(filepath.Join(registryDir, "index.json"), []byte(index), 0o644); err != nil {
+		t.Fatalf("write index: %v", err)
+	}
+
+	projectFile := filepath.Join(tmpDir, "castfile")
+	if err := os.WriteFile(projectFile, []byte("name: registry\nregistry: "+registryDir+"\n"), 0o644); err != nil {
+		t.Fatalf("write castfile: %v", err)
+	}
+
+	out, err := executeRootForTest([]string{"task", "search", "-p", projectFile, "service"}, "")
+	if err != nil {
+		t.Fatalf("task search failed: %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "deploy  1.10.0") || strings.Contains(out, "lint") {
+		t.Fatalf("expected only deploy with its latest version, got: %s", out)
+	}
+
+	out, err = executeRootForTest([]string{"task", "info", "-p", projectFile, "deploy"}, "")
+	if err != nil {
+		t.Fatalf("task info failed: %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "uses: reg:deploy@1.10.0") || !strings.Contains(out, filepath.Join(registryDir, "deploy-1.2.0.tar.gz")) {
+		t.Fatalf("expected spell details with resolved tarball URLs, got: %s", out)
+	}
+}
+
+func TestTaskPackAndPublishToDirectoryRegistry(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv(projects.CastRemoteTasksDirEnv, filepath.Join(tmpDir, "stable"))
+
+	spellDir := filepath.Join(tmpDir, "spell")
+	if err := os.MkdirAll(spellDir, 0o755); err != nil {
+		t.Fatalf("mkdir spell: %v", err)
+	}
+	castTask := "name: hello\ndescription: Say hello\nruns:\n  using: composite\n  steps:\n    - run: echo hello\n"
+	if err := os.WriteFile(filepath.Join(spellDir, "cast.task"), []byte(castTask), 0o644); err != nil {
+		t.Fatalf("write cast.task: %v", err)
+	}
+
+	outDir := filepath.Join(tmpDir, "dist")
+	out, err := executeRootForTest([]string{"task", "pack", spellDir, "--version", "0.2.0", "--out", outDir}, "")
+	if err != nil {
+		t.Fatalf("task pack failed: %v\n%s", err, out)
+	}
+	tarball := filepath.Join(outDir, "hello-0.2.0.tar.gz")
+	if !strings.Contains(out, "Packed hello 0.2.0: "+tarball) || !strings.Contains(out, "integrity: sha256=") {
+		t.Fatalf("expected pack summary, got: %s", out)
+	}
+
+	registryDir := filepath.Join(tmpDir, "registry")
+	out, err = executeRootForTest([]string{"task", "publish", tarball, "--registry", registryDir}, "")
+	if err != nil {
+		t.Fatalf("task publish failed: %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "Published hello 0.2.0") || !strings.Contains(out, "uses: reg:hello@0.2.0") {
+		t.Fatalf("expected publish summary, got: %s", out)
+	}
+
+	out, err = executeRootForTest([]string{"task", "info", "--registry", registryDir, "hello"}, "")
+	if err != nil {
+		t.Fatalf("task info failed: %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "desc: Say hello") || !strings.Contains(out, filepath.Join(registryDir, "hello", "hello-0.2.0.tar.gz")) {
+		t.Fatalf("expected the published spell in the registry, got: %s", out)
+	}
+}
+
+func TestTaskSignWritesCastSig(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	block, err := ssh.MarshalPrivateKey(key, "")
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	keyFile := filepath.Join(tmpDir, "id_ed25519")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+
+	spellDir := filepath.Join(tmpDir, "spell")
+	if err := os.MkdirAll(spellDir, 0o755); err != nil {
+		t.Fatalf("mkdir spell: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(spellDir, "cast.task"), []byte("name: hello\nruns:\n  using: composite\n  steps:\n    - run: echo hello\n"), 0o644); err != nil {
+		t.Fatalf("write cast.task: %v", err)
+	}
+
+	out, err := executeRootForTest([]string{"task", "sign", spellDir, "--key", keyFile}, "")
+	if err != nil {
+		t.Fatalf("task sign failed: %v\n%s", err, out)
+	}
+
+	public := key.Public().(ed25519.PublicKey)
+	if !strings.Contains(out, "key: "+integrity.FormatPublicKey(public)) {
+		t.Fatalf("expected the public key to add to trusted_sources, got: %s", out)
+	}
+	if err := integrity.VerifyTree(spellDir, []ed25519.PublicKey{public}); err != nil {
+		t.Fatalf("expected a valid cast.sig: %v", err)
+	}
+}
+
+func TestTaskOutdatedAndUpgradeRewriteStaleRefs(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv(projects.CastRemoteTasksDirEnv, filepath.Join(tmpDir, "stable"))
+
+	repoDir := filepath.Join(tmpDir, "spells")
+	if err := os.MkdirAll(filepath.Join(repoDir, "lint"), 0o755); err != nil {
+		t.Fatalf("mkdir repo: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, "lint", "cast.task"), []byte("name: lint\nruns:\n  using: composite\n  steps:\n    - run: echo lint\n"), 0o644); err != nil {
+		t.Fatalf("write cast.task: %v", err)
+	}
+	for _, args := range [][]string{
+		{"init"},
+		{"-c", "user.name=Test", "-c", "user.email=test@example.com", "add", "."},
+		{"-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "-m", "initial"},
+		{"tag", "v1.0.0"},
+		{"tag", "v1.1.0"},
+		{"tag", "v2.0.0"},
+	} {
+		git := exec.Command("git", args...)
+		git.Dir = repoDir
+		if out, err := git.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	uses := "file://" + filepath.ToSlash(repoDir) + "@v1.0.0/lint"
+	projectFile := filepath.Join(tmpDir, "castfile")
+	if err := os.WriteFile(projectFile, []byte("name: stale\ntasks:\n  lint:\n    # pinned for the release branch\n    uses: "+uses+"\n"), 0o644); err != nil {
+		t.Fatalf("write castfile: %v", err)
+	}
+
+	out, err := executeRootForTest([]string{"task", "outdated", "-p", projectFile}, "")
+	if err != nil {
+		t.Fatalf("task outdated failed: %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "CURRENT  WANTED  LATEST") || !strings.Contains(out, "v1.0.0   v1.1.0  v2.0.0  castfile:5") {
+		t.Fatalf("expected the stale ref with wanted and latest versions, got: %s", out)
+	}
+
+	out, err = executeRootForTest([]string{"task", "upgrade", "-p", projectFile}, "")
+	if err != nil {
+		t.Fatalf("task upgrade failed: %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "castfile:5: "+uses+" -> "+strings.Replace(uses, "v1.0.0", "v1.1.0", 1)) {
+		t.Fatalf("expected the in-major upgrade, got: %s", out)
+	}
+
+	data, err := os.ReadFile(projectFile)
+	if err != nil {
+		t.Fatalf("read castfile: %v", err)
+	}
+	if !strings.Contains(string(data), "    # pinned for the release branch\n    uses: "+strings.Replace(uses, "v1.0.0", "v1.1.0", 1)+"\n") {
+		t.Fatalf("expected the ref rewritten in place, got:\n%s", data)
+	}
+}
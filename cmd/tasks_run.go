@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/frostyeti/cast/internal/errors"
+	"github.com/frostyeti/cast/internal/offline"
 	"github.com/frostyeti/cast/internal/projects"
 	"github.com/frostyeti/cast/internal/runstatus"
 	"github.com/frostyeti/go/env"
@@ -70,6 +71,8 @@ var tasksRunCmd = &cobra.Command{
 		flags.StringArrayP("dotenv", "E", []string{}, "List of dotenv files to load")
 		flags.StringToStringP("env", "e", map[string]string{}, "List of environment variables to set")
 		flags.StringP("context", "c", contextName, "Context to use.")
+		flags.Bool("frozen", false, "Fail when remote tasks or modules differ from cast.lock")
+		flags.Bool("offline", false, "Resolve remote tasks, modules and tools only from local caches (or set CAST_OFFLINE=1)")
 
 		targets := []string{}
 		cmdArgs := []string{}
@@ -97,6 +100,9 @@ var tasksRunCmd = &cobra.Command{
 
 			if len(n) > 0 && n[0] == '-' {
 				cmdArgs = append(cmdArgs, n)
+				if strings.Contains(n, "=") || isBoolFlag(flags, n) {
+					continue
+				}
 				j := i + 1
 				if j < size && len(args[j]) > 0 && args[j][0] != '-' {
 					cmdArgs = append(cmdArgs, args[j])
@@ -122,6 +128,12 @@ var tasksRunCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		if isOffline, _ := flags.GetBool("offline"); isOffline {
+			if err := offline.Enable(); err != nil {
+				return err
+			}
+		}
+
 		projectFile, _ = flags.GetString("project")
 		contextName, _ = flags.GetString("context")
 		projectName := ""
@@ -253,6 +265,9 @@ var tasksRunCmd = &cobra.Command{
 		}
 
 		project.ContextName = contextName
+		if frozen, _ := flags.GetBool("frozen"); frozen {
+			project.LockMode = projects.LockFrozen
+		}
 		err = project.Init()
 		if err != nil {
 			return errors.Newf("failed to initialize project %s: %w", projectFile, err)
@@ -299,6 +314,10 @@ var tasksRunCmd = &cobra.Command{
 			}
 		}
 
+		if err := project.CheckPublicTargets(targets, contextName); err != nil {
+			return err
+		}
+
 		params := projects.RunTasksParams{
 			Targets:     targets,
 			Args:        remainingArgs,
@@ -340,6 +359,20 @@ func init() {
 	tasksRunCmd.Flags().StringP("context", "c", context, "Context name to use from the project")
 	tasksRunCmd.Flags().StringArrayP("dotenv", "E", []string{}, "List of dotenv files to load")
 	tasksRunCmd.Flags().StringToStringP("env", "e", map[string]string{}, "List of environment variables to set")
+	tasksRunCmd.Flags().Bool("frozen", false, "Fail when remote tasks or modules differ from cast.lock")
+}
+
+// isBoolFlag reports whether arg names a boolean flag, which never consumes
+// the following argument as its value.
+func isBoolFlag(flags *pflag.FlagSet, arg string) bool {
+	var flag *pflag.Flag
+	if name, ok := strings.CutPrefix(arg, "--"); ok {
+		flag = flags.Lookup(name)
+	} else if len(arg) == 2 {
+		flag = flags.ShorthandLookup(arg[1:])
+	}
+
+	return flag != nil && flag.Value.Type() == "bool"
 }
 
 func shouldShowTaskHelp(targets, args []string) bool {
@@ -361,13 +394,7 @@ func lookupTaskForContext(project *projects.Project, target, contextName string)
 		return projectsTaskView{}, false
 	}
 
-	if contextName != "" {
-		if t, found := project.Tasks.Get(target + ":" + contextName); found {
-			return projectsTaskView{Help: t.Help, Desc: t.Desc}, true
-		}
-	}
-
-	t, found := project.Tasks.Get(target)
+	t, found := project.Tasks.Resolve(target, contextName)
 	if !found {
 		return projectsTaskView{}, false
 	}
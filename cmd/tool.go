@@ -6,6 +6,8 @@ import (
 	stdexec "os/exec"
 	"runtime"
 
+	"github.com/frostyeti/cast/internal/errors"
+	"github.com/frostyeti/cast/internal/offline"
 	"github.com/spf13/cobra"
 )
 
@@ -39,6 +41,11 @@ func handleToolInstall(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	args, err := stripOfflineFlag(args)
+	if err != nil {
+		return err
+	}
+
 	if len(args) == 0 {
 		// Fallback to empty install which installs from mise.toml
 		return runMiseCmdFunc([]string{"install"})
@@ -158,6 +165,10 @@ func installDeno() error {
 		return nil
 	}
 
+	if offline.Enabled() {
+		return offlineToolError("deno")
+	}
+
 	fmt.Println("Installing deno...")
 	var cmd *stdexec.Cmd
 	if runtime.GOOS == "windows" {
@@ -177,6 +188,10 @@ func installBun() error {
 		return nil
 	}
 
+	if offline.Enabled() {
+		return offlineToolError("bun")
+	}
+
 	fmt.Println("Installing bun...")
 	var cmd *stdexec.Cmd
 	if runtime.GOOS == "windows" {
@@ -196,6 +211,10 @@ func installMise() error {
 		return nil
 	}
 
+	if offline.Enabled() {
+		return offlineToolError("mise")
+	}
+
 	fmt.Println("Installing mise...")
 	var cmd *stdexec.Cmd
 	if runtime.GOOS == "windows" {
@@ -222,8 +241,33 @@ func runMiseCmd(args []string) error {
 	}
 
 	cmd := stdexec.Command("mise", args...)
+	if offline.Enabled() {
+		// mise only uses tools it already installed when offline.
+		cmd.Env = append(os.Environ(), "MISE_OFFLINE=1")
+	}
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	cmd.Stdin = os.Stdin
 	return cmd.Run()
 }
+
+// stripOfflineFlag removes --offline from args of commands that do not parse
+// their own flags and turns offline mode on when it was present.
+func stripOfflineFlag(args []string) ([]string, error) {
+	rest := make([]string, 0, len(args))
+	for _, a := range args {
+		if a == "--offline" {
+			if err := offline.Enable(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		rest = append(rest, a)
+	}
+
+	return rest, nil
+}
+
+func offlineToolError(tool string) error {
+	return errors.Newf("%s is not installed and cannot be downloaded while cast is offline; add it to the build image or PATH, or run without --offline", tool)
+}
@@ -1,6 +1,11 @@
 package cmd
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"github.com/frostyeti/cast/internal/offline"
+)
 
 func TestHandleToolInstallSpecialCases(t *testing.T) {
 	originalInstallDeno := installDenoFunc
@@ -60,3 +65,26 @@ func TestHandleToolInstallSpecialCases(t *testing.T) {
 		t.Fatalf("expected fallback to mise command, got %s", called)
 	}
 }
+
+func TestToolInstallRefusesDownloadsOffline(t *testing.T) {
+	t.Setenv(offline.Env, "")
+	t.Setenv("PATH", t.TempDir())
+
+	args, err := stripOfflineFlag([]string{"--offline", "deno"})
+	if err != nil {
+		t.Fatalf("stripOfflineFlag returned error: %v", err)
+	}
+	if len(args) != 1 || args[0] != "deno" {
+		t.Fatalf("expected --offline to be removed, got %v", args)
+	}
+	if !offline.Enabled() {
+		t.Fatal("expected --offline to enable offline mode")
+	}
+
+	for name, install := range map[string]func() error{"deno": installDeno, "bun": installBun, "mise": installMise} {
+		err := install()
+		if err == nil || !strings.Contains(err.Error(), "offline") {
+			t.Fatalf("expected %s install to be refused offline, got: %v", name, err)
+		}
+	}
+}
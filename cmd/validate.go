@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/frostyeti/cast/internal/errors"
+	"github.com/frostyeti/cast/internal/projects"
+	"github.com/frostyeti/go/env"
+	"github.com/spf13/cobra"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the castfile and its imports for problems",
+	Long: `Load the project and its imports and report every problem found without
+running anything: unknown uses handlers, needs, hooks and job steps that point
+at missing tasks, hosts that match no inventory host or tag, invalid timeouts
+and cron expressions, and needs or extends cycles.
+
+Each problem is printed as file:line:column: severity: message (code). Use
+--json for editor and CI integrations. The command exits non-zero when any
+error is reported.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		projectFile, err := resolveProjectFileFromFlagOrCwd(cmd)
+		if err != nil {
+			return err
+		}
+
+		contextName := resolveDefaultContextName(cmd, projectFile)
+		diags := projects.ValidateFile(projectFile, contextName)
+
+		asJSON, _ := cmd.Flags().GetBool("json")
+		if asJSON {
+			if diags == nil {
+				diags = projects.Diagnostics{}
+			}
+			data, err := json.MarshalIndent(map[string]any{
+				"project":     projectFile,
+				"context":     contextName,
+				"valid":       !diags.HasErrors(),
+				"diagnostics": diags,
+			}, "", "  ")
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintln(cmd.OutOrStdout(), string(data))
+		} else {
+			dir := filepath.Dir(projectFile)
+			for _, diag := range diags {
+				diag.File = relativeToDir(diag.File, dir)
+				_, _ = fmt.Fprintln(cmd.OutOrStdout(), diag.String())
+			}
+		}
+
+		errorCount := 0
+		for _, diag := range diags {
+			if diag.Severity == projects.SeverityError {
+				errorCount++
+			}
+		}
+
+		if errorCount > 0 {
+			return errors.Newf("%s has %d error(s)", filepath.Base(projectFile), errorCount)
+		}
+
+		if !asJSON {
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s is valid\n", filepath.Base(projectFile))
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+	project := env.Get("CAST_PROJECT")
+	context := env.Get("CAST_CONTEXT")
+	validateCmd.Flags().StringP("project", "p", project, "Path to the project file (castfile.yaml)")
+	validateCmd.Flags().StringP("context", "c", context, "Context name to use from the project")
+	validateCmd.Flags().Bool("json", false, "Print diagnostics as JSON")
+	_ = validateCmd.RegisterFlagCompletionFunc("project", provideProjectFlagCompletion)
+	_ = validateCmd.RegisterFlagCompletionFunc("context", provideContextFlagCompletion)
+}
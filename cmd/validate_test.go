@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateCommandReportsDiagnosticsAsJSON(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectFile := filepath.Join(tmpDir, "castfile")
+	content := `name: demo
+tasks:
+  build:
+    needs: [missing]
+    run: echo build
+`
+	if err := os.WriteFile(projectFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("write castfile: %v", err)
+	}
+
+	out, err := executeRootForTest([]string{"validate", "-p", projectFile, "--json"}, "")
+	if err == nil {
+		t.Fatalf("expected validate to fail, got:\n%s", out)
+	}
+
+	start := strings.Index(out, "{")
+	end := strings.LastIndex(out, "}")
+	if start < 0 || end < start {
+		t.Fatalf("expected json output, got:\n%s", out)
+	}
+
+	var result struct {
+		Valid       bool `json:"valid"`
+		Diagnostics []struct {
+			Line   int    `json:"line"`
+			Column int    `json:"column"`
+			Code   string `json:"code"`
+		} `json:"diagnostics"`
+	}
+	if err := json.Unmarshal([]byte(out[start:end+1]), &result); err != nil {
+		t.Fatalf("decode json: %v\n%s", err, out)
+	}
+
+	if result.Valid || len(result.Diagnostics) != 1 {
+		t.Fatalf("expected one diagnostic, got %+v", result)
+	}
+	diag := result.Diagnostics[0]
+	if diag.Code != "missing-need" || diag.Line != 4 || diag.Column != 13 {
+		t.Fatalf("unexpected diagnostic: %+v", diag)
+	}
+}
@@ -0,0 +1,310 @@
+// Package archive creates, downloads and extracts the gzip compressed
+// tarballs used by module imports and registry spells.
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/frostyeti/cast/internal/errors"
+)
+
+// Open returns the content at uri. http and https URIs are downloaded;
+// file:// URIs and plain paths are read from disk.
+func Open(uri string) (io.ReadCloser, error) {
+	return OpenWithHeader(uri, nil)
+}
+
+// OpenWithHeader is Open with header added to http and https requests, for
+// hosts that need an authorization token.
+func OpenWithHeader(uri string, header http.Header) (io.ReadCloser, error) {
+	if strings.HasPrefix(uri, "http://") || strings.HasPrefix(uri, "https://") {
+		req, err := http.NewRequest(http.MethodGet, uri, nil)
+		if err != nil {
+			return nil, err
+		}
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			_ = resp.Body.Close()
+			return nil, errors.Newf("failed to download %s: status %d", uri, resp.StatusCode)
+		}
+		return resp.Body, nil
+	}
+
+	path := uri
+	if strings.HasPrefix(uri, "file://") {
+		u, err := url.Parse(uri)
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.FromSlash(u.Path)
+	}
+
+	return os.Open(path)
+}
+
+// Stage fills a temporary directory created next to dir and renames it to dir
+// once fill succeeds, so a failed download or check never leaves partial
+// content behind. The temporary directory is hidden so cache listings skip
+// entries that are still being fetched.
+func Stage(dir string, fill func(tmpDir string) error) error {
+	parent := filepath.Dir(dir)
+	if err := os.MkdirAll(parent, 0o755); err != nil {
+		return err
+	}
+
+	tmpDir, err := os.MkdirTemp(parent, ".fetch-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.RemoveAll(tmpDir)
+	}()
+
+	if err := fill(tmpDir); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpDir, dir)
+}
+
+// Download fetches the tarball at uri and extracts it into targetDir. When
+// digest is set it must equal the hex sha256 of the downloaded archive.
+func Download(uri, targetDir, digest string) error {
+	body, err := Open(uri)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = body.Close()
+	}()
+
+	sum := sha256.New()
+	if err := ExtractTarGz(io.TeeReader(body, sum), targetDir); err != nil {
+		return err
+	}
+
+	// drain trailing padding so the digest covers the whole archive.
+	if _, err := io.Copy(sum, body); err != nil {
+		return err
+	}
+
+	if digest != "" {
+		got := hex.EncodeToString(sum.Sum(nil))
+		if !strings.EqualFold(got, digest) {
+			return errors.Newf("sha256 mismatch for %s: expected %s, got %s", uri, strings.ToLower(digest), got)
+		}
+	}
+
+	return nil
+}
+
+// ExtractOptions selects and renames the entries ExtractTarGzWithOptions
+// writes.
+type ExtractOptions struct {
+	// StripComponents drops that many leading path elements from every entry,
+	// like `tar --strip-components`, for archives with a root directory.
+	StripComponents int
+	// Only keeps the entries at or below this slash separated path, after
+	// stripping. Empty keeps every entry.
+	Only string
+}
+
+// ExtractTarGz extracts a gzip compressed tarball into targetDir. Entries
+// that would land outside targetDir are skipped.
+func ExtractTarGz(r io.Reader, targetDir string) error {
+	return ExtractTarGzWithOptions(r, targetDir, ExtractOptions{})
+}
+
+// ExtractTarGzWithOptions extracts the entries of a gzip compressed tarball
+// selected by opts into targetDir. Entries that would land outside targetDir
+// are skipped.
+func ExtractTarGzWithOptions(r io.Reader, targetDir string, opts ExtractOptions) error {
+	if err := os.MkdirAll(targetDir, 0o755); err != nil {
+		return err
+	}
+
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = gzr.Close()
+	}()
+
+	tr := tar.NewReader(gzr)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		name, ok := selectEntry(header.Name, opts)
+		if !ok {
+			continue
+		}
+		targetPath := filepath.Join(targetDir, filepath.FromSlash(name))
+
+		// Protect against directory traversal
+		if !strings.HasPrefix(targetPath, filepath.Clean(targetDir)+string(os.PathSeparator)) && targetPath != filepath.Clean(targetDir) {
+			continue
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(targetPath, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(targetPath), 0o755); err != nil {
+				return err
+			}
+			outFile, err := os.Create(targetPath)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(outFile, tr); err != nil {
+				_ = outFile.Close()
+				return err
+			}
+			if err := outFile.Close(); err != nil {
+				return err
+			}
+			if err := os.Chmod(targetPath, os.FileMode(header.Mode)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// selectEntry returns the path name is extracted to under opts, or false
+// when the entry is stripped away or outside opts.Only.
+func selectEntry(name string, opts ExtractOptions) (string, bool) {
+	name = strings.TrimPrefix(filepath.ToSlash(name), "./")
+	if opts.StripComponents > 0 {
+		parts := strings.Split(name, "/")
+		if len(parts) <= opts.StripComponents {
+			return "", false
+		}
+		name = strings.Join(parts[opts.StripComponents:], "/")
+	}
+
+	only := strings.Trim(opts.Only, "/")
+	if only == "" {
+		return name, name != ""
+	}
+	trimmed := strings.TrimSuffix(name, "/")
+	if trimmed != only && !strings.HasPrefix(trimmed, only+"/") {
+		return "", false
+	}
+
+	return name, true
+}
+
+// WriteTarGz writes the files under root, given as slash separated paths
+// relative to root, as a gzip compressed tarball. Entries are sorted and
+// carry no timestamps or owners, so the same content always produces the
+// same archive and digest.
+func WriteTarGz(w io.Writer, root string, files []string) error {
+	sorted := append([]string{}, files...)
+	sort.Strings(sorted)
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, rel := range sorted {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+
+		mode := int64(0o644)
+		if info.Mode()&0o111 != 0 {
+			mode = 0o755
+		}
+
+		header := &tar.Header{
+			Name:     rel,
+			Mode:     mode,
+			Size:     info.Size(),
+			Typeflag: tar.TypeReg,
+			ModTime:  time.Unix(0, 0).UTC(),
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(tw, file)
+		_ = file.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
+
+// ReadFile returns the content of the entry called name in the gzip
+// compressed tarball at path.
+func ReadFile(path, name string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	gzr, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = gzr.Close()
+	}()
+
+	tr := tar.NewReader(gzr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, errors.Newf("%s does not contain %s", path, name)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if strings.TrimPrefix(header.Name, "./") == name {
+			return io.ReadAll(tr)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package eval
 
 import (
+	"fmt"
 	"strconv"
 	"strings"
 
@@ -57,7 +58,7 @@ func EvalTemplate(template string, scope map[string]interface{}) (string, error)
 				return "", err
 			}
 
-			result.WriteString(strings.TrimSpace(evaled.(string)))
+			result.WriteString(strings.TrimSpace(fmt.Sprint(evaled)))
 		} else {
 			result.WriteString(tok.value)
 		}
@@ -0,0 +1,162 @@
+// Package integrity computes and verifies content hashes for fetched remote
+// tasks and modules.
+package integrity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/frostyeti/cast/internal/errors"
+)
+
+// Split separates an integrity pin such as `#sha256=<hex>` from the end of a
+// remote reference. The pin is returned without the leading `#` and is empty
+// when the reference does not carry one.
+func Split(ref string) (string, string) {
+	idx := strings.LastIndex(ref, "#")
+	if idx < 0 {
+		return ref, ""
+	}
+
+	alg, _, ok := strings.Cut(ref[idx+1:], "=")
+	if !ok || alg == "" || strings.ContainsAny(alg, "/@:") {
+		return ref, ""
+	}
+
+	return ref[:idx], ref[idx+1:]
+}
+
+// Validate checks that pin uses a supported algorithm and a well formed
+// digest. Only sha256 is supported.
+func Validate(pin string) error {
+	alg, digest, _ := strings.Cut(pin, "=")
+	if alg != "sha256" {
+		return errors.Newf("unsupported integrity algorithm %q, expected sha256", alg)
+	}
+
+	if len(digest) != sha256.Size*2 {
+		return errors.Newf("invalid sha256 integrity %q: expected %d hex characters", digest, sha256.Size*2)
+	}
+	if _, err := hex.DecodeString(digest); err != nil {
+		return errors.Newf("invalid sha256 integrity %q: %v", digest, err)
+	}
+
+	return nil
+}
+
+// Verify fails when the tree hash of dir does not match pin.
+func Verify(dir, pin string) error {
+	if err := Validate(pin); err != nil {
+		return err
+	}
+
+	hash, err := HashTree(dir)
+	if err != nil {
+		return err
+	}
+
+	expected := "sha256:" + strings.ToLower(strings.TrimPrefix(pin, "sha256="))
+	if hash != expected {
+		return errors.Newf("integrity mismatch: expected %s, got %s", Pin(expected), Pin(hash))
+	}
+
+	return nil
+}
+
+// Pin formats a tree hash as the pin written after `#` in a reference.
+func Pin(hash string) string {
+	return strings.Replace(hash, ":", "=", 1)
+}
+
+// HashTree returns a sha256 hash over the relative path, type and content of
+// every file under root, skipping the .git directory and a cast.sig at the
+// root. Each record tags the entry as a regular file (`F`), an executable
+// file (`X`) or a symlink (`L`), so swapping a file for a symlink or flipping
+// its exec bit changes the hash. Files are visited in sorted order so the
+// hash only changes when the tree does.
+func HashTree(root string) (string, error) {
+	signaturePath := filepath.Join(root, SignatureFile)
+	files := []string{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if path == signaturePath {
+			return nil
+		}
+
+		files = append(files, path)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sort.Strings(files)
+	sum := sha256.New()
+	for _, path := range files {
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return "", err
+		}
+
+		record, err := hashFile(path)
+		if err != nil {
+			return "", err
+		}
+
+		_, _ = io.WriteString(sum, filepath.ToSlash(rel)+"\x00"+record+"\n")
+	}
+
+	return "sha256:" + hex.EncodeToString(sum.Sum(nil)), nil
+}
+
+// hashFile returns the tree hash record of path: `L` and the link target for
+// a symlink, otherwise `F`, or `X` when any exec bit is set, and the sha256
+// of the content.
+func hashFile(path string) (string, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return "", err
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return "", err
+		}
+		return "L" + filepath.ToSlash(target), nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	sum := sha256.New()
+	if _, err := io.Copy(sum, file); err != nil {
+		return "", err
+	}
+
+	kind := "F"
+	if info.Mode().Perm()&0o111 != 0 {
+		kind = "X"
+	}
+
+	return kind + hex.EncodeToString(sum.Sum(nil)), nil
+}
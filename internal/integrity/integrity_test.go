@@ -0,0 +1,108 @@
+package integrity
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	cases := map[string][2]string{
+		"gh:org/repo@v1.0.0#sha256=abc":    {"gh:org/repo@v1.0.0", "sha256=abc"},
+		"https://x.dev/mod.tar.gz":         {"https://x.dev/mod.tar.gz", ""},
+		"https://x.dev/page#section":       {"https://x.dev/page#section", ""},
+		"git@host:org/repo.git@v1#sha512=": {"git@host:org/repo.git@v1", "sha512="},
+	}
+
+	for ref, want := range cases {
+		gotRef, gotPin := Split(ref)
+		if gotRef != want[0] || gotPin != want[1] {
+			t.Errorf("Split(%q) = %q, %q; want %q, %q", ref, gotRef, gotPin, want[0], want[1])
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := "sha256=" + strings.Repeat("a", 64)
+	if err := Validate(valid); err != nil {
+		t.Fatalf("expected %s to be valid: %v", valid, err)
+	}
+
+	for _, pin := range []string{"sha512=" + strings.Repeat("a", 128), "sha256=abc", "sha256=" + strings.Repeat("z", 64)} {
+		if err := Validate(pin); err == nil {
+			t.Errorf("expected %s to be rejected", pin)
+		}
+	}
+}
+
+func TestHashTreeAndVerify(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "spell"), []byte("a"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	hash, err := HashTree(dir)
+	if err != nil {
+		t.Fatalf("HashTree: %v", err)
+	}
+	if err := Verify(dir, Pin(hash)); err != nil {
+		t.Fatalf("expected the tree to verify: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, ".git"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".git", "HEAD"), []byte("ref"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := Verify(dir, Pin(hash)); err != nil {
+		t.Fatalf("expected .git to be ignored: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "spell"), []byte("b"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	err = Verify(dir, Pin(hash))
+	if err == nil || !strings.Contains(err.Error(), "integrity mismatch: expected "+Pin(hash)) {
+		t.Fatalf("expected a mismatch after the content changed, got: %v", err)
+	}
+}
+
+func TestHashTreeRecordsFileType(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks and exec bits are not portable to windows")
+	}
+
+	write := func(t *testing.T, setup func(dir string) error) string {
+		t.Helper()
+		dir := t.TempDir()
+		if err := setup(dir); err != nil {
+			t.Fatalf("setup: %v", err)
+		}
+		hash, err := HashTree(dir)
+		if err != nil {
+			t.Fatalf("HashTree: %v", err)
+		}
+		return hash
+	}
+
+	target := "/etc/passwd"
+	file := write(t, func(dir string) error {
+		return os.WriteFile(filepath.Join(dir, "run.sh"), []byte(target), 0o644)
+	})
+	link := write(t, func(dir string) error {
+		return os.Symlink(target, filepath.Join(dir, "run.sh"))
+	})
+	exec := write(t, func(dir string) error {
+		return os.WriteFile(filepath.Join(dir, "run.sh"), []byte(target), 0o755)
+	})
+
+	if file == link {
+		t.Fatalf("expected a symlink to hash differently from a file holding its target")
+	}
+	if file == exec {
+		t.Fatalf("expected the exec bit to change the hash")
+	}
+}
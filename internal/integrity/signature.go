@@ -0,0 +1,189 @@
+package integrity
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/frostyeti/cast/internal/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// SignatureFile is the detached signature kept at the root of a signed task
+// tree. HashTree skips it, so signing a tree does not change its pin.
+const SignatureFile = "cast.sig"
+
+const (
+	signatureVersion = 1
+	signatureContext = "cast-signature-v1\n"
+	ed25519KeyPrefix = "ed25519:"
+)
+
+// Signature is the content of a cast.sig file: the tree hash that was signed
+// and one ed25519 signature over it per signing key.
+type Signature struct {
+	Version    int            `json:"version"`
+	Tree       string         `json:"tree"`
+	Signatures []KeySignature `json:"signatures"`
+}
+
+// KeySignature is a signature made by Key, written as `ed25519:<base64>`.
+type KeySignature struct {
+	Key string `json:"key"`
+	Sig string `json:"sig"`
+}
+
+// ParsePublicKey reads an ed25519 public key written either as an OpenSSH
+// authorized key line (`ssh-ed25519 AAAA... comment`) or as
+// `ed25519:<base64>`.
+func ParsePublicKey(value string) (ed25519.PublicKey, error) {
+	value = strings.TrimSpace(value)
+
+	if encoded, ok := strings.CutPrefix(value, ed25519KeyPrefix); ok {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, errors.Newf("invalid ed25519 public key %q", value)
+		}
+		return ed25519.PublicKey(key), nil
+	}
+
+	parsed, _, _, _, err := ssh.ParseAuthorizedKey([]byte(value))
+	if err != nil {
+		return nil, errors.Newf("invalid public key %q: expected `ssh-ed25519 AAAA...` or `ed25519:<base64>`", value)
+	}
+	if parsed.Type() != ssh.KeyAlgoED25519 {
+		return nil, errors.Newf("unsupported public key type %s, only ed25519 keys can verify signatures", parsed.Type())
+	}
+
+	key, ok := parsed.(ssh.CryptoPublicKey).CryptoPublicKey().(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.Newf("invalid ed25519 public key %q", value)
+	}
+
+	return key, nil
+}
+
+// FormatPublicKey writes key in the `ed25519:<base64>` form.
+func FormatPublicKey(key ed25519.PublicKey) string {
+	return ed25519KeyPrefix + base64.StdEncoding.EncodeToString(key)
+}
+
+// ParsePrivateKey reads an ed25519 private key in OpenSSH or PKCS#8 PEM form,
+// as written by `ssh-keygen -t ed25519`. passphrase decrypts protected keys.
+func ParsePrivateKey(data, passphrase []byte) (ed25519.PrivateKey, error) {
+	var raw any
+	var err error
+	if len(passphrase) > 0 {
+		raw, err = ssh.ParseRawPrivateKeyWithPassphrase(data, passphrase)
+	} else {
+		raw, err = ssh.ParseRawPrivateKey(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := raw.(type) {
+	case ed25519.PrivateKey:
+		return key, nil
+	case *ed25519.PrivateKey:
+		return *key, nil
+	}
+
+	return nil, errors.Newf("unsupported private key type %T, only ed25519 keys can sign", raw)
+}
+
+// SignTree signs the tree hash of dir with key. Signatures in existing made
+// by other keys over the same tree are kept, so several maintainers can sign
+// one release.
+func SignTree(dir string, key ed25519.PrivateKey, existing *Signature) (*Signature, error) {
+	tree, err := HashTree(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	public := FormatPublicKey(key.Public().(ed25519.PublicKey))
+	sig := &Signature{Version: signatureVersion, Tree: tree}
+	if existing != nil && existing.Tree == tree {
+		for _, entry := range existing.Signatures {
+			if entry.Key != public {
+				sig.Signatures = append(sig.Signatures, entry)
+			}
+		}
+	}
+
+	sig.Signatures = append(sig.Signatures, KeySignature{
+		Key: public,
+		Sig: base64.StdEncoding.EncodeToString(ed25519.Sign(key, []byte(signatureContext+tree))),
+	})
+
+	return sig, nil
+}
+
+// ReadSignature reads a cast.sig file.
+func ReadSignature(path string) (*Signature, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sig := &Signature{}
+	if err := json.Unmarshal(data, sig); err != nil {
+		return nil, errors.Newf("failed to parse signature %s: %w", path, err)
+	}
+	if sig.Version > signatureVersion {
+		return nil, errors.Newf("signature %s uses version %d but cast supports version %d", path, sig.Version, signatureVersion)
+	}
+
+	return sig, nil
+}
+
+// WriteSignature writes sig to path.
+func WriteSignature(path string, sig *Signature) error {
+	data, err := json.MarshalIndent(sig, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// VerifyTree fails unless dir carries a cast.sig over its current content
+// made by one of keys.
+func VerifyTree(dir string, keys []ed25519.PublicKey) error {
+	sig, err := ReadSignature(filepath.Join(dir, SignatureFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errors.Newf("no %s found", SignatureFile)
+		}
+		return err
+	}
+
+	tree, err := HashTree(dir)
+	if err != nil {
+		return err
+	}
+	if sig.Tree != tree {
+		return errors.Newf("content changed after signing: %s covers %s, got %s", SignatureFile, Pin(sig.Tree), Pin(tree))
+	}
+
+	message := []byte(signatureContext + tree)
+	failure := errors.Newf("%s is not signed by a trusted key", SignatureFile)
+	for _, entry := range sig.Signatures {
+		signer, err := ParsePublicKey(entry.Key)
+		if err != nil || !slices.ContainsFunc(keys, func(key ed25519.PublicKey) bool { return signer.Equal(key) }) {
+			continue
+		}
+
+		raw, err := base64.StdEncoding.DecodeString(entry.Sig)
+		if err == nil && ed25519.Verify(signer, message, raw) {
+			return nil
+		}
+		failure = errors.Newf("invalid signature by %s", entry.Key)
+	}
+
+	return failure
+}
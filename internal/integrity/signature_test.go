@@ -0,0 +1,106 @@
+package integrity
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func newSigningKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	return key
+}
+
+func TestSignAndVerifyTree(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "cast.task"), []byte("name: hello\n"), 0o644); err != nil {
+		t.Fatalf("write cast.task: %v", err)
+	}
+
+	before, err := HashTree(dir)
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+
+	alice, bob := newSigningKey(t), newSigningKey(t)
+	sig, err := SignTree(dir, alice, nil)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	sig, err = SignTree(dir, bob, sig)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	if len(sig.Signatures) != 2 {
+		t.Fatalf("expected both signatures to be kept, got %+v", sig.Signatures)
+	}
+	if err := WriteSignature(filepath.Join(dir, SignatureFile), sig); err != nil {
+		t.Fatalf("write signature: %v", err)
+	}
+
+	after, err := HashTree(dir)
+	if err != nil || after != before {
+		t.Fatalf("expected cast.sig to leave the tree hash unchanged, got %s and %s: %v", before, after, err)
+	}
+
+	if err := VerifyTree(dir, []ed25519.PublicKey{bob.Public().(ed25519.PublicKey)}); err != nil {
+		t.Fatalf("expected a trusted signature to verify: %v", err)
+	}
+
+	if err := VerifyTree(dir, []ed25519.PublicKey{newSigningKey(t).Public().(ed25519.PublicKey)}); err == nil || !strings.Contains(err.Error(), "not signed by a trusted key") {
+		t.Fatalf("expected an untrusted signer to be rejected, got: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "cast.task"), []byte("name: evil\n"), 0o644); err != nil {
+		t.Fatalf("tamper: %v", err)
+	}
+	if err := VerifyTree(dir, []ed25519.PublicKey{alice.Public().(ed25519.PublicKey)}); err == nil || !strings.Contains(err.Error(), "content changed after signing") {
+		t.Fatalf("expected tampered content to be rejected, got: %v", err)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	key := newSigningKey(t)
+	public := key.Public().(ed25519.PublicKey)
+
+	sshPublic, err := ssh.NewPublicKey(public)
+	if err != nil {
+		t.Fatalf("ssh public key: %v", err)
+	}
+	authorized := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPublic))) + " release@example.com"
+
+	for _, value := range []string{authorized, FormatPublicKey(public)} {
+		parsed, err := ParsePublicKey(value)
+		if err != nil || !parsed.Equal(public) {
+			t.Fatalf("failed to parse %q: %v", value, err)
+		}
+	}
+	if _, err := ParsePublicKey("ed25519:AAAA"); err == nil {
+		t.Fatal("expected a short key to be rejected")
+	}
+
+	block, err := ssh.MarshalPrivateKeyWithPassphrase(key, "", []byte("secret"))
+	if err != nil {
+		t.Fatalf("marshal private key: %v", err)
+	}
+	data := pem.EncodeToMemory(block)
+	if _, err := ParsePrivateKey(data, nil); err == nil {
+		t.Fatal("expected a protected key to need its passphrase")
+	}
+	parsed, err := ParsePrivateKey(data, []byte("secret"))
+	if err != nil || !parsed.Equal(key) {
+		t.Fatalf("failed to parse the private key: %v", err)
+	}
+}
@@ -1,26 +1,30 @@
 package modules
 
 import (
-	"archive/tar"
-	"compress/gzip"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/frostyeti/cast/internal/archive"
 	"github.com/frostyeti/cast/internal/errors"
-	"github.com/frostyeti/go/exec"
+	"github.com/frostyeti/cast/internal/integrity"
+	"github.com/frostyeti/cast/internal/offline"
 )
 
-func FetchModule(projectDir string, uri string) (string, error) {
-	// Create cache directory .cast/cache/modules
-	modulesDir := filepath.Join(projectDir, ".cast", "cache", "modules")
-	if err := os.MkdirAll(modulesDir, 0o755); err != nil {
-		return "", err
+// FetchModule downloads a module tarball into modulesDir, the module cache
+// of the project, and returns the extracted directory. Git module references
+// are resolved by the projects package together with remote tasks. When pin
+// is set, such as `sha256=<hex>`, the extracted tree must match it; cached
+// modules are verified again on every load. In offline mode only cached
+// modules resolve.
+func FetchModule(modulesDir string, uri string, pin string) (string, error) {
+	if pin != "" {
+		if err := integrity.Validate(pin); err != nil {
+			return "", errors.Newf("module %s has an invalid integrity pin: %w", uri, err)
+		}
 	}
 
 	// Key directory by hash of URI to ensure unique version keys
@@ -31,129 +35,51 @@ func FetchModule(projectDir string, uri string) (string, error) {
 	parts := strings.Split(uri, "/")
 	namePart := parts[len(parts)-1]
 	namePart = strings.ReplaceAll(namePart, ".tar.gz", "")
-	namePart = strings.ReplaceAll(namePart, ".git", "")
 
 	targetDir := filepath.Join(modulesDir, fmt.Sprintf("%s-%s", namePart, hashStr[:8]))
 
 	if _, err := os.Stat(targetDir); err == nil {
 		// Already cached
-		return targetDir, nil
-	}
-
-	if strings.HasSuffix(uri, ".tar.gz") || strings.HasPrefix(uri, "http") && strings.Contains(uri, "tar.gz") {
-		err := fetchTarball(uri, targetDir)
-		if err != nil {
-			return "", err
-		}
-		return targetDir, nil
-	}
-
-	if strings.HasPrefix(uri, "github.com/") || strings.HasPrefix(uri, "git@") || strings.HasSuffix(uri, ".git") {
-		err := fetchGit(uri, targetDir)
-		if err != nil {
-			return "", err
+		if err := verifyModule(targetDir, pin); err != nil {
+			_ = os.RemoveAll(targetDir)
+			return "", errors.Newf("cached module %s failed verification: %w", uri, err)
 		}
 		return targetDir, nil
 	}
 
-	return "", errors.Newf("unsupported module URI: %s", uri)
-}
-
-func fetchGit(uri, targetDir string) error {
-	// Handle github.com/user/repo@v1.0.0
-	parts := strings.Split(uri, "@")
-	repoPath := parts[0]
-	version := "main"
-	if len(parts) > 1 {
-		version = parts[1]
+	if !strings.HasSuffix(uri, ".tar.gz") && !(strings.HasPrefix(uri, "http") && strings.Contains(uri, "tar.gz")) {
+		return "", errors.Newf("unsupported module URI: %s", uri)
 	}
 
-	repoURL := repoPath
-	if strings.HasPrefix(repoPath, "github.com/") {
-		repoURL = "https://" + repoPath
-		if !strings.HasSuffix(repoURL, ".git") {
-			repoURL += ".git"
-		}
+	if offline.Enabled() {
+		return "", offline.NotCachedError("module " + uri)
 	}
 
-	cmd := exec.New("git", "clone", "--depth", "1", "--branch", version, repoURL, targetDir)
-	out, err := cmd.Run()
-	if err != nil || out.Code != 0 {
-		return errors.Newf("failed to clone module %s: %v\n%s", uri, err, out.Stdout)
+	err := archive.Stage(targetDir, func(tmpDir string) error {
+		return fetchTarball(uri, tmpDir, pin)
+	})
+	if err != nil {
+		return "", err
 	}
 
-	return nil
+	return targetDir, nil
 }
 
-func fetchTarball(uri, targetDir string) error {
-	resp, err := http.Get(uri)
-	if err != nil {
-		return err
-	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
-
-	if resp.StatusCode != http.StatusOK {
-		return errors.Newf("failed to download %s: status %d", uri, resp.StatusCode)
+func verifyModule(dir, pin string) error {
+	if pin == "" {
+		return nil
 	}
 
-	if err := os.MkdirAll(targetDir, 0o755); err != nil {
-		return err
-	}
+	return integrity.Verify(dir, pin)
+}
 
-	gzr, err := gzip.NewReader(resp.Body)
-	if err != nil {
+func fetchTarball(uri, targetDir, pin string) error {
+	if err := archive.Download(uri, targetDir, ""); err != nil {
 		return err
 	}
-	defer func() {
-		_ = gzr.Close()
-	}()
-
-	tr := tar.NewReader(gzr)
 
-	for {
-		header, err := tr.Next()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return err
-		}
-
-		// Some tarballs contain a root directory, we might want to strip it,
-		// but for simplicity we just extract as is.
-		targetPath := filepath.Join(targetDir, header.Name)
-
-		// Protect against directory traversal
-		if !strings.HasPrefix(targetPath, filepath.Clean(targetDir)+string(os.PathSeparator)) && targetPath != filepath.Clean(targetDir) {
-			continue
-		}
-
-		switch header.Typeflag {
-		case tar.TypeDir:
-			if err := os.MkdirAll(targetPath, 0755); err != nil {
-				return err
-			}
-		case tar.TypeReg:
-			if err := os.MkdirAll(filepath.Dir(targetPath), 0o755); err != nil {
-				return err
-			}
-			outFile, err := os.Create(targetPath)
-			if err != nil {
-				return err
-			}
-			if _, err := io.Copy(outFile, tr); err != nil {
-				_ = outFile.Close()
-				return err
-			}
-			if err := outFile.Close(); err != nil {
-				return err
-			}
-			if err := os.Chmod(targetPath, os.FileMode(header.Mode)); err != nil {
-				return err
-			}
-		}
+	if err := verifyModule(targetDir, pin); err != nil {
+		return errors.Newf("module %s failed verification: %w", uri, err)
 	}
 
 	return nil
@@ -0,0 +1,117 @@
+package modules
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/frostyeti/cast/internal/integrity"
+	"github.com/frostyeti/cast/internal/offline"
+)
+
+func moduleTarball(t *testing.T, content string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Name: "cast.module.yaml", Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatalf("write header: %v", err)
+	}
+	if _, err := tw.Write([]byte(content)); err != nil {
+		t.Fatalf("write content: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("close gzip: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestFetchModule_VerifiesTarballIntegrity(t *testing.T) {
+	archive := moduleTarball(t, "tasks:\n  hello: echo hello\n")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(archive)
+	}))
+	defer server.Close()
+
+	expectedDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(expectedDir, "cast.module.yaml"), []byte("tasks:\n  hello: echo hello\n"), 0o644); err != nil {
+		t.Fatalf("write expected module: %v", err)
+	}
+	hash, err := integrity.HashTree(expectedDir)
+	if err != nil {
+		t.Fatalf("hash expected module: %v", err)
+	}
+	pin := integrity.Pin(hash)
+	uri := server.URL + "/shared.tar.gz"
+
+	modulesDir := filepath.Join(t.TempDir(), "modules")
+	_, err = FetchModule(modulesDir, uri, "sha256="+strings.Repeat("0", 64))
+	if err == nil || !strings.Contains(err.Error(), "integrity mismatch") {
+		t.Fatalf("expected a mismatched pin to be refused, got: %v", err)
+	}
+	entries, _ := os.ReadDir(modulesDir)
+	if len(entries) != 0 {
+		t.Fatalf("expected nothing to be cached after a failed verification, got %v", entries)
+	}
+
+	dir, err := FetchModule(modulesDir, uri, pin)
+	if err != nil {
+		t.Fatalf("expected the pinned tarball to verify, got: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "cast.module.yaml"), []byte("tasks: {}\n"), 0o644); err != nil {
+		t.Fatalf("tamper cached module: %v", err)
+	}
+	_, err = FetchModule(modulesDir, uri, pin)
+	if err == nil || !strings.Contains(err.Error(), "cached module") {
+		t.Fatalf("expected the cached module to be verified again, got: %v", err)
+	}
+}
+
+func TestFetchModule_OfflineUsesOnlyTheCache(t *testing.T) {
+	requests := 0
+	archive := moduleTarball(t, "tasks:\n  hello: echo hello\n")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write(archive)
+	}))
+	defer server.Close()
+
+	uri := server.URL + "/shared.tar.gz"
+	modulesDir := t.TempDir()
+
+	t.Setenv(offline.Env, "1")
+	_, err := FetchModule(modulesDir, uri, "")
+	if err == nil || !strings.Contains(err.Error(), "cast is offline") {
+		t.Fatalf("expected an offline error for an uncached module, got: %v", err)
+	}
+
+	t.Setenv(offline.Env, "")
+	dir, err := FetchModule(modulesDir, uri, "")
+	if err != nil {
+		t.Fatalf("fetch online: %v", err)
+	}
+
+	t.Setenv(offline.Env, "1")
+	cached, err := FetchModule(modulesDir, uri, "")
+	if err != nil {
+		t.Fatalf("fetch cached module offline: %v", err)
+	}
+	if cached != dir {
+		t.Fatalf("expected cached dir %s, got %s", dir, cached)
+	}
+	if requests != 1 {
+		t.Fatalf("expected a single download, got %d", requests)
+	}
+}
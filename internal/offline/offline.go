@@ -0,0 +1,35 @@
+// Package offline reports whether cast runs in offline mode, where remote
+// tasks, modules and tools are only resolved from local caches.
+package offline
+
+import (
+	"os"
+	"strings"
+
+	"github.com/frostyeti/cast/internal/errors"
+)
+
+// Env is the environment variable that enables offline mode. The --offline
+// flag sets it so every package and child cast process sees the same mode.
+const Env = "CAST_OFFLINE"
+
+// Enabled reports whether offline mode is on.
+func Enabled() bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(Env))) {
+	case "1", "true", "yes", "on":
+		return true
+	default:
+		return false
+	}
+}
+
+// Enable turns offline mode on for this process and its children.
+func Enable() error {
+	return os.Setenv(Env, "1")
+}
+
+// NotCachedError reports that what is not in the local cache and cannot be
+// fetched while offline.
+func NotCachedError(what string) error {
+	return errors.Newf("%s is not cached and cast is offline; run `cast task install` while online to prefetch it", what)
+}
@@ -0,0 +1,308 @@
+package projects
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// cacheMetadataSuffix names the file written next to a cache entry to record
+// the ref it was fetched for and when it was last used. It sits beside the
+// entry rather than inside it so content hashes and signatures are unchanged.
+const cacheMetadataSuffix = ".cast-cache.json"
+
+// moduleTarballDirPattern matches the directories modules.FetchModule
+// extracts tarballs into: the archive name and 8 hex digits of its URI hash.
+var moduleTarballDirPattern = regexp.MustCompile(`-[0-9a-f]{8}$`)
+
+// CacheEntry is a remote task or module checked out in one of the caches.
+type CacheEntry struct {
+	Dir      string    `json:"dir"`
+	Scope    string    `json:"scope"`
+	Kind     string    `json:"kind"`
+	Ref      string    `json:"ref,omitempty"`
+	Resolved string    `json:"resolved,omitempty"`
+	Commit   string    `json:"commit,omitempty"`
+	Size     int64     `json:"size"`
+	LastUsed time.Time `json:"last_used"`
+
+	root string
+}
+
+// PruneCacheOptions bounds the caches. Entries unused for longer than
+// OlderThan are removed first, then the least recently used entries until
+// the caches fit in MaxSize bytes. A zero value disables that bound.
+type PruneCacheOptions struct {
+	MaxSize   int64
+	OlderThan time.Duration
+	DryRun    bool
+	Now       time.Time
+}
+
+type cacheMetadata struct {
+	Ref      string    `json:"ref"`
+	Resolved string    `json:"resolved,omitempty"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+type cacheRoot struct {
+	dir   string
+	scope string
+	kind  string
+}
+
+// recordCacheUse stamps the cache entry dir with ref and the current time.
+// It is best effort: a read-only cache must not fail the run.
+func recordCacheUse(dir, ref, resolved string) {
+	data, err := json.Marshal(cacheMetadata{Ref: ref, Resolved: resolved, LastUsed: time.Now().UTC()})
+	if err != nil {
+		return
+	}
+
+	dir = filepath.Clean(dir)
+	tmp, err := os.CreateTemp(filepath.Dir(dir), "."+filepath.Base(dir)+".*.tmp")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), dir+cacheMetadataSuffix)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+}
+
+func readCacheMetadata(dir string) (cacheMetadata, bool) {
+	meta := cacheMetadata{}
+	data, err := os.ReadFile(dir + cacheMetadataSuffix)
+	if err != nil {
+		return meta, false
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return meta, false
+	}
+
+	return meta, true
+}
+
+// cacheRoots returns the stable and volatile task and module caches of the
+// project in projectDir. A volatile cache configured onto a stable one is
+// listed once.
+func cacheRoots(projectDir string) []cacheRoot {
+	candidates := []cacheRoot{
+		{dir: stableRemoteTasksDir(projectDir), scope: "stable", kind: "task"},
+		{dir: ResolveVolatileRemoteTasksDir(projectDir), scope: "volatile", kind: "task"},
+		{dir: stableRemoteModulesDir(projectDir), scope: "stable", kind: "module"},
+		{dir: ResolveVolatileRemoteModulesDir(projectDir), scope: "volatile", kind: "module"},
+	}
+
+	roots := []cacheRoot{}
+	seen := map[string]bool{}
+	for _, root := range candidates {
+		root.dir = filepath.Clean(root.dir)
+		if seen[root.dir] {
+			continue
+		}
+		seen[root.dir] = true
+		roots = append(roots, root)
+	}
+
+	return roots
+}
+
+// ListCache returns the remote tasks and modules in the caches of the
+// project in projectDir, least recently used first. Entries fetched before
+// usage was recorded fall back to their modification time.
+func ListCache(projectDir string) ([]CacheEntry, error) {
+	entries := []CacheEntry{}
+	for _, root := range cacheRoots(projectDir) {
+		found, err := listCacheRoot(root)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, found...)
+	}
+
+	slices.SortStableFunc(entries, func(a, b CacheEntry) int {
+		return a.LastUsed.Compare(b.LastUsed)
+	})
+
+	return entries, nil
+}
+
+func listCacheRoot(root cacheRoot) ([]CacheEntry, error) {
+	entries := []CacheEntry{}
+	err := filepath.WalkDir(root.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == root.dir {
+				return filepath.SkipAll
+			}
+			return err
+		}
+		if !d.IsDir() || path == root.dir {
+			return nil
+		}
+		// in-progress downloads and clones, and the .git of a checkout.
+		if strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+
+		if !isCacheEntry(root, path) {
+			return nil
+		}
+
+		entry, err := newCacheEntry(root, path)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+		return filepath.SkipDir
+	})
+
+	return entries, err
+}
+
+// isCacheEntry reports whether path is a single cached task or module: a git
+// checkout, a registry spell version, a module tarball, or any directory a
+// fetch has recorded usage for.
+func isCacheEntry(root cacheRoot, path string) bool {
+	if _, err := os.Stat(path + cacheMetadataSuffix); err == nil {
+		return true
+	}
+	if _, err := os.Stat(filepath.Join(path, ".git")); err == nil {
+		return true
+	}
+
+	rel, err := filepath.Rel(root.dir, path)
+	if err != nil {
+		return false
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+
+	if root.kind == "task" {
+		// registry/<hash>/spells/<name>/<version>, where name may be scoped.
+		if len(parts) >= 5 && parts[0] == registryCacheDirName && parts[2] == registrySpellsDirName {
+			_, _, _, ok := parseVersionParts(parts[len(parts)-1])
+			return ok
+		}
+		return false
+	}
+
+	return len(parts) == 1 && moduleTarballDirPattern.MatchString(parts[0])
+}
+
+func newCacheEntry(root cacheRoot, dir string) (CacheEntry, error) {
+	entry := CacheEntry{
+		Dir:    dir,
+		Scope:  root.scope,
+		Kind:   root.kind,
+		Commit: gitHeadCommit(dir),
+		root:   root.dir,
+	}
+
+	if meta, ok := readCacheMetadata(dir); ok {
+		entry.Ref = meta.Ref
+		entry.Resolved = meta.Resolved
+		entry.LastUsed = meta.LastUsed
+	} else {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return entry, err
+		}
+		entry.LastUsed = info.ModTime().UTC()
+	}
+
+	size, err := dirSize(dir)
+	if err != nil {
+		return entry, err
+	}
+	entry.Size = size
+
+	return entry, nil
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+
+	return size, err
+}
+
+// PruneCache evicts cache entries of the project in projectDir as bounded
+// by opts, least recently used first, and returns the evicted entries.
+func PruneCache(projectDir string, opts PruneCacheOptions) ([]CacheEntry, error) {
+	entries, err := ListCache(projectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	var total int64
+	for _, entry := range entries {
+		total += entry.Size
+	}
+
+	evicted := []CacheEntry{}
+	for _, entry := range entries {
+		expired := opts.OlderThan > 0 && now.Sub(entry.LastUsed) > opts.OlderThan
+		oversized := opts.MaxSize > 0 && total > opts.MaxSize
+		if !expired && !oversized {
+			continue
+		}
+
+		if !opts.DryRun {
+			if err := removeCacheEntry(entry); err != nil {
+				return evicted, err
+			}
+		}
+		total -= entry.Size
+		evicted = append(evicted, entry)
+	}
+
+	return evicted, nil
+}
+
+// removeCacheEntry deletes entry and its usage record, then the parent
+// directories it leaves empty, up to the cache root.
+func removeCacheEntry(entry CacheEntry) error {
+	if err := os.RemoveAll(entry.Dir); err != nil {
+		return err
+	}
+	if err := os.Remove(entry.Dir + cacheMetadataSuffix); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	for dir := filepath.Dir(entry.Dir); dir != entry.root && strings.HasPrefix(dir, entry.root); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package projects
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListAndPruneCache(t *testing.T) {
+	repoDir, projectDir, projectFile := setupLockfileRemote(t)
+	t.Setenv(CastRemoteModulesDirEnv, filepath.Join(filepath.Dir(projectDir), "modules"))
+	uses := "file://" + filepath.ToSlash(repoDir) + "@v1.0.0/hello"
+
+	project := newLockfileProject(projectDir, projectFile, LockAuto)
+	if _, err := FetchRemoteTaskWithOptions(project, uses, nil, FetchRemoteTaskOptions{Stdout: io.Discard}); err != nil {
+		t.Fatalf("failed to fetch remote task: %v", err)
+	}
+
+	// a module tarball extracted before usage was recorded.
+	staleModule := filepath.Join(ResolveVolatileRemoteModulesDir(projectDir), "tools-0123abcd")
+	if err := os.MkdirAll(staleModule, 0o755); err != nil {
+		t.Fatalf("mkdir module: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(staleModule, "mod.ts"), []byte("export const x = 1;\n"), 0o644); err != nil {
+		t.Fatalf("write module: %v", err)
+	}
+	old := time.Now().Add(-60 * 24 * time.Hour)
+	if err := os.Chtimes(staleModule, old, old); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+
+	entries, err := ListCache(projectDir)
+	if err != nil {
+		t.Fatalf("ListCache failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 cache entries, got %+v", entries)
+	}
+	if entries[0].Dir != staleModule || entries[0].Scope != "volatile" || entries[0].Kind != "module" || entries[0].Ref != "" {
+		t.Fatalf("expected the stale module first, got %+v", entries[0])
+	}
+
+	task := entries[1]
+	commit := strings.TrimSpace(runGitOutput(t, repoDir, "rev-parse", "v1.0.0"))
+	if task.Scope != "stable" || task.Kind != "task" || task.Ref != uses || task.Resolved != "v1.0.0" || task.Commit != commit {
+		t.Fatalf("unexpected task entry: %+v", task)
+	}
+	if task.Size <= 0 || time.Since(task.LastUsed) > time.Minute {
+		t.Fatalf("expected a sized, recently used task entry, got %+v", task)
+	}
+
+	// a cache hit refreshes the last used time.
+	aged, _ := json.Marshal(cacheMetadata{Ref: uses, Resolved: "v1.0.0", LastUsed: old})
+	if err := os.WriteFile(task.Dir+cacheMetadataSuffix, aged, 0o644); err != nil {
+		t.Fatalf("age metadata: %v", err)
+	}
+	if _, err := FetchRemoteTaskWithOptions(project, uses, nil, FetchRemoteTaskOptions{Stdout: io.Discard}); err != nil {
+		t.Fatalf("failed to load cached task: %v", err)
+	}
+	if meta, ok := readCacheMetadata(task.Dir); !ok || time.Since(meta.LastUsed) > time.Minute {
+		t.Fatalf("expected the cache hit to record its use, got %+v", meta)
+	}
+
+	evicted, err := PruneCache(projectDir, PruneCacheOptions{OlderThan: 30 * 24 * time.Hour, DryRun: true})
+	if err != nil {
+		t.Fatalf("PruneCache dry run failed: %v", err)
+	}
+	if len(evicted) != 1 || evicted[0].Dir != staleModule {
+		t.Fatalf("expected the dry run to select the stale module, got %+v", evicted)
+	}
+	if _, err := os.Stat(staleModule); err != nil {
+		t.Fatalf("dry run removed the module: %v", err)
+	}
+
+	if _, err := PruneCache(projectDir, PruneCacheOptions{OlderThan: 30 * 24 * time.Hour}); err != nil {
+		t.Fatalf("PruneCache failed: %v", err)
+	}
+	if _, err := os.Stat(staleModule); !os.IsNotExist(err) {
+		t.Fatalf("expected the stale module to be removed, got %v", err)
+	}
+	if _, err := os.Stat(task.Dir); err != nil {
+		t.Fatalf("expected the recently used task to be kept: %v", err)
+	}
+
+	evicted, err = PruneCache(projectDir, PruneCacheOptions{MaxSize: 1})
+	if err != nil {
+		t.Fatalf("PruneCache by size failed: %v", err)
+	}
+	if len(evicted) != 1 || evicted[0].Dir != task.Dir {
+		t.Fatalf("expected the task to be evicted to fit the size, got %+v", evicted)
+	}
+	if _, err := os.Stat(task.Dir + cacheMetadataSuffix); !os.IsNotExist(err) {
+		t.Fatalf("expected the usage record to be removed, got %v", err)
+	}
+	stable := stableRemoteTasksDir(projectDir)
+	if _, err := os.Stat(stable); err != nil {
+		t.Fatalf("expected the cache root to be kept: %v", err)
+	}
+	if left, _ := os.ReadDir(stable); len(left) != 0 {
+		t.Fatalf("expected empty parent directories to be removed, got %v", left)
+	}
+}
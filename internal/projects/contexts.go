@@ -0,0 +1,80 @@
+package projects
+
+import (
+	"slices"
+
+	"github.com/frostyeti/cast/internal/types"
+)
+
+// contextVars returns the project vars with the vars of every context in the
+// active chain merged over them.
+func (p *Project) contextVars() *types.Vars {
+	if len(p.contextChain) == 0 {
+		return p.Schema.Vars
+	}
+
+	vars := p.Schema.Vars.Clone()
+	for _, ctx := range p.contextChain {
+		vars.Merge(ctx.Vars)
+	}
+
+	return vars
+}
+
+// contextInventories returns the inventories declared by the active context
+// chain that the project does not already reference.
+func (p *Project) contextInventories() []string {
+	inventories := []string{}
+	for _, ctx := range p.contextChain {
+		for _, inv := range ctx.Inventories {
+			if !slices.Contains(p.Schema.Inventories, inv) && !slices.Contains(inventories, inv) {
+				inventories = append(inventories, inv)
+			}
+		}
+	}
+
+	return inventories
+}
+
+// contextHosts returns the default hosts of the active context. A context
+// without hosts inherits the hosts of the context it extends.
+func (p *Project) contextHosts() []string {
+	for i := len(p.contextChain) - 1; i >= 0; i-- {
+		if len(p.contextChain[i].Hosts) > 0 {
+			return p.contextChain[i].Hosts
+		}
+	}
+
+	return nil
+}
+
+// loadContextEnv layers the dotenv files and env of each context in the
+// active chain, parent first. Dotenv entries inside a context block always
+// apply, so their own `contexts` filter is ignored.
+func loadContextEnv(p *Project, t *envTracker, e *types.Env, substitution bool) error {
+	for _, ctx := range p.contextChain {
+		if ctx.DotEnv != nil {
+			section := types.DotEnvs{}
+			for _, de := range *ctx.DotEnv {
+				de.Contexts = nil
+				section = append(section, de)
+			}
+
+			err := trackDotEnvFiles(t, section, e, "", substitution, p.Dir)
+			if err != nil {
+				return err
+			}
+		}
+
+		if ctx.Env != nil {
+			err := t.track(e, "context:"+ctx.Name, p.File, nil, func() error {
+				return loadEnv(ctx.Env, e, substitution)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package projects_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/frostyeti/cast/internal/projects"
+)
+
+func TestRunTask_InlineContextsLayerOverProjectSettings(t *testing.T) {
+	projectDir := t.TempDir()
+	projectFile := filepath.Join(projectDir, "castfile.yaml")
+
+	content := `
+name: contexts-demo
+env:
+  REGION: local
+  TIER: dev
+vars:
+  replicas: 1
+contexts:
+  shared:
+    env:
+      REGION: us-east
+    dotenv: ./shared.env
+    vars:
+      replicas: 2
+  staging:
+    extends: shared
+    env:
+      TIER: staging
+  prod:
+    extends: shared
+    env:
+      TIER: prod
+    vars:
+      replicas: 5
+tasks:
+  show:
+    uses: bash
+    template: gotmpl
+    run: echo "$REGION $TIER ${SHARED:-none} {{ .vars.replicas }}"
+`
+
+	if err := os.WriteFile(projectFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write castfile: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "shared.env"), []byte("SHARED=yes\n"), 0o644); err != nil {
+		t.Fatalf("failed to write dotenv: %v", err)
+	}
+
+	cases := map[string]string{
+		"default": "local dev none 1",
+		"staging": "us-east staging yes 2",
+		"prod":    "us-east prod yes 5",
+	}
+
+	for contextName, want := range cases {
+		proj := &projects.Project{}
+		if err := proj.LoadFromYaml(projectFile); err != nil {
+			t.Fatalf("failed to load project: %v", err)
+		}
+
+		var stdout bytes.Buffer
+		_, err := proj.RunTask(projects.RunTasksParams{
+			Targets:     []string{"show"},
+			Context:     context.Background(),
+			ContextName: contextName,
+			Stdout:      &stdout,
+			Stderr:      &stdout,
+		})
+		if err != nil {
+			t.Fatalf("%s: failed to run task: %v\nOutput: %s", contextName, err, stdout.String())
+		}
+
+		if !strings.Contains(stdout.String(), want) {
+			t.Fatalf("%s: expected %q, got: %s", contextName, want, stdout.String())
+		}
+	}
+}
+
+func TestInit_RejectsCircularContextExtends(t *testing.T) {
+	projectDir := t.TempDir()
+	projectFile := filepath.Join(projectDir, "castfile.yaml")
+
+	content := `
+name: contexts-loop
+contexts:
+  a:
+    extends: b
+  b:
+    extends: a
+tasks:
+  noop: echo noop
+`
+
+	if err := os.WriteFile(projectFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write castfile: %v", err)
+	}
+
+	proj := &projects.Project{ContextName: "a"}
+	if err := proj.LoadFromYaml(projectFile); err != nil {
+		t.Fatalf("failed to load project: %v", err)
+	}
+
+	err := proj.Init()
+	if err == nil || !strings.Contains(err.Error(), "circular extends chain") {
+		t.Fatalf("expected circular extends error, got: %v", err)
+	}
+}
+
+func TestInit_ContextInventoriesLeaveSchemaUnchanged(t *testing.T) {
+	projectDir := t.TempDir()
+	projectFile := filepath.Join(projectDir, "castfile.yaml")
+
+	content := `
+name: contexts-inventory
+contexts:
+  prod:
+    inventories: [./prod-inv.yaml]
+tasks:
+  noop: echo noop
+`
+
+	if err := os.WriteFile(projectFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write castfile: %v", err)
+	}
+	invContent := `
+hosts:
+  prod-db:
+    host: 10.0.0.1
+`
+	if err := os.WriteFile(filepath.Join(projectDir, "prod-inv.yaml"), []byte(invContent), 0o644); err != nil {
+		t.Fatalf("failed to write inventory: %v", err)
+	}
+
+	proj := &projects.Project{ContextName: "prod"}
+	if err := proj.LoadFromYaml(projectFile); err != nil {
+		t.Fatalf("failed to load project: %v", err)
+	}
+	if err := proj.Init(); err != nil {
+		t.Fatalf("failed to init project: %v", err)
+	}
+
+	if _, ok := proj.Hosts["prod-db"]; !ok {
+		t.Fatalf("expected the context inventory to be loaded, got %v", proj.Hosts)
+	}
+	if len(proj.Schema.Inventories) != 0 {
+		t.Fatalf("expected the parsed inventories to stay as written, got %v", proj.Schema.Inventories)
+	}
+}
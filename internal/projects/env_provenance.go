@@ -0,0 +1,222 @@
+package projects
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/frostyeti/cast/internal/errors"
+	"github.com/frostyeti/cast/internal/types"
+	"github.com/frostyeti/go/dotenv"
+)
+
+// EnvSource records a layer that set a variable and the value it set.
+type EnvSource struct {
+	Layer string `json:"layer"`
+	File  string `json:"file,omitempty"`
+	Value string `json:"value"`
+}
+
+// EnvEntry is a resolved variable along with the layer that set its final
+// value and the earlier layers it replaced, oldest first.
+type EnvEntry struct {
+	Name       string      `json:"name"`
+	Value      string      `json:"value"`
+	Secret     bool        `json:"secret,omitempty"`
+	Source     EnvSource   `json:"source"`
+	Overridden []EnvSource `json:"overridden,omitempty"`
+}
+
+// envTracker attributes each variable change to the layer that made it while
+// the project and task environments are assembled.
+type envTracker struct {
+	sources map[string][]EnvSource
+}
+
+func newEnvTracker() *envTracker {
+	return &envTracker{sources: map[string][]EnvSource{}}
+}
+
+func (t *envTracker) clone() *envTracker {
+	clone := newEnvTracker()
+	if t == nil {
+		return clone
+	}
+
+	for k, v := range t.sources {
+		clone.sources[k] = append([]EnvSource{}, v...)
+	}
+
+	return clone
+}
+
+// track runs fn and records every variable it adds or changes in e against
+// layer. fileFor, when set, names the file an individual key came from.
+func (t *envTracker) track(e *types.Env, layer, file string, fileFor map[string]string, fn func() error) error {
+	if t == nil {
+		return fn()
+	}
+
+	before := e.ToMap()
+	if err := fn(); err != nil {
+		return err
+	}
+
+	for k, v := range e.Map {
+		if old, ok := before[k]; ok && old == v {
+			continue
+		}
+
+		source := EnvSource{Layer: layer, File: file, Value: v}
+		if f, ok := fileFor[k]; ok {
+			source.File = f
+		}
+
+		t.sources[k] = append(t.sources[k], source)
+	}
+
+	return nil
+}
+
+// dotenvKeyFiles maps each key to the last dotenv file that defines it.
+func dotenvKeyFiles(files []string) map[string]string {
+	keyFiles := map[string]string{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+
+		doc, err := dotenv.Parse(string(data))
+		if err != nil {
+			continue
+		}
+
+		for _, node := range doc.ToArray() {
+			if node.Type != dotenv.VARIABLE || node.Key == nil || *node.Key == "" {
+				continue
+			}
+			keyFiles[*node.Key] = file
+		}
+	}
+
+	return keyFiles
+}
+
+// ResolveTask returns the task a target resolves to for the active context,
+// preferring a `target:context` variant when one exists.
+func (p *Project) ResolveTask(target string) (types.Task, bool) {
+	return p.Tasks.Resolve(target, p.ContextName)
+}
+
+// ResolveEnv returns the environment a task would receive using the same
+// layering as RunTask, with the layer and file that set each variable.
+// When taskName is empty only the project environment is resolved.
+func (p *Project) ResolveEnv(taskName string, overrides map[string]string) ([]EnvEntry, error) {
+	if err := p.Init(); err != nil {
+		return nil, err
+	}
+
+	tracker := p.envTracker.clone()
+	e := p.Env.Clone()
+
+	if len(overrides) > 0 {
+		keys := make([]string, 0, len(overrides))
+		for k := range overrides {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		err := tracker.track(e, "override", "", nil, func() error {
+			for _, k := range keys {
+				e.Set(k, overrides[k])
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if taskName != "" {
+		task, ok := p.ResolveTask(taskName)
+		if !ok {
+			return nil, errors.Newf("task %s not found", taskName)
+		}
+
+		if err := resolveTaskEnv(p.taskDir(task), task, e, tracker, p.taskFile(task)); err != nil {
+			return nil, err
+		}
+	}
+
+	names := make([]string, 0, len(e.Map))
+	for k := range e.Map {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	entries := make([]EnvEntry, 0, len(names))
+	for _, name := range names {
+		entry := EnvEntry{
+			Name:   name,
+			Value:  e.Map[name],
+			Secret: e.IsSecret(name),
+			Source: EnvSource{Layer: "unknown", Value: e.Map[name]},
+		}
+
+		history := tracker.sources[name]
+		if len(history) > 0 {
+			entry.Source = history[len(history)-1]
+			entry.Overridden = append([]EnvSource{}, history[:len(history)-1]...)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// taskFile returns the file that defines task: the module file for imported
+// tasks and the project file otherwise.
+func (p *Project) taskFile(task types.Task) string {
+	if task.Env != nil {
+		if file := task.Env.Get("CAST_MODULE_FILE"); file != "" {
+			return file
+		}
+	}
+
+	return p.File
+}
+
+// taskDir returns the directory relative task paths such as `cwd` and
+// `dotenv` resolve against: the directory of the file that defines task, or
+// the project directory when it is unknown.
+func (p *Project) taskDir(task types.Task) string {
+	if task.File != "" {
+		return filepath.Dir(task.File)
+	}
+
+	return p.Dir
+}
+
+// LooksSecret reports whether a variable name suggests a credential so it can
+// be masked even when it was not declared as a secret.
+func LooksSecret(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, marker := range []string{"SECRET", "TOKEN", "PASSWORD", "PASSWD", "API_KEY", "APIKEY", "PRIVATE_KEY", "CREDENTIAL"} {
+		if strings.Contains(upper, marker) {
+			return true
+		}
+	}
+
+	// AUTH only counts as a whole segment, so AUTH_HEADER is masked while
+	// AUTHOR and OAUTH_URL are not.
+	for _, segment := range strings.FieldsFunc(upper, func(r rune) bool { return r == '_' || r == '-' || r == '.' }) {
+		if segment == "AUTH" || segment == "AUTHORIZATION" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,196 @@
+package projects
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/frostyeti/cast/internal/errors"
+	"github.com/frostyeti/cast/internal/types"
+)
+
+// TaskOrigin points at the file and position that define a task.
+type TaskOrigin struct {
+	Name   string `json:"name"`
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+}
+
+// ModuleOrigin describes the module import that contributed a task.
+type ModuleOrigin struct {
+	Name      string `json:"name,omitempty"`
+	From      string `json:"from,omitempty"`
+	File      string `json:"file"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// TaskHandlerInfo describes how a task's `uses` value is dispatched.
+// Kind is one of builtin, remote, package, local, fallback, or missing.
+type TaskHandlerInfo struct {
+	Kind    string `json:"kind"`
+	Uses    string `json:"uses"`
+	Path    string `json:"path,omitempty"`
+	Version string `json:"version,omitempty"`
+	Cached  bool   `json:"cached,omitempty"`
+}
+
+// TaskExplanation is the resolution chain for a task: where it was defined,
+// what it was merged from, and what will run it.
+type TaskExplanation struct {
+	Target  string          `json:"target"`
+	Context string          `json:"context,omitempty"`
+	Variant string          `json:"variant,omitempty"`
+	Task    TaskOrigin      `json:"task"`
+	Module  *ModuleOrigin   `json:"module,omitempty"`
+	Extends []TaskOrigin    `json:"extends,omitempty"`
+	Handler TaskHandlerInfo `json:"handler"`
+	// Definition is the task after extends, project defaults and the
+	// default `uses` have been applied.
+	Definition types.Task `json:"definition"`
+}
+
+// ExplainTask resolves target the same way RunTask does and reports each step
+// of the resolution without running anything.
+func (p *Project) ExplainTask(target string) (*TaskExplanation, error) {
+	if err := p.Init(); err != nil {
+		return nil, err
+	}
+
+	task, ok := p.ResolveTask(target)
+	if !ok {
+		return nil, errors.Newf("task %s not found", target)
+	}
+
+	explanation := &TaskExplanation{
+		Target:     target,
+		Context:    p.ContextName,
+		Task:       taskOrigin(task),
+		Definition: task,
+	}
+
+	if task.Name != target {
+		explanation.Variant = task.Name
+	}
+
+	if task.File != "" && task.File != p.File {
+		for _, path := range p.importedOrder {
+			mod := p.imported[path]
+			if mod.File != task.File {
+				continue
+			}
+
+			explanation.Module = &ModuleOrigin{
+				Name:      mod.Name,
+				From:      mod.From,
+				File:      mod.File,
+				Namespace: mod.Namespace,
+			}
+			break
+		}
+	}
+
+	// walk the extends graph depth first in the order parents are applied.
+	seen := map[string]bool{task.Name: true}
+	var walk func(current types.Task) error
+	walk = func(current types.Task) error {
+		for _, name := range current.Extends {
+			base, ok := p.Tasks.Get(name)
+			if !ok {
+				return errors.Newf("task %s extends undefined task %s", current.Name, name)
+			}
+
+			if seen[base.Name] {
+				continue
+			}
+			seen[base.Name] = true
+
+			if err := walk(base); err != nil {
+				return err
+			}
+			explanation.Extends = append(explanation.Extends, taskOrigin(base))
+		}
+		return nil
+	}
+	if err := walk(task); err != nil {
+		return nil, err
+	}
+
+	uses := ""
+	if task.Uses != nil {
+		uses = *task.Uses
+	}
+
+	explanation.Handler = p.explainHandler(uses)
+	return explanation, nil
+}
+
+func taskOrigin(task types.Task) TaskOrigin {
+	return TaskOrigin{
+		Name:   task.Name,
+		File:   task.File,
+		Line:   task.Line,
+		Column: task.Column,
+	}
+}
+
+// explainHandler mirrors the handler lookup in RunTask: registered handlers,
+// then remote references, then fallback tasks under .cast/tasks.
+func (p *Project) explainHandler(uses string) TaskHandlerInfo {
+	info := TaskHandlerInfo{Kind: "missing", Uses: uses}
+
+	if _, ok := GetTaskHandler(uses); ok {
+		info.Kind = "builtin"
+		return info
+	}
+
+	if IsRemoteTask(uses) {
+		switch {
+		case strings.HasPrefix(uses, "jsr:") || strings.HasPrefix(uses, "npm:"):
+			info.Kind = "package"
+		case strings.HasPrefix(uses, "./") || strings.HasPrefix(uses, "../") || filepath.IsAbs(uses):
+			info.Kind = "local"
+			info.Path = uses
+			if !filepath.IsAbs(uses) {
+				info.Path = filepath.Join(p.Dir, uses)
+			}
+			_, err := os.Stat(info.Path)
+			info.Cached = err == nil
+		case IsRegistryRef(uses):
+			info.Kind = "remote"
+			name, version, err := parseRegistryRef(uses)
+			registry := ResolveRegistryURL(p)
+			if err != nil || registry == "" {
+				return info
+			}
+
+			info.Version = version
+			if resolved := cachedRegistryVersion(registrySpellDir(p.Dir, registry, name), version); resolved != "" {
+				info.Path = remoteEntryFile(filepath.Join(registrySpellDir(p.Dir, registry, name), resolved))
+				info.Version = resolved
+				info.Cached = true
+			}
+		default:
+			info.Kind = "remote"
+			plan, err := planRemoteGitTask(p, uses)
+			if err != nil {
+				return info
+			}
+
+			info.Path = plan.layout.entryDir
+			info.Version = plan.resolvedVersion
+			_, err = os.Stat(plan.layout.repoDir)
+			info.Cached = err == nil
+		}
+
+		return info
+	}
+
+	if fallbackPath, found := findFallbackTask(uses, p.Dir); found {
+		info.Kind = "fallback"
+		info.Path = fallbackPath
+		info.Cached = true
+	}
+
+	return info
+}
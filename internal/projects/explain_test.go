@@ -0,0 +1,61 @@
+package projects_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/frostyeti/cast/internal/projects"
+)
+
+func TestExplainTask_ReportsImportingModule(t *testing.T) {
+	rootDir := t.TempDir()
+	moduleDir := filepath.Join(rootDir, "module")
+	if err := os.MkdirAll(moduleDir, 0o755); err != nil {
+		t.Fatalf("failed to create module dir: %v", err)
+	}
+
+	moduleFile := filepath.Join(moduleDir, "castfile.yaml")
+	moduleContent := `name: sample-module
+tasks:
+  show:
+    uses: bash
+    run: echo show
+`
+	if err := os.WriteFile(moduleFile, []byte(moduleContent), 0o644); err != nil {
+		t.Fatalf("failed to write module castfile: %v", err)
+	}
+
+	projectFile := filepath.Join(rootDir, "castfile.yaml")
+	projectContent := `name: root-project
+imports:
+  - from: ./module
+    namespace: mod
+`
+	if err := os.WriteFile(projectFile, []byte(projectContent), 0o644); err != nil {
+		t.Fatalf("failed to write root castfile: %v", err)
+	}
+
+	proj := &projects.Project{}
+	if err := proj.LoadFromYaml(projectFile); err != nil {
+		t.Fatalf("failed to load project: %v", err)
+	}
+
+	explanation, err := proj.ExplainTask("mod-show")
+	if err != nil {
+		t.Fatalf("failed to explain task: %v", err)
+	}
+
+	if explanation.Task.File != moduleFile || explanation.Task.Line != 3 {
+		t.Fatalf("expected task defined at %s:3, got %s:%d", moduleFile, explanation.Task.File, explanation.Task.Line)
+	}
+	if explanation.Module == nil {
+		t.Fatalf("expected module origin to be reported")
+	}
+	if explanation.Module.Namespace != "mod" || explanation.Module.From != "./module" {
+		t.Fatalf("unexpected module origin: %+v", *explanation.Module)
+	}
+	if explanation.Handler.Kind != "builtin" || explanation.Handler.Uses != "bash" {
+		t.Fatalf("unexpected handler: %+v", explanation.Handler)
+	}
+}
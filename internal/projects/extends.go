@@ -0,0 +1,365 @@
+package projects
+
+import (
+	"slices"
+
+	"github.com/frostyeti/cast/internal/errors"
+	"github.com/frostyeti/cast/internal/types"
+)
+
+// resolveTaskExtends merges every task with the tasks it extends. Parents are
+// resolved first and applied in the order they are listed, so later parents
+// override earlier ones and the task overrides them all. Parents may be
+// imported module tasks referenced by their namespaced name.
+func (p *Project) resolveTaskExtends() error {
+	resolved := map[string]types.Task{}
+	visiting := map[string]bool{}
+
+	var resolve func(task types.Task) (types.Task, error)
+	resolve = func(task types.Task) (types.Task, error) {
+		if merged, ok := resolved[task.Id]; ok {
+			return merged, nil
+		}
+
+		if len(task.Extends) == 0 {
+			resolved[task.Id] = task
+			return task, nil
+		}
+
+		if visiting[task.Id] {
+			return task, errors.Newf("task %s has a circular extends chain", task.Name)
+		}
+		visiting[task.Id] = true
+		defer delete(visiting, task.Id)
+
+		var base *types.Task
+		for _, name := range task.Extends {
+			parent, ok := p.Tasks.Get(name)
+			if !ok {
+				return task, errors.Newf("task %s extends undefined task %s", task.Name, name)
+			}
+
+			parent, err := resolve(parent)
+			if err != nil {
+				return task, err
+			}
+
+			if base == nil {
+				base = &parent
+				continue
+			}
+
+			merged := mergeTaskBase(*base, parent)
+			base = &merged
+		}
+
+		merged := mergeTaskBase(*base, task)
+		resolved[task.Id] = merged
+		return merged, nil
+	}
+
+	for _, task := range p.Tasks.Values() {
+		merged, err := resolve(task)
+		if err != nil {
+			return err
+		}
+
+		if len(task.Extends) > 0 {
+			p.Tasks.Set(&merged)
+		}
+	}
+
+	return nil
+}
+
+// mergeTaskBase returns task with the values it leaves unset taken from base.
+// `env` and `with` are deep merged; the task's merge strategies decide how
+// `args`, `needs`, `hosts` and `dotenv` combine.
+func mergeTaskBase(base, task types.Task) types.Task {
+	if task.Desc == nil && base.Desc != nil {
+		task.Desc = base.Desc
+	}
+
+	if (task.Uses == nil || *task.Uses == "") && base.Uses != nil {
+		task.Uses = base.Uses
+	}
+
+	if (task.Cwd == nil || *task.Cwd == "") && base.Cwd != nil {
+		task.Cwd = base.Cwd
+	}
+
+	if (task.Run == nil || *task.Run == "") && base.Run != nil {
+		task.Run = base.Run
+	}
+
+	if (task.Help == nil || *task.Help == "") && base.Help != nil {
+		task.Help = base.Help
+	}
+
+	if (task.Timeout == nil || *task.Timeout == "") && base.Timeout != nil {
+		task.Timeout = base.Timeout
+	}
+
+	if task.Retry == nil && base.Retry != nil {
+		task.Retry = base.Retry
+	}
+
+	if (task.Template == nil || *task.Template == "") && base.Template != nil {
+		task.Template = base.Template
+	}
+
+	if (task.Force == nil || *task.Force == "") && base.Force != nil {
+		task.Force = base.Force
+	}
+
+	if task.Hooks == nil && base.Hooks != nil {
+		task.Hooks = base.Hooks
+	}
+
+	if len(task.OS) == 0 && len(base.OS) > 0 {
+		task.OS = base.OS
+	}
+
+	if len(task.Arch) == 0 && len(base.Arch) > 0 {
+		task.Arch = base.Arch
+	}
+
+	task.Args = mergeList(base.Args, task.Args, task.Merge.TaskMergeStrategy("args"))
+	task.Hosts = mergeList(base.Hosts, task.Hosts, task.Merge.TaskMergeStrategy("hosts"))
+	task.DotEnv = mergeList(base.DotEnv, task.DotEnv, task.Merge.TaskMergeStrategy("dotenv"))
+
+	switch task.Merge.TaskMergeStrategy("needs") {
+	case types.MergeAppend:
+		needs := append(types.Needs{}, base.Needs...)
+		for _, need := range task.Needs {
+			if _, ok := needs.FindByName(need.Id); !ok {
+				needs = append(needs, need)
+			}
+		}
+		task.Needs = needs
+	default:
+		if len(task.Needs) == 0 && len(base.Needs) > 0 {
+			task.Needs = base.Needs
+		}
+	}
+
+	task.EnvRequired = mergeEnvRequirements(base.EnvRequired, task.EnvRequired)
+
+	e := base.Env.Clone()
+	for k, v := range task.Env.Iter() {
+		e.Set(k, v)
+	}
+	task.Env = e
+
+	if base.Vars != nil {
+		vars := base.Vars.Clone()
+		vars.Merge(task.Vars)
+		task.Vars = vars
+	}
+
+	with := base.With.Clone()
+	for _, k := range task.With.Keys() {
+		v, _ := task.With.Get(k)
+		if existing, ok := with.Get(k); ok {
+			v = deepMergeValue(existing, v)
+		}
+		with.Set(k, v)
+	}
+	task.With = with
+
+	return task
+}
+
+// mergeList combines inherited and own list values. Appending skips values
+// the inherited list already holds; replacing keeps the own values when set.
+func mergeList(base, own []string, strategy string) []string {
+	if strategy == types.MergeReplace {
+		if len(own) == 0 && len(base) > 0 {
+			return base
+		}
+		return own
+	}
+
+	if len(base) == 0 {
+		return own
+	}
+
+	merged := append([]string{}, base...)
+	for _, v := range own {
+		if !slices.Contains(merged, v) {
+			merged = append(merged, v)
+		}
+	}
+
+	return merged
+}
+
+// deepMergeValue merges nested maps key by key. Any other value replaces the
+// inherited value.
+func deepMergeValue(base, value any) any {
+	baseMap, ok := base.(map[string]any)
+	if !ok {
+		return value
+	}
+
+	valueMap, ok := value.(map[string]any)
+	if !ok {
+		return value
+	}
+
+	merged := make(map[string]any, len(baseMap)+len(valueMap))
+	for k, v := range baseMap {
+		merged[k] = v
+	}
+	for k, v := range valueMap {
+		if existing, ok := merged[k]; ok {
+			v = deepMergeValue(existing, v)
+		}
+		merged[k] = v
+	}
+
+	return merged
+}
+
+// resolveJobExtends merges every job with the jobs it extends. Parents are
+// resolved first and applied in the order they are listed.
+func (p *Project) resolveJobExtends() error {
+	if p.Schema.Jobs == nil {
+		return nil
+	}
+
+	resolved := map[string]types.Job{}
+	visiting := map[string]bool{}
+
+	var resolve func(job types.Job) (types.Job, error)
+	resolve = func(job types.Job) (types.Job, error) {
+		if merged, ok := resolved[job.Id]; ok {
+			return merged, nil
+		}
+
+		if len(job.Extends) == 0 {
+			resolved[job.Id] = job
+			return job, nil
+		}
+
+		if visiting[job.Id] {
+			return job, errors.Newf("job %s has a circular extends chain", job.Name)
+		}
+		visiting[job.Id] = true
+		defer delete(visiting, job.Id)
+
+		var base *types.Job
+		for _, name := range job.Extends {
+			parent, ok := p.Schema.Jobs.Get(name)
+			if !ok {
+				return job, errors.Newf("job %s extends undefined job %s", job.Name, name)
+			}
+
+			parent, err := resolve(parent)
+			if err != nil {
+				return job, err
+			}
+
+			if base == nil {
+				base = &parent
+				continue
+			}
+
+			merged := mergeJobBase(*base, parent)
+			base = &merged
+		}
+
+		merged := mergeJobBase(*base, job)
+		resolved[job.Id] = merged
+		return merged, nil
+	}
+
+	for _, job := range p.Schema.Jobs.Values() {
+		merged, err := resolve(job)
+		if err != nil {
+			return err
+		}
+
+		if len(job.Extends) > 0 {
+			p.Schema.Jobs.Set(&merged)
+		}
+	}
+
+	return nil
+}
+
+// mergeJobBase returns job with the values it leaves unset taken from base.
+func mergeJobBase(base, job types.Job) types.Job {
+	if job.Desc == "" && base.Desc != "" {
+		job.Desc = base.Desc
+	}
+
+	if job.If == nil && base.If != nil {
+		job.If = base.If
+	}
+
+	if (job.Timeout == nil || *job.Timeout == "") && base.Timeout != nil {
+		job.Timeout = base.Timeout
+	}
+
+	if (job.Cron == nil || *job.Cron == "") && base.Cron != nil {
+		job.Cron = base.Cron
+	}
+
+	if (job.Cwd == nil || *job.Cwd == "") && base.Cwd != nil {
+		job.Cwd = base.Cwd
+	}
+
+	if job.Needs == nil && base.Needs != nil {
+		job.Needs = base.Needs
+	}
+
+	if len(job.Steps) == 0 && len(base.Steps) > 0 {
+		job.Steps = base.Steps
+	}
+
+	if job.DotEnv == nil && base.DotEnv != nil {
+		job.DotEnv = base.DotEnv
+	} else if job.DotEnv != nil && base.DotEnv != nil {
+		dotenv := append(types.DotEnvs{}, (*base.DotEnv)...)
+
+		for _, de := range *job.DotEnv {
+			replaceIndex := -1
+			for i, existingDe := range dotenv {
+				if de.Path == existingDe.Path {
+					replaceIndex = i
+				}
+			}
+
+			if replaceIndex > -1 {
+				dotenv[replaceIndex] = de
+			} else {
+				dotenv = append(dotenv, de)
+			}
+		}
+
+		job.DotEnv = &dotenv
+	}
+
+	job.EnvRequired = mergeEnvRequirements(base.EnvRequired, job.EnvRequired)
+
+	if base.Vars != nil {
+		vars := base.Vars.Clone()
+		vars.Merge(job.Vars)
+		job.Vars = vars
+	}
+
+	if base.Env != nil {
+		if job.Env == nil {
+			job.Env = base.Env.Clone()
+		} else {
+			e := base.Env.Clone()
+			for k, v := range job.Env.Iter() {
+				e.Set(k, v)
+			}
+			job.Env = e
+		}
+	}
+
+	return job
+}
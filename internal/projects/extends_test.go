@@ -0,0 +1,141 @@
+package projects_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/frostyeti/cast/internal/projects"
+)
+
+func TestInit_MergesMultipleAndModuleExtends(t *testing.T) {
+	rootDir := t.TempDir()
+	moduleDir := filepath.Join(rootDir, "shared")
+	if err := os.MkdirAll(moduleDir, 0o755); err != nil {
+		t.Fatalf("failed to create module dir: %v", err)
+	}
+
+	moduleContent := `
+name: shared
+tasks:
+  base:
+    uses: bash
+    timeout: 5m
+    args: [--color]
+    env:
+      FROM: module
+  docker-base:
+    extends: base
+    with:
+      volumes: [./cache:/cache]
+      options:
+        network: host
+        pull: always
+`
+	if err := os.WriteFile(filepath.Join(moduleDir, "castfile.yaml"), []byte(moduleContent), 0o644); err != nil {
+		t.Fatalf("failed to write module castfile: %v", err)
+	}
+
+	projectFile := filepath.Join(rootDir, "castfile.yaml")
+	projectContent := `
+name: extends-demo
+imports:
+  - from: ./shared
+    namespace: shared
+tasks:
+  lint: echo lint
+  local-base:
+    needs: [lint]
+    cwd: ./app
+    env:
+      FROM: project
+      LOCAL: "yes"
+  build:
+    extends: [shared:docker-base, local-base]
+    merge:
+      args: append
+      needs: append
+    args: [--verbose]
+    needs: [test]
+    with:
+      options:
+        pull: never
+    run: echo build
+  test: echo test
+`
+	if err := os.WriteFile(projectFile, []byte(projectContent), 0o644); err != nil {
+		t.Fatalf("failed to write castfile: %v", err)
+	}
+
+	proj := &projects.Project{}
+	if err := proj.LoadFromYaml(projectFile); err != nil {
+		t.Fatalf("failed to load project: %v", err)
+	}
+	if err := proj.Init(); err != nil {
+		t.Fatalf("failed to init project: %v", err)
+	}
+
+	build, ok := proj.Tasks.Get("build")
+	if !ok {
+		t.Fatalf("expected build task")
+	}
+
+	if build.Timeout == nil || *build.Timeout != "5m" {
+		t.Fatalf("expected timeout from the module base, got %v", build.Timeout)
+	}
+	if build.Cwd == nil || *build.Cwd != "./app" {
+		t.Fatalf("expected cwd from the local base, got %v", build.Cwd)
+	}
+	if got := build.Env.Get("FROM"); got != "project" {
+		t.Fatalf("expected later parents to override earlier ones, got FROM=%s", got)
+	}
+	if got := build.Env.Get("LOCAL"); got != "yes" {
+		t.Fatalf("expected env from every parent, got LOCAL=%s", got)
+	}
+	if !slices.Equal(build.Args, []string{"--color", "--verbose"}) {
+		t.Fatalf("expected appended args, got %v", build.Args)
+	}
+	if names := build.Needs.Names(); !slices.Equal(names, []string{"lint", "test"}) {
+		t.Fatalf("expected appended needs, got %v", names)
+	}
+
+	options, _ := build.With.Get("options")
+	optionMap, _ := options.(map[string]any)
+	if optionMap["network"] != "host" || optionMap["pull"] != "never" {
+		t.Fatalf("expected with to deep merge, got %v", options)
+	}
+	if _, ok := build.With.Get("volumes"); !ok {
+		t.Fatalf("expected volumes from the module base")
+	}
+}
+
+func TestInit_RejectsCircularTaskExtends(t *testing.T) {
+	projectDir := t.TempDir()
+	projectFile := filepath.Join(projectDir, "castfile.yaml")
+
+	content := `
+name: extends-loop
+tasks:
+  a:
+    extends: [b]
+    run: echo a
+  b:
+    extends: a
+    run: echo b
+`
+	if err := os.WriteFile(projectFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write castfile: %v", err)
+	}
+
+	proj := &projects.Project{}
+	if err := proj.LoadFromYaml(projectFile); err != nil {
+		t.Fatalf("failed to load project: %v", err)
+	}
+
+	err := proj.Init()
+	if err == nil || !strings.Contains(err.Error(), "circular extends chain") {
+		t.Fatalf("expected circular extends error, got: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package projects
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/frostyeti/cast/internal/types"
+)
+
+// moduleFileNames lists the files checked, in order, when a module import
+// points at a directory.
+var moduleFileNames = []string{
+	"cast.mod",
+	"cast.module.yaml",
+	"cast.module.yml",
+	"mod.yaml",
+	"mod.yml",
+	"castfile.yaml",
+	"castfile.yml",
+	"castfile",
+}
+
+// LocalFiles returns the castfile along with its include files and the
+// inventory files and module imports it references by a relative or absolute
+// path. Remote imports and files resolved from the cast data directories are
+// not included because they are not owned by the project.
+func LocalFiles(file string) ([]string, error) {
+	schema := &types.Project{}
+	if err := schema.ReadFromYaml(file); err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(file)
+	files := []string{file}
+	add := func(path string) {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		if stat, err := os.Stat(path); err != nil || stat.IsDir() {
+			return
+		}
+		if !slices.Contains(files, path) {
+			files = append(files, path)
+		}
+	}
+
+	for _, include := range schema.IncludedFiles {
+		add(include)
+	}
+
+	for _, inv := range schema.Inventories {
+		if isLocalRef(inv) {
+			add(inv)
+		}
+	}
+
+	if schema.Imports != nil {
+		for _, imp := range *schema.Imports {
+			if !isLocalRef(imp.From) {
+				continue
+			}
+
+			path := imp.From
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(dir, path)
+			}
+			if stat, err := os.Stat(path); err == nil && stat.IsDir() {
+				path = moduleFileInDir(path)
+			} else if err != nil {
+				for _, ext := range []string{".yaml", ".yml"} {
+					if _, err := os.Stat(path + ext); err == nil {
+						path += ext
+						break
+					}
+				}
+			}
+			add(path)
+		}
+	}
+
+	return files, nil
+}
+
+func isLocalRef(ref string) bool {
+	return filepath.IsAbs(ref) || strings.HasPrefix(ref, "./") || strings.HasPrefix(ref, "../")
+}
+
+func moduleFileInDir(dir string) string {
+	for _, name := range moduleFileNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+
+	return dir
+}
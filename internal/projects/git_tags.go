@@ -4,6 +4,9 @@ import (
 	stdexec "os/exec"
 	"strconv"
 	"strings"
+
+	"github.com/frostyeti/cast/internal/errors"
+	"github.com/frostyeti/cast/internal/offline"
 )
 
 var gitLsRemote = func(repoURL string) (string, int, error) {
@@ -15,6 +18,37 @@ var gitLsRemote = func(repoURL string) (string, int, error) {
 	return string(out), 0, nil
 }
 
+// listRemoteTags returns the tag names of repoURL from `git ls-remote`.
+func listRemoteTags(repoURL string) ([]string, error) {
+	stdout, code, err := gitLsRemote(repoURL)
+	if err != nil || code != 0 {
+		if err == nil {
+			err = errors.Newf("git ls-remote exited with code %d", code)
+		}
+		return nil, errors.Newf("failed to list tags of %s: %w: %s", repoURL, err, strings.TrimSpace(stdout))
+	}
+
+	lines := strings.Split(stdout, "\n")
+	tags := make([]string, 0, len(lines))
+	for _, line := range lines {
+		parts := strings.Fields(line)
+		if len(parts) < 2 {
+			continue
+		}
+		tagRef := parts[1]
+		if !strings.HasPrefix(tagRef, "refs/tags/") {
+			continue
+		}
+		tag := strings.TrimPrefix(tagRef, "refs/tags/")
+		if strings.HasSuffix(tag, "^{}") {
+			continue
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, nil
+}
+
 type gitResolveMode int
 
 const (
@@ -26,6 +60,14 @@ const (
 // resolveGitVersion checks if a requested version like "v1" can be resolved
 // to a specific git tag by querying the remote repository.
 func resolveGitReference(repoURL, version, subPath string) (string, gitResolveMode) {
+	return resolveReference(version, subPath, func() ([]string, error) {
+		return listRemoteTags(repoURL)
+	})
+}
+
+// resolveReference resolves version against the tags returned by listTags,
+// which is only called for semver versions.
+func resolveReference(version, subPath string, listTags func() ([]string, error)) (string, gitResolveMode) {
 	if version == "" || isHeadRef(version) {
 		return "HEAD", gitResolveDefault
 	}
@@ -42,95 +84,86 @@ func resolveGitReference(repoURL, version, subPath string) (string, gitResolveMo
 		return version, gitResolveBranch
 	}
 
-	stdout, code, err := gitLsRemote(repoURL)
-	if err != nil || code != 0 {
+	// offline runs never query the remote; planRemoteGit matches the
+	// version against the tags already in the cache instead.
+	if offline.Enabled() {
 		return version, gitResolveBranch
 	}
 
-	lines := strings.Split(stdout, "\n")
-	tags := make([]string, 0, len(lines))
-	for _, line := range lines {
-		parts := strings.Fields(line)
-		if len(parts) < 2 {
-			continue
-		}
-		tagRef := parts[1]
-		if !strings.HasPrefix(tagRef, "refs/tags/") {
-			continue
-		}
-		tag := strings.TrimPrefix(tagRef, "refs/tags/")
-		if strings.HasSuffix(tag, "^{}") {
-			continue
-		}
-		tags = append(tags, tag)
+	tags, err := listTags()
+	if err != nil {
+		return version, gitResolveBranch
 	}
 
-	chooseBest := func(candidates []string) string {
-		if strings.Contains(version, "-") {
-			for _, t := range candidates {
-				if t == version || strings.HasSuffix(t, "/"+version) {
-					return t
-				}
+	if subPath != "" {
+		prefix := strings.TrimSuffix(subPath, "/") + "/"
+		prefixed := make([]string, 0)
+		for _, t := range tags {
+			if strings.HasPrefix(t, prefix) {
+				prefixed = append(prefixed, t)
 			}
-			return ""
 		}
-
-		if version == "HEAD" {
-			return ""
+		if best := chooseBestTag(version, subPath, prefixed); best != "" {
+			return best, gitResolveBranch
 		}
+	}
 
-		requested := trimVersion(version)
-		var bestTag string
-		var bestMajor, bestMinor, bestPatch int
-		found := false
+	if best := chooseBestTag(version, subPath, tags); best != "" {
+		return best, gitResolveBranch
+	}
 
+	return version, gitResolveBranch
+}
+
+// chooseBestTag picks the tag that best matches version. A version with a
+// pre-release suffix must match exactly; otherwise the highest tag sharing
+// the requested major or major.minor prefix wins.
+func chooseBestTag(version, subPath string, candidates []string) string {
+	if strings.Contains(version, "-") {
 		for _, t := range candidates {
-			base := t
-			if idx := strings.LastIndex(base, "/"); idx > -1 {
-				if subPath == "" {
-					continue
-				}
-				base = base[idx+1:]
+			if t == version || strings.HasSuffix(t, "/"+version) {
+				return t
 			}
+		}
+		return ""
+	}
 
-			baseVersion := trimVersion(base)
-			if baseVersion != requested && !strings.HasPrefix(baseVersion, requested+".") {
-				continue
-			}
+	if version == "HEAD" {
+		return ""
+	}
 
-			major, minor, patch, ok := parseVersionParts(base)
-			if !ok {
-				continue
-			}
+	requested := trimVersion(version)
+	var bestTag string
+	var bestMajor, bestMinor, bestPatch int
+	found := false
 
-			if !found || major > bestMajor || (major == bestMajor && minor > bestMinor) || (major == bestMajor && minor == bestMinor && patch > bestPatch) {
-				found = true
-				bestMajor, bestMinor, bestPatch = major, minor, patch
-				bestTag = t
+	for _, t := range candidates {
+		base := t
+		if idx := strings.LastIndex(base, "/"); idx > -1 {
+			if subPath == "" {
+				continue
 			}
+			base = base[idx+1:]
 		}
 
-		return bestTag
-	}
-
-	if subPath != "" {
-		prefix := strings.TrimSuffix(subPath, "/") + "/"
-		prefixed := make([]string, 0)
-		for _, t := range tags {
-			if strings.HasPrefix(t, prefix) {
-				prefixed = append(prefixed, t)
-			}
+		baseVersion := trimVersion(base)
+		if baseVersion != requested && !strings.HasPrefix(baseVersion, requested+".") {
+			continue
 		}
-		if best := chooseBest(prefixed); best != "" {
-			return best, gitResolveBranch
+
+		major, minor, patch, ok := parseVersionParts(base)
+		if !ok {
+			continue
 		}
-	}
 
-	if best := chooseBest(tags); best != "" {
-		return best, gitResolveBranch
+		if !found || major > bestMajor || (major == bestMajor && minor > bestMinor) || (major == bestMajor && minor == bestMinor && patch > bestPatch) {
+			found = true
+			bestMajor, bestMinor, bestPatch = major, minor, patch
+			bestTag = t
+		}
 	}
 
-	return version, gitResolveBranch
+	return bestTag
 }
 
 func resolveGitVersion(repoURL, version, subPath string) string {
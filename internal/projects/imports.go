@@ -0,0 +1,224 @@
+package projects
+
+import (
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/frostyeti/cast/internal/errors"
+	"github.com/frostyeti/cast/internal/integrity"
+	"github.com/frostyeti/cast/internal/modules"
+	"github.com/frostyeti/cast/internal/types"
+)
+
+// superTask is the name an overriding task uses in `extends` and `needs` to
+// refer to the imported task it replaces.
+const superTask = "super"
+
+// moduleRef records which file imported a remote module at which version.
+type moduleRef struct {
+	version  string
+	importer string
+}
+
+// moduleTaskNames returns the names of the module tasks an import brings in:
+// the `tasks` allowlist when set, otherwise every task, minus `exclude`.
+// Excluded and renamed tasks must exist in the module.
+func moduleTaskNames(mod types.Module) ([]string, error) {
+	for _, name := range mod.Exclude {
+		if _, ok := mod.Tasks.Get(name); !ok {
+			return nil, errors.Newf("module %s excludes unknown task %s", mod.From, name)
+		}
+	}
+
+	for name := range mod.Rename {
+		if _, ok := mod.Tasks.Get(name); !ok {
+			return nil, errors.Newf("module %s renames unknown task %s", mod.From, name)
+		}
+	}
+
+	names := mod.TaskNames
+	if len(names) == 0 {
+		for _, task := range mod.Tasks.Values() {
+			names = append(names, task.Name)
+		}
+	}
+
+	return slices.DeleteFunc(slices.Clone(names), func(name string) bool {
+		return slices.ContainsFunc(mod.Exclude, func(excluded string) bool {
+			return strings.EqualFold(excluded, name)
+		})
+	}), nil
+}
+
+// moduleTaskName returns the name a module task is imported as, after rename
+// and namespacing.
+func moduleTaskName(mod types.Module, name string) string {
+	if renamed, ok := mod.Rename[name]; ok {
+		name = renamed
+	}
+
+	if mod.Namespace != "" {
+		name = mod.Namespace + ":" + name
+	}
+
+	return name
+}
+
+// splitModuleRef splits a remote module reference such as
+// `github.com/org/repo@v1.2.0` into its source and version. The version is
+// empty when the reference does not pin one.
+func splitModuleRef(ref string) (string, string) {
+	at := strings.LastIndex(ref, "@")
+	// the @ of git@host:org/repo is part of the source.
+	if at <= 0 || (strings.HasPrefix(ref, "git@") && at == len("git")) {
+		return ref, ""
+	}
+
+	return ref[:at], ref[at+1:]
+}
+
+// isRemoteModuleRef reports whether an import points at a remote module. It
+// accepts the same references as remote tasks plus module tarballs.
+func isRemoteModuleRef(ref string) bool {
+	ref, _ = integrity.Split(ref)
+	if isModuleTarball(ref) {
+		return true
+	}
+
+	if isLocalRef(ref) || IsRegistryRef(ref) || strings.HasPrefix(ref, "jsr:") || strings.HasPrefix(ref, "npm:") {
+		return false
+	}
+
+	return IsRemoteTask(ref)
+}
+
+func isModuleTarball(ref string) bool {
+	return strings.HasSuffix(ref, ".tar.gz") || strings.HasPrefix(ref, "http") && strings.Contains(ref, "tar.gz")
+}
+
+// fetchRemoteModule downloads a remote module import and returns the path of
+// the module file to load. Git references use the same syntax, version
+// resolution and stable or volatile caches as remote tasks; a reference
+// without a version follows the default branch.
+func (p *Project) fetchRemoteModule(written string) (string, error) {
+	ref, pin := integrity.Split(written)
+	if pin != "" {
+		if err := integrity.Validate(pin); err != nil {
+			return "", errors.Newf("remote module '%s' has an invalid integrity pin: %w", written, err)
+		}
+	}
+
+	if !isTrustedSource(ref, p.Schema.TrustedSources) {
+		return "", errors.Newf("remote module '%s' is not in trusted_sources", ref)
+	}
+
+	if isModuleTarball(ref) {
+		dir, err := modules.FetchModule(ResolveVolatileRemoteModulesDir(p.Dir), ref, pin)
+		if err != nil {
+			return "", errors.Newf("failed to fetch remote module %s: %w", ref, err)
+		}
+		if err := verifySignature(p, "module", ref, dir); err != nil {
+			_ = os.RemoveAll(dir)
+			return "", err
+		}
+		recordCacheUse(dir, ref, "")
+
+		if err := p.lockRef("modules", written, "", dir); err != nil {
+			return "", err
+		}
+		return moduleFileInDir(dir), nil
+	}
+
+	gitRef := ref
+	if _, version := splitModuleRef(ref); version == "" {
+		gitRef += "@HEAD"
+	}
+
+	plan, err := planRemoteGit(gitRef, stableRemoteModulesDir(p.Dir), ResolveVolatileRemoteModulesDir(p.Dir), remoteFetchMode(p))
+	if err != nil {
+		return "", err
+	}
+
+	// progress goes to stderr so commands that print json keep a clean stdout.
+	if err := fetchRemoteGitPlan(plan, "module", ref, false, os.Stderr); err != nil {
+		return "", errors.Newf("failed to fetch remote module %s: %w", ref, err)
+	}
+
+	if err := verifyCachedTree(plan.layout.repoDir, pin, "module", ref); err != nil {
+		return "", err
+	}
+	if err := verifySignature(p, "module", ref, plan.layout.entryDir); err != nil {
+		_ = os.RemoveAll(plan.layout.repoDir)
+		return "", err
+	}
+
+	recordCacheUse(plan.layout.repoDir, ref, plan.resolvedVersion)
+
+	if err := p.lockRef("modules", written, plan.resolvedVersion, plan.layout.repoDir); err != nil {
+		return "", err
+	}
+
+	return moduleFileInDir(plan.layout.entryDir), nil
+}
+
+// checkModuleVersion records the version a remote module is imported at and
+// reports an error when another import already requested a different one.
+func (p *Project) checkModuleVersion(ref, importer string) error {
+	ref, _ = integrity.Split(ref)
+	if strings.HasSuffix(ref, ".tar.gz") {
+		return nil
+	}
+
+	source, version := splitModuleRef(ref)
+	// imports of different subpaths of one repository share its version.
+	version, _ = splitVersionAndSubPath(version)
+	if p.moduleVersions == nil {
+		p.moduleVersions = map[string]moduleRef{}
+	}
+
+	existing, ok := p.moduleVersions[source]
+	if !ok {
+		p.moduleVersions[source] = moduleRef{version: version, importer: importer}
+		return nil
+	}
+
+	if existing.version == version {
+		return nil
+	}
+
+	describe := func(version string) string {
+		if version == "" {
+			return "the default branch"
+		}
+		return version
+	}
+
+	return errors.Newf("module %s is imported at %s by %s and at %s by %s; import a single version",
+		source, describe(existing.version), existing.importer, describe(version), importer)
+}
+
+// resolveSuper points `super` entries in the extends and needs of an
+// overriding task at the imported task it replaces.
+func resolveSuper(task *types.Task, base string) {
+	extends := slices.Clone(task.Extends)
+	for i, name := range extends {
+		if name == superTask {
+			extends[i] = base
+		}
+	}
+	task.Extends = extends
+
+	needs := slices.Clone(task.Needs)
+	for i, need := range needs {
+		if need.Id == superTask {
+			needs[i].Id = base
+		}
+	}
+	task.Needs = needs
+}
+
+// usesSuper reports whether a task references `super`.
+func usesSuper(task types.Task) bool {
+	return slices.Contains(task.Extends, superTask) || slices.Contains(task.Needs.Names(), superTask)
+}
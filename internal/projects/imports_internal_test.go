@@ -0,0 +1,202 @@
+package projects
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplitModuleRef(t *testing.T) {
+	cases := map[string][2]string{
+		"github.com/org/repo@v1.2.0":         {"github.com/org/repo", "v1.2.0"},
+		"github.com/org/repo":                {"github.com/org/repo", ""},
+		"git@github.com:org/repo.git":        {"git@github.com:org/repo.git", ""},
+		"git@github.com:org/repo.git@v2.0.0": {"git@github.com:org/repo.git", "v2.0.0"},
+	}
+
+	for ref, want := range cases {
+		source, version := splitModuleRef(ref)
+		if source != want[0] || version != want[1] {
+			t.Errorf("splitModuleRef(%q) = %q, %q; want %q, %q", ref, source, version, want[0], want[1])
+		}
+	}
+}
+
+func TestCheckModuleVersionReportsConflicts(t *testing.T) {
+	p := &Project{}
+
+	if err := p.checkModuleVersion("github.com/org/shared@v1.0.0", "castfile.yaml"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := p.checkModuleVersion("github.com/org/shared@v1.0.0", "a/cast.module.yaml"); err != nil {
+		t.Fatalf("expected the same version to be accepted, got: %v", err)
+	}
+
+	err := p.checkModuleVersion("github.com/org/shared@v2.0.0", "b/cast.module.yaml")
+	if err == nil {
+		t.Fatalf("expected a version conflict")
+	}
+	for _, want := range []string{"github.com/org/shared", "v1.0.0 by castfile.yaml", "v2.0.0 by b/cast.module.yaml"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q in %q", want, err.Error())
+		}
+	}
+}
+
+func TestInit_FetchesRemoteModulesLikeRemoteTasks(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv(CastRemoteModulesDirEnv, filepath.Join(tmpDir, "stable"))
+	t.Setenv(CastVolatileRemoteModulesDirEnv, filepath.Join(tmpDir, "volatile"))
+
+	repoDir := filepath.Join(tmpDir, "modules-repo")
+	moduleDir := filepath.Join(repoDir, "mods", "shared")
+	if err := os.MkdirAll(moduleDir, 0o755); err != nil {
+		t.Fatalf("mkdir module: %v", err)
+	}
+	writeModule := func(message string) {
+		content := "tasks:\n  hello:\n    uses: bash\n    run: echo " + message + "\n"
+		if err := os.WriteFile(filepath.Join(moduleDir, "cast.module.yaml"), []byte(content), 0o644); err != nil {
+			t.Fatalf("write module: %v", err)
+		}
+	}
+
+	runGit(t, repoDir, "init")
+	runGit(t, repoDir, "config", "user.name", "Test User")
+	runGit(t, repoDir, "config", "user.email", "test@example.com")
+	writeModule("v1.1")
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "v1.1")
+	runGit(t, repoDir, "tag", "v1.1.0")
+	writeModule("v1.2")
+	runGit(t, repoDir, "commit", "-am", "v1.2")
+	runGit(t, repoDir, "tag", "v1.2.0")
+	writeModule("head")
+	if err := os.WriteFile(filepath.Join(repoDir, "cast.module.yaml"), []byte("tasks:\n  root: echo root\n"), 0o644); err != nil {
+		t.Fatalf("write root module: %v", err)
+	}
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "head")
+
+	repoURL := "file://" + filepath.ToSlash(repoDir)
+	load := func(t *testing.T, imports string) (*Project, error) {
+		t.Helper()
+		projectDir := t.TempDir()
+		file := filepath.Join(projectDir, "castfile.yaml")
+		if err := os.WriteFile(file, []byte("name: remote-modules\n"+imports), 0o644); err != nil {
+			t.Fatalf("write castfile: %v", err)
+		}
+
+		p := &Project{}
+		if err := p.LoadFromYaml(file); err != nil {
+			t.Fatalf("load project: %v", err)
+		}
+		return p, p.Init()
+	}
+
+	t.Run("semver family with subpath", func(t *testing.T) {
+		p, err := load(t, "imports:\n  - from: "+repoURL+"@v1/mods/shared\n    ns: shared\n")
+		if err != nil {
+			t.Fatalf("init: %v", err)
+		}
+
+		task, ok := p.Tasks.Get("shared:hello")
+		if !ok || task.Run == nil || !strings.Contains(*task.Run, "v1.2") {
+			t.Fatalf("expected v1 to resolve to the v1.2.0 module, got %+v", task)
+		}
+		if _, err := os.Stat(filepath.Join(tmpDir, "stable")); err != nil {
+			t.Fatalf("expected tagged modules in the stable cache: %v", err)
+		}
+	})
+
+	t.Run("default branch without version", func(t *testing.T) {
+		p, err := load(t, "imports:\n  - from: "+repoURL+"\n    ns: root\n")
+		if err != nil {
+			t.Fatalf("init: %v", err)
+		}
+		if _, ok := p.Tasks.Get("root:root"); !ok {
+			t.Fatalf("expected the default branch root module, got tasks %v", p.Tasks.Keys())
+		}
+
+		p, err = load(t, "imports:\n  - from: "+repoURL+"@HEAD/mods/shared\n    ns: shared\n")
+		if err != nil {
+			t.Fatalf("init: %v", err)
+		}
+		task, ok := p.Tasks.Get("shared:hello")
+		if !ok || task.Run == nil || !strings.Contains(*task.Run, "head") {
+			t.Fatalf("expected HEAD module, got %+v", task)
+		}
+		if _, err := os.Stat(filepath.Join(tmpDir, "volatile")); err != nil {
+			t.Fatalf("expected branch modules in the volatile cache: %v", err)
+		}
+	})
+
+	t.Run("trusted sources", func(t *testing.T) {
+		_, err := load(t, "trusted_sources: [github.com/acme/*]\nimports:\n  - from: "+repoURL+"@v1.1.0/mods/shared\n")
+		if err == nil || !strings.Contains(err.Error(), "is not in trusted_sources") {
+			t.Fatalf("expected trusted_sources error, got: %v", err)
+		}
+	})
+}
+
+func TestInit_FetchesTarballModulesIntoVolatileModulesDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	volatileDir := filepath.Join(tmpDir, "volatile")
+	t.Setenv(CastVolatileRemoteModulesDirEnv, volatileDir)
+	t.Setenv(CastFetchEnv, "")
+
+	content := "tasks:\n  hello:\n    uses: bash\n    run: echo tarball\n"
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Name: "cast.module.yaml", Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatalf("write header: %v", err)
+	}
+	if _, err := tw.Write([]byte(content)); err != nil {
+		t.Fatalf("write content: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("close gzip: %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	projectDir := filepath.Join(tmpDir, "project")
+	if err := os.MkdirAll(projectDir, 0o755); err != nil {
+		t.Fatalf("mkdir project: %v", err)
+	}
+	file := filepath.Join(projectDir, "castfile.yaml")
+	castfile := "name: tarball-modules\nimports:\n  - from: " + server.URL + "/shared.tar.gz\n    ns: shared\n"
+	if err := os.WriteFile(file, []byte(castfile), 0o644); err != nil {
+		t.Fatalf("write castfile: %v", err)
+	}
+
+	p := &Project{}
+	if err := p.LoadFromYaml(file); err != nil {
+		t.Fatalf("load project: %v", err)
+	}
+	if err := p.Init(); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+
+	if _, ok := p.Tasks.Get("shared:hello"); !ok {
+		t.Fatalf("expected the tarball module task, got tasks %v", p.Tasks.Keys())
+	}
+	matches, _ := filepath.Glob(filepath.Join(volatileDir, "shared-*", "cast.module.yaml"))
+	if len(matches) != 1 {
+		t.Fatalf("expected the module in %s, got %v", volatileDir, matches)
+	}
+	if _, err := os.Stat(filepath.Join(projectDir, ".cast", "cache", "modules")); !os.IsNotExist(err) {
+		t.Fatalf("expected no module under the project cache, got %v", err)
+	}
+}
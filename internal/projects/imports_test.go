@@ -0,0 +1,204 @@
+package projects_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/frostyeti/cast/internal/projects"
+)
+
+func writeImportFixture(t *testing.T, projectContent string) string {
+	t.Helper()
+
+	rootDir := t.TempDir()
+	moduleDir := filepath.Join(rootDir, "shared")
+	if err := os.MkdirAll(moduleDir, 0o755); err != nil {
+		t.Fatalf("failed to create module dir: %v", err)
+	}
+
+	moduleContent := `
+name: shared
+tasks:
+  lint:
+    uses: bash
+    run: echo module-lint
+  build:
+    uses: bash
+    env:
+      TARGET: module
+    run: echo "module-build $TARGET"
+  publish:
+    uses: bash
+    run: echo module-publish
+`
+	if err := os.WriteFile(filepath.Join(moduleDir, "castfile.yaml"), []byte(moduleContent), 0o644); err != nil {
+		t.Fatalf("failed to write module castfile: %v", err)
+	}
+
+	projectFile := filepath.Join(rootDir, "castfile.yaml")
+	if err := os.WriteFile(projectFile, []byte(projectContent), 0o644); err != nil {
+		t.Fatalf("failed to write castfile: %v", err)
+	}
+
+	return projectFile
+}
+
+func TestInit_ImportExcludeAndRename(t *testing.T) {
+	projectFile := writeImportFixture(t, `
+name: import-controls
+imports:
+  - from: ./shared
+    namespace: shared
+    exclude: [publish]
+    rename:
+      lint: check
+`)
+
+	proj := &projects.Project{}
+	if err := proj.LoadFromYaml(projectFile); err != nil {
+		t.Fatalf("failed to load project: %v", err)
+	}
+	if err := proj.Init(); err != nil {
+		t.Fatalf("failed to init project: %v", err)
+	}
+
+	if _, ok := proj.Tasks.Get("shared:check"); !ok {
+		t.Fatalf("expected renamed task shared:check, got %v", proj.Tasks.Keys())
+	}
+	if _, ok := proj.Tasks.Get("shared:lint"); ok {
+		t.Fatalf("expected shared:lint to be renamed away")
+	}
+	if _, ok := proj.Tasks.Get("shared:publish"); ok {
+		t.Fatalf("expected shared:publish to be excluded")
+	}
+	if _, ok := proj.Tasks.Get("shared:build"); !ok {
+		t.Fatalf("expected shared:build to be imported")
+	}
+}
+
+func TestInit_ImportRejectsUnknownExclude(t *testing.T) {
+	projectFile := writeImportFixture(t, `
+name: import-controls
+imports:
+  - from: ./shared
+    exclude: [missing]
+`)
+
+	proj := &projects.Project{}
+	if err := proj.LoadFromYaml(projectFile); err != nil {
+		t.Fatalf("failed to load project: %v", err)
+	}
+
+	err := proj.Init()
+	if err == nil || !strings.Contains(err.Error(), "excludes unknown task missing") {
+		t.Fatalf("expected unknown exclude error, got: %v", err)
+	}
+}
+
+func TestInit_ProjectTaskShadowsImportedTask(t *testing.T) {
+	projectFile := writeImportFixture(t, `
+name: import-controls
+imports:
+  - from: ./shared
+tasks:
+  build:
+    uses: bash
+    run: echo project-build
+`)
+
+	proj := &projects.Project{}
+	if err := proj.LoadFromYaml(projectFile); err != nil {
+		t.Fatalf("failed to load project: %v", err)
+	}
+	if err := proj.Init(); err != nil {
+		t.Fatalf("failed to init project: %v", err)
+	}
+
+	task, ok := proj.Tasks.Get("build")
+	if !ok || task.Run == nil || *task.Run != "echo project-build" {
+		t.Fatalf("expected the project task to shadow the imported build, got %+v", task)
+	}
+	if _, ok := proj.Tasks.Get("build:base"); ok {
+		t.Fatalf("expected no build:base without override: true")
+	}
+	if _, ok := proj.Tasks.Get("lint"); !ok {
+		t.Fatalf("expected the other imported tasks to stay available")
+	}
+}
+
+func TestInit_RedefiningImportedTaskFailsWithOverrideFalse(t *testing.T) {
+	projectFile := writeImportFixture(t, `
+name: import-controls
+imports:
+  - from: ./shared
+    namespace: shared
+    override: false
+tasks:
+  shared:build: echo local
+`)
+
+	proj := &projects.Project{}
+	if err := proj.LoadFromYaml(projectFile); err != nil {
+		t.Fatalf("failed to load project: %v", err)
+	}
+
+	err := proj.Init()
+	if err == nil || !strings.Contains(err.Error(), "override: false") {
+		t.Fatalf("expected override error, got: %v", err)
+	}
+}
+
+func TestRunTask_OverrideCallsImportedTaskThroughSuper(t *testing.T) {
+	projectFile := writeImportFixture(t, `
+name: import-controls
+imports:
+  - from: ./shared
+    namespace: shared
+    override: true
+tasks:
+  shared:build:
+    extends: super
+    needs: [shared:lint]
+    env:
+      TARGET: project
+  shared:lint:
+    needs: [super]
+    uses: bash
+    run: echo project-lint
+`)
+
+	proj := &projects.Project{}
+	if err := proj.LoadFromYaml(projectFile); err != nil {
+		t.Fatalf("failed to load project: %v", err)
+	}
+
+	var stdout bytes.Buffer
+	_, err := proj.RunTask(projects.RunTasksParams{
+		Targets:     []string{"shared:build"},
+		Context:     context.Background(),
+		ContextName: "default",
+		Stdout:      &stdout,
+		Stderr:      &stdout,
+	})
+	if err != nil {
+		t.Fatalf("failed to run tasks: %v\nOutput: %s", err, stdout.String())
+	}
+
+	output := stdout.String()
+	for _, want := range []string{"module-lint", "project-lint", "module-build project"} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("expected %q in output, got: %s", want, output)
+		}
+	}
+	if strings.Index(output, "module-lint") > strings.Index(output, "project-lint") {
+		t.Fatalf("expected super to run before the override, got: %s", output)
+	}
+
+	if _, ok := proj.Tasks.Get("shared:build:base"); !ok {
+		t.Fatalf("expected the imported task to stay available as shared:build:base")
+	}
+}
@@ -12,8 +12,9 @@ import (
 	"go.yaml.in/yaml/v4"
 )
 
-func loadInventories(p *Project) error {
-	if len(p.Schema.Inventories) == 0 {
+// loadInventories loads the inventory files named by refs into the hosts of p.
+func loadInventories(p *Project, refs []string) error {
+	if len(refs) == 0 {
 		return nil
 	}
 
@@ -30,7 +31,7 @@ func loadInventories(p *Project) error {
 	}
 	globalInvDir := filepath.Join(dataDir, "cast", "inventory")
 
-	for _, invRef := range p.Schema.Inventories {
+	for _, invRef := range refs {
 		var invPath string
 		found := false
 
@@ -6,6 +6,7 @@ import (
 
 	"github.com/frostyeti/cast/internal/errors"
 	"github.com/frostyeti/cast/internal/runstatus"
+	"github.com/frostyeti/cast/internal/types"
 )
 
 type RunJobParams struct {
@@ -135,12 +136,49 @@ func (p *Project) RunJob(params RunJobParams) error {
 		}
 	}
 
+	problems := []RequiredEnvProblem{}
 	for _, jobID := range jobsToRun {
 		job, ok := p.Schema.Jobs.Get(jobID)
 		if !ok {
 			return errors.Newf("job %s not found", jobID)
 		}
 
+		targets := []string{}
+		for _, step := range job.Steps {
+			if step.TaskName != nil {
+				targets = append(targets, *step.TaskName)
+			}
+		}
+
+		taskList, err := p.Tasks.FlattenTasks(targets, params.ContextName)
+		if err != nil {
+			return errors.Newf("job %s: %w", jobID, err)
+		}
+
+		jobProblems, err := p.collectRequiredEnvProblems(p.Env, taskList, jobRequiredEnvScope(p, job))
+		if err != nil {
+			return err
+		}
+		problems = appendRequiredEnvProblems(problems, jobProblems...)
+	}
+
+	if len(problems) > 0 {
+		return &RequiredEnvError{Problems: problems}
+	}
+
+	for _, jobID := range jobsToRun {
+		job, ok := p.Schema.Jobs.Get(jobID)
+		if !ok {
+			return errors.Newf("job %s not found", jobID)
+		}
+
+		projectVars, _ := p.Scope.Get("vars")
+		baseVars, _ := projectVars.(map[string]any)
+		vars, err := resolveVars(job.Vars, p.Scope.ToMap(), baseVars)
+		if err != nil {
+			return errors.Newf("job %s: %w", jobID, err)
+		}
+
 		for _, step := range job.Steps {
 			if step.TaskName != nil {
 				runParams := RunTasksParams{
@@ -148,8 +186,10 @@ func (p *Project) RunJob(params RunJobParams) error {
 					Context:     params.Context,
 					ContextName: params.ContextName,
 					Args:        params.Args,
+					Vars:        vars,
 					Stdout:      params.Stdout,
 					Stderr:      params.Stderr,
+					requiredEnv: []requiredEnvScope{jobRequiredEnvScope(p, job)},
 				}
 
 				results, err := p.RunTask(runParams)
@@ -172,3 +212,12 @@ func (p *Project) RunJob(params RunJobParams) error {
 
 	return nil
 }
+
+func jobRequiredEnvScope(p *Project, job types.Job) requiredEnvScope {
+	file := job.File
+	if file == "" {
+		file = p.File
+	}
+
+	return requiredEnvScope{label: "job " + job.Id, file: file, reqs: job.EnvRequired}
+}
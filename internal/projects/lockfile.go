@@ -0,0 +1,218 @@
+package projects
+
+import (
+	"os"
+	stdexec "os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/frostyeti/cast/internal/errors"
+	"github.com/frostyeti/cast/internal/integrity"
+	"go.yaml.in/yaml/v4"
+)
+
+// LockfileName is the name of the lockfile written next to the castfile.
+const LockfileName = "cast.lock"
+
+const lockfileHeader = "# cast.lock is generated by `cast task install` and `cast task update`. Do not edit.\n"
+
+// LockMode controls how remote task and module refs are checked against
+// cast.lock.
+type LockMode int
+
+const (
+	// LockAuto verifies refs only when running in CI and a lockfile exists.
+	LockAuto LockMode = iota
+	// LockFrozen fails when a fetched ref is missing from cast.lock or differs
+	// from the locked commit or content hash.
+	LockFrozen
+	// LockWrite records every fetched ref so the lockfile can be rewritten.
+	LockWrite
+)
+
+// Lockfile pins every remote task and module ref of a project to the commit
+// it resolved to and a hash of the fetched tree.
+type Lockfile struct {
+	Version int                  `yaml:"version"`
+	Tasks   map[string]LockEntry `yaml:"tasks,omitempty"`
+	Modules map[string]LockEntry `yaml:"modules,omitempty"`
+}
+
+// LockEntry is a single locked ref. Commit is empty for sources that are not
+// git repositories, such as tarballs.
+type LockEntry struct {
+	Resolved string `yaml:"resolved,omitempty"`
+	Commit   string `yaml:"commit,omitempty"`
+	Hash     string `yaml:"hash"`
+}
+
+// NewLockfile returns an empty lockfile.
+func NewLockfile() *Lockfile {
+	return &Lockfile{
+		Version: 1,
+		Tasks:   map[string]LockEntry{},
+		Modules: map[string]LockEntry{},
+	}
+}
+
+// LockfilePath returns the path of cast.lock for a project directory.
+func LockfilePath(dir string) string {
+	return filepath.Join(dir, LockfileName)
+}
+
+// ReadLockfile reads cast.lock from dir. A missing file returns nil without
+// an error.
+func ReadLockfile(dir string) (*Lockfile, error) {
+	data, err := os.ReadFile(LockfilePath(dir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	lock := NewLockfile()
+	if err := yaml.Unmarshal(data, lock); err != nil {
+		return nil, errors.Newf("failed to parse %s: %w", LockfilePath(dir), err)
+	}
+	if lock.Tasks == nil {
+		lock.Tasks = map[string]LockEntry{}
+	}
+	if lock.Modules == nil {
+		lock.Modules = map[string]LockEntry{}
+	}
+
+	return lock, nil
+}
+
+// Write saves the lockfile to dir.
+func (l *Lockfile) Write(dir string) error {
+	data, err := yaml.Marshal(l)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(LockfilePath(dir), append([]byte(lockfileHeader), data...), 0o644)
+}
+
+// WriteLockfile writes the refs recorded while the project was initialized
+// and its remote tasks were fetched. The project must use LockWrite.
+func (p *Project) WriteLockfile() error {
+	if p.LockMode != LockWrite {
+		return errors.New("the lockfile can only be written when the project uses LockWrite")
+	}
+
+	if p.lockRecorded == nil {
+		p.lockRecorded = NewLockfile()
+	}
+
+	return p.lockRecorded.Write(p.Dir)
+}
+
+func (p *Project) lockFrozen() bool {
+	switch p.LockMode {
+	case LockFrozen:
+		return true
+	case LockWrite:
+		return false
+	}
+
+	if !isCIEnabled() {
+		return false
+	}
+
+	_, err := os.Stat(LockfilePath(p.Dir))
+	return err == nil
+}
+
+func isCIEnabled() bool {
+	v := strings.ToLower(strings.TrimSpace(os.Getenv("CI")))
+	switch v {
+	case "1", "true", "yes", "on":
+		return true
+	default:
+		return false
+	}
+}
+
+// lockRef records or verifies a fetched remote ref. kind is "tasks" or
+// "modules" and dir is the directory the ref was fetched into.
+func (p *Project) lockRef(kind, ref, resolved, dir string) error {
+	frozen := p.lockFrozen()
+	if !frozen && p.LockMode != LockWrite {
+		return nil
+	}
+
+	entry, err := newLockEntry(resolved, dir)
+	if err != nil {
+		return errors.Newf("failed to hash %s: %w", ref, err)
+	}
+
+	if p.LockMode == LockWrite {
+		if p.lockRecorded == nil {
+			p.lockRecorded = NewLockfile()
+		}
+		p.lockRecorded.entries(kind)[ref] = entry
+		return nil
+	}
+
+	if p.lock == nil {
+		lock, err := ReadLockfile(p.Dir)
+		if err != nil {
+			return err
+		}
+		if lock == nil {
+			return errors.Newf("%s not found; run `cast task install` to create it", LockfilePath(p.Dir))
+		}
+		p.lock = lock
+	}
+
+	locked, ok := p.lock.entries(kind)[ref]
+	if !ok {
+		return errors.Newf("%s is not in %s; run `cast task install` to update it", ref, LockfileName)
+	}
+	if locked.Commit != "" && entry.Commit != "" && locked.Commit != entry.Commit {
+		return errors.Newf("%s resolved to commit %s but %s pins %s; run `cast task update` to accept the change", ref, entry.Commit, LockfileName, locked.Commit)
+	}
+	if locked.Hash != entry.Hash {
+		return errors.Newf("%s content hash %s does not match %s in %s; run `cast task update` to accept the change", ref, entry.Hash, locked.Hash, LockfileName)
+	}
+
+	return nil
+}
+
+func (l *Lockfile) entries(kind string) map[string]LockEntry {
+	if kind == "modules" {
+		return l.Modules
+	}
+
+	return l.Tasks
+}
+
+func newLockEntry(resolved, dir string) (LockEntry, error) {
+	hash, err := integrity.HashTree(dir)
+	if err != nil {
+		return LockEntry{}, err
+	}
+
+	return LockEntry{
+		Resolved: resolved,
+		Commit:   gitHeadCommit(dir),
+		Hash:     hash,
+	}, nil
+}
+
+// gitHeadCommit returns the commit checked out in dir, or an empty string
+// when dir is not a git repository.
+func gitHeadCommit(dir string) string {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		return ""
+	}
+
+	out, err := stdexec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(out))
+}
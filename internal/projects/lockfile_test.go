@@ -0,0 +1,127 @@
+package projects
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func setupLockfileRemote(t *testing.T) (string, string, string) {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	t.Setenv(CastRemoteTasksDirEnv, filepath.Join(tmpDir, "stable"))
+	t.Setenv(CastVolatileRemoteTasksDirEnv, filepath.Join(tmpDir, "volatile"))
+	t.Setenv("CI", "")
+
+	repoDir := filepath.Join(tmpDir, "remote-repo")
+	spellDir := filepath.Join(repoDir, "hello")
+	if err := os.MkdirAll(spellDir, 0o755); err != nil {
+		t.Fatalf("mkdir remote repo: %v", err)
+	}
+	spell := "name: hello\nruns:\n  using: composite\n  steps:\n    - uses: bash\n      run: echo hello\n"
+	if err := os.WriteFile(filepath.Join(spellDir, "spell"), []byte(spell), 0o644); err != nil {
+		t.Fatalf("write spell: %v", err)
+	}
+
+	runGit(t, repoDir, "init")
+	runGit(t, repoDir, "config", "user.name", "Test User")
+	runGit(t, repoDir, "config", "user.email", "test@example.com")
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "initial")
+	runGit(t, repoDir, "tag", "v1.0.0")
+
+	projectDir := filepath.Join(tmpDir, "project")
+	if err := os.MkdirAll(projectDir, 0o755); err != nil {
+		t.Fatalf("mkdir project: %v", err)
+	}
+	projectFile := filepath.Join(projectDir, "castfile.yaml")
+	if err := os.WriteFile(projectFile, []byte("name: lockfile-test\n"), 0o644); err != nil {
+		t.Fatalf("write project file: %v", err)
+	}
+
+	return repoDir, projectDir, projectFile
+}
+
+func newLockfileProject(projectDir, projectFile string, mode LockMode) *Project {
+	return &Project{
+		Dir:      projectDir,
+		File:     projectFile,
+		CastDir:  filepath.Join(projectDir, ".cast"),
+		LockMode: mode,
+	}
+}
+
+func TestLockfile_WriteAndVerifyRemoteTasks(t *testing.T) {
+	repoDir, projectDir, projectFile := setupLockfileRemote(t)
+	uses := "file://" + filepath.ToSlash(repoDir) + "@v1.0.0/hello"
+
+	writer := newLockfileProject(projectDir, projectFile, LockWrite)
+	entry, err := FetchRemoteTaskWithOptions(writer, uses, nil, FetchRemoteTaskOptions{Stdout: io.Discard})
+	if err != nil {
+		t.Fatalf("failed to fetch remote task: %v", err)
+	}
+	if err := writer.WriteLockfile(); err != nil {
+		t.Fatalf("failed to write lockfile: %v", err)
+	}
+
+	lock, err := ReadLockfile(projectDir)
+	if err != nil || lock == nil {
+		t.Fatalf("failed to read lockfile: %v", err)
+	}
+	locked, ok := lock.Tasks[uses]
+	if !ok {
+		t.Fatalf("expected %s in lockfile, got %v", uses, lock.Tasks)
+	}
+	commit := strings.TrimSpace(runGitOutput(t, repoDir, "rev-parse", "HEAD"))
+	if locked.Commit != commit || locked.Resolved != "v1.0.0" || !strings.HasPrefix(locked.Hash, "sha256:") {
+		t.Fatalf("unexpected lock entry: %+v (commit %s)", locked, commit)
+	}
+
+	frozen := newLockfileProject(projectDir, projectFile, LockFrozen)
+	if _, err := FetchRemoteTaskWithOptions(frozen, uses, nil, FetchRemoteTaskOptions{Stdout: io.Discard}); err != nil {
+		t.Fatalf("expected frozen fetch to match the lockfile, got: %v", err)
+	}
+
+	missing := newLockfileProject(projectDir, projectFile, LockFrozen)
+	other := "file://" + filepath.ToSlash(repoDir) + "@v1.0.0"
+	_, err = FetchRemoteTaskWithOptions(missing, other, nil, FetchRemoteTaskOptions{Stdout: io.Discard})
+	if err == nil || !strings.Contains(err.Error(), "is not in cast.lock") {
+		t.Fatalf("expected missing lock entry error, got: %v", err)
+	}
+
+	if err := os.WriteFile(entry, []byte("name: tampered\n"), 0o644); err != nil {
+		t.Fatalf("failed to modify cached task: %v", err)
+	}
+
+	drifted := newLockfileProject(projectDir, projectFile, LockFrozen)
+	_, err = FetchRemoteTaskWithOptions(drifted, uses, nil, FetchRemoteTaskOptions{Stdout: io.Discard})
+	if err == nil || !strings.Contains(err.Error(), "content hash") {
+		t.Fatalf("expected content hash drift error, got: %v", err)
+	}
+
+	unchecked := newLockfileProject(projectDir, projectFile, LockAuto)
+	if _, err := FetchRemoteTaskWithOptions(unchecked, uses, nil, FetchRemoteTaskOptions{Stdout: io.Discard}); err != nil {
+		t.Fatalf("expected drift to be ignored outside CI, got: %v", err)
+	}
+
+	t.Setenv("CI", "true")
+	ci := newLockfileProject(projectDir, projectFile, LockAuto)
+	_, err = FetchRemoteTaskWithOptions(ci, uses, nil, FetchRemoteTaskOptions{Stdout: io.Discard})
+	if err == nil || !strings.Contains(err.Error(), "content hash") {
+		t.Fatalf("expected CI runs to verify the lockfile, got: %v", err)
+	}
+}
+
+func TestLockfile_FrozenRequiresLockfile(t *testing.T) {
+	repoDir, projectDir, projectFile := setupLockfileRemote(t)
+	uses := "file://" + filepath.ToSlash(repoDir) + "@v1.0.0/hello"
+
+	p := newLockfileProject(projectDir, projectFile, LockFrozen)
+	_, err := FetchRemoteTaskWithOptions(p, uses, nil, FetchRemoteTaskOptions{Stdout: io.Discard})
+	if err == nil || !strings.Contains(err.Error(), "run `cast task install`") {
+		t.Fatalf("expected missing lockfile error, got: %v", err)
+	}
+}
@@ -0,0 +1,426 @@
+package projects
+
+import (
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/frostyeti/cast/internal/errors"
+	"github.com/frostyeti/cast/internal/integrity"
+	"github.com/frostyeti/cast/internal/offline"
+	"go.yaml.in/yaml/v4"
+)
+
+// RefUpdate is a versioned remote ref written in a castfile or one of its
+// local modules, with the newest versions published for it. Wanted is the
+// newest version in the same major and Latest the newest overall, both
+// written with the prefix and precision of Current, so `@v1.2` compares
+// against `v1.5` rather than `v1.5.3`.
+type RefUpdate struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Kind    string `json:"kind"`
+	Ref     string `json:"ref"`
+	Current string `json:"current"`
+	Wanted  string `json:"wanted,omitempty"`
+	Latest  string `json:"latest,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Outdated reports whether a newer version than Current is published.
+func (u RefUpdate) Outdated() bool {
+	return u.Error == "" && (u.Wanted != u.Current || u.Latest != u.Current)
+}
+
+// Target returns the version an upgrade moves to: Latest when major is set,
+// otherwise Wanted.
+func (u RefUpdate) Target(major bool) string {
+	if major {
+		return u.Latest
+	}
+
+	return u.Wanted
+}
+
+// UpgradedRef returns Ref pointing at Target(major). An integrity pin is
+// dropped because it cannot match the new version.
+func (u RefUpdate) UpgradedRef(major bool) string {
+	ref, _ := integrity.Split(u.Ref)
+	source, rest := splitModuleRef(ref)
+	version, _ := splitVersionAndSubPath(rest)
+
+	return source + "@" + u.Target(major) + rest[len(version):]
+}
+
+// FindRefUpdates scans the `uses` values and imports of the castfile of p and
+// its local files for refs pinned to a semver tag or registry version, and
+// looks up the newest published versions of each. Branch, commit and HEAD
+// refs are skipped since they are not pinned to a release. Lookup failures
+// are recorded on the update instead of failing the scan.
+func FindRefUpdates(p *Project) ([]RefUpdate, error) {
+	if offline.Enabled() {
+		return nil, errors.New("checking for newer versions needs network access; drop --offline")
+	}
+
+	files, err := LocalFiles(p.File)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := []RefUpdate{}
+	for _, file := range files {
+		found, err := scanVersionedRefs(file)
+		if err != nil {
+			return nil, err
+		}
+		updates = append(updates, found...)
+	}
+
+	type lookup struct {
+		versions []string
+		err      error
+	}
+
+	// refs of one repository or spell share a single lookup.
+	lookups := map[string]lookup{}
+	for i := range updates {
+		update := &updates[i]
+
+		key, err := refVersionSource(p, update.Ref)
+		if err != nil {
+			update.Error = err.Error()
+			continue
+		}
+
+		found, ok := lookups[key]
+		if !ok {
+			found.versions, found.err = publishedVersions(p, update.Ref)
+			lookups[key] = found
+		}
+		if found.err != nil {
+			update.Error = found.err.Error()
+			continue
+		}
+
+		update.Wanted, update.Latest = newestVersions(update.Current, found.versions)
+	}
+
+	return updates, nil
+}
+
+// ApplyRefUpdates rewrites every outdated ref in place, moving it to its
+// Wanted version, or Latest when major is set. Only the ref text changes, so
+// comments and formatting are kept. It returns the updates it applied.
+func ApplyRefUpdates(updates []RefUpdate, major bool) ([]RefUpdate, error) {
+	byFile := map[string][]RefUpdate{}
+	order := []string{}
+	for _, update := range updates {
+		if !update.Outdated() || update.Target(major) == update.Current {
+			continue
+		}
+		if _, ok := byFile[update.File]; !ok {
+			order = append(order, update.File)
+		}
+		byFile[update.File] = append(byFile[update.File], update)
+	}
+
+	applied := []RefUpdate{}
+	for _, file := range order {
+		// rewrite from the end of each line so earlier columns stay valid.
+		slices.SortStableFunc(byFile[file], func(a, b RefUpdate) int {
+			if a.Line != b.Line {
+				return a.Line - b.Line
+			}
+			return b.Column - a.Column
+		})
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return applied, err
+		}
+
+		lines := strings.SplitAfter(string(data), "\n")
+		for _, update := range byFile[file] {
+			if update.Line < 1 || update.Line > len(lines) {
+				return applied, errors.Newf("%s:%d: ref %s is out of range", file, update.Line, update.Ref)
+			}
+
+			line := lines[update.Line-1]
+			start := min(max(update.Column-1, 0), len(line))
+			idx := strings.Index(line[start:], update.Ref)
+			if idx < 0 {
+				return applied, errors.Newf("%s:%d: could not find ref %s to rewrite", file, update.Line, update.Ref)
+			}
+			idx += start
+
+			lines[update.Line-1] = line[:idx] + update.UpgradedRef(major) + line[idx+len(update.Ref):]
+			applied = append(applied, update)
+		}
+
+		info, err := os.Stat(file)
+		if err != nil {
+			return applied, err
+		}
+		if err := os.WriteFile(file, []byte(strings.Join(lines, "")), info.Mode().Perm()); err != nil {
+			return applied, err
+		}
+	}
+
+	return applied, nil
+}
+
+// scanVersionedRefs returns the remote refs in file that are pinned to a
+// version: every `uses` value, and the top level `imports` or `modules`
+// entries.
+func scanVersionedRefs(file string) ([]RefUpdate, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(data, doc); err != nil {
+		return nil, errors.Newf("failed to parse %s: %w", file, err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	updates := []RefUpdate{}
+	add := func(kind string, node *yaml.Node) {
+		if node == nil || node.Kind != yaml.ScalarNode {
+			return
+		}
+		if current := refVersion(kind, node.Value); current != "" {
+			updates = append(updates, RefUpdate{
+				File:    file,
+				Line:    node.Line,
+				Column:  node.Column,
+				Kind:    kind,
+				Ref:     node.Value,
+				Current: current,
+			})
+		}
+	}
+
+	var walk func(node *yaml.Node)
+	walk = func(node *yaml.Node) {
+		if node.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == "uses" {
+					add("task", node.Content[i+1])
+				}
+			}
+		}
+		for _, child := range node.Content {
+			walk(child)
+		}
+	}
+
+	root := doc.Content[0]
+	walk(root)
+
+	if root.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if key := root.Content[i].Value; key != "imports" && key != "modules" {
+				continue
+			}
+
+			value := root.Content[i+1]
+			items := []*yaml.Node{value}
+			if value.Kind == yaml.SequenceNode {
+				items = value.Content
+			}
+			for _, item := range items {
+				if item.Kind == yaml.MappingNode {
+					item = mappingValue(item, "from")
+				}
+				add("import", item)
+			}
+		}
+	}
+
+	slices.SortStableFunc(updates, func(a, b RefUpdate) int {
+		return a.Line - b.Line
+	})
+
+	return updates, nil
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// refVersion returns the semver version ref is pinned to, or an empty string
+// when it is local, unversioned or follows a branch, commit or HEAD.
+func refVersion(kind, ref string) string {
+	ref, _ = integrity.Split(strings.TrimSpace(ref))
+	if kind == "import" {
+		if !isRemoteModuleRef(ref) || isModuleTarball(ref) {
+			return ""
+		}
+	} else if !IsRemoteTask(ref) || isLocalRef(ref) || strings.HasPrefix(ref, "jsr:") || strings.HasPrefix(ref, "npm:") {
+		return ""
+	}
+
+	_, rest := splitModuleRef(ref)
+	version, _ := splitVersionAndSubPath(rest)
+	if version == "" || isHeadRef(version) || isGitCommitRef(version) {
+		return ""
+	}
+	if _, ok := parseConstraintVersion(version); !ok {
+		return ""
+	}
+
+	return version
+}
+
+// refVersionSource returns the key published versions are shared under:
+// the repository and subpath of a git ref, or the spell of a registry ref.
+func refVersionSource(p *Project, ref string) (string, error) {
+	ref, _ = integrity.Split(ref)
+	if IsRegistryRef(ref) {
+		name, _, err := parseRegistryRef(ref)
+		if err != nil {
+			return "", err
+		}
+		return ResolveRegistryURL(p) + "\x00" + name, nil
+	}
+
+	target, err := parseRemoteGitTarget(ref)
+	if err != nil {
+		return "", err
+	}
+
+	return target.repoURL + "\x00" + target.subPath, nil
+}
+
+// publishedVersions lists the versions published for ref: registry releases,
+// or the tags of its repository. Tags prefixed with the subpath of the ref,
+// such as `deploy/v1.2.0`, are preferred as resolveGitReference does.
+func publishedVersions(p *Project, ref string) ([]string, error) {
+	ref, _ = integrity.Split(ref)
+	if IsRegistryRef(ref) {
+		name, _, err := parseRegistryRef(ref)
+		if err != nil {
+			return nil, err
+		}
+
+		index, err := LoadRegistryIndex(p.Dir, ResolveRegistryURL(p))
+		if err != nil {
+			return nil, err
+		}
+		spell, ok := index.Find(name)
+		if !ok {
+			return nil, errors.Newf("spell %q is not in registry %s", name, index.url)
+		}
+
+		versions := []string{}
+		for _, release := range spell.Versions {
+			versions = append(versions, release.Version)
+		}
+		return versions, nil
+	}
+
+	target, err := parseRemoteGitTarget(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := listVersionTags(remoteFetchMode(p), target.repoURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if target.subPath != "" {
+		prefix := strings.TrimSuffix(target.subPath, "/") + "/"
+		prefixed := []string{}
+		for _, tag := range tags {
+			if version, ok := strings.CutPrefix(tag, prefix); ok {
+				prefixed = append(prefixed, version)
+			}
+		}
+		if len(prefixed) > 0 {
+			return prefixed, nil
+		}
+	}
+
+	return slices.DeleteFunc(tags, func(tag string) bool {
+		return strings.Contains(tag, "/")
+	}), nil
+}
+
+// newestVersions returns the newest release in the major of current and the
+// newest overall, formatted like current. Pre-releases are ignored, and
+// current is returned when nothing newer is published.
+func newestVersions(current string, versions []string) (string, string) {
+	currentParts, _ := parseConstraintVersion(current)
+	precision := versionPrecision(current)
+
+	var wanted, latest [3]int
+	foundWanted, foundLatest := false, false
+	for _, version := range versions {
+		if strings.Contains(strings.TrimLeft(version, "vV"), "-") {
+			continue
+		}
+		major, minor, patch, ok := parseVersionParts(version)
+		if !ok {
+			continue
+		}
+
+		parts := [3]int{major, minor, patch}
+		if !foundLatest || compareVersionParts(parts, latest) > 0 {
+			latest, foundLatest = parts, true
+		}
+		if major == currentParts[0] && (!foundWanted || compareVersionParts(parts, wanted) > 0) {
+			wanted, foundWanted = parts, true
+		}
+	}
+
+	newer := func(parts [3]int, found bool) string {
+		if !found || compareVersionParts(truncateVersion(parts, precision), truncateVersion(currentParts, precision)) <= 0 {
+			return current
+		}
+		return formatVersionLike(current, parts, precision)
+	}
+
+	return newer(wanted, foundWanted), newer(latest, foundLatest)
+}
+
+// versionPrecision returns how many of major, minor and patch version
+// spells out.
+func versionPrecision(version string) int {
+	return min(len(strings.Split(trimVersion(version), ".")), 3)
+}
+
+func truncateVersion(parts [3]int, precision int) [3]int {
+	for i := precision; i < len(parts); i++ {
+		parts[i] = 0
+	}
+
+	return parts
+}
+
+// formatVersionLike writes parts with the `v` prefix of like and the given
+// precision.
+func formatVersionLike(like string, parts [3]int, precision int) string {
+	prefix := ""
+	if strings.HasPrefix(like, "v") || strings.HasPrefix(like, "V") {
+		prefix = like[:1]
+	}
+
+	values := make([]string, 0, precision)
+	for _, part := range parts[:precision] {
+		values = append(values, strconv.Itoa(part))
+	}
+
+	return prefix + strings.Join(values, ".")
+}
@@ -0,0 +1,116 @@
+package projects
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewestVersionsKeepsPrefixAndPrecision(t *testing.T) {
+	published := []string{"v1.0.0", "v1.2.0", "v1.4.1", "v1.5.0-rc.1", "v2.0.0", "v2.3.1", "latest"}
+
+	cases := []struct {
+		current, wanted, latest string
+	}{
+		{"v1.2.0", "v1.4.1", "v2.3.1"},
+		{"v1.2", "v1.4", "v2.3"},
+		{"v1", "v1", "v2"},
+		{"1.4.1", "1.4.1", "2.3.1"},
+		{"v2.3.1", "v2.3.1", "v2.3.1"},
+	}
+	for _, c := range cases {
+		wanted, latest := newestVersions(c.current, published)
+		if wanted != c.wanted || latest != c.latest {
+			t.Errorf("newestVersions(%q) = %q, %q; want %q, %q", c.current, wanted, latest, c.wanted, c.latest)
+		}
+	}
+}
+
+func TestFindAndApplyRefUpdates(t *testing.T) {
+	repoDir, _, projectFile := setupLockfileRemote(t)
+	runGit(t, repoDir, "tag", "v1.2.0")
+	runGit(t, repoDir, "tag", "v1.3.0-rc.1")
+	runGit(t, repoDir, "tag", "v2.1.0")
+
+	repo := "file://" + filepath.ToSlash(repoDir)
+	castfile := `# deploy helpers
+imports:
+  - from: ` + repo + `@v1.0.0 # shared module
+tasks:
+  lint:
+    uses: "` + repo + `@v1.0.0/hello" # keep this comment
+  deploy:
+    uses: ` + repo + `@v1/hello
+  pinned:
+    uses: ` + repo + `@v1.0.0/hello#sha256=3b5d5c3712955042212316173ccf37be800ab4d6b4e2b1e1b5d7f0c2c7f3d4e1
+  tip:
+    uses: ` + repo + `@HEAD/hello
+  shell:
+    uses: bash
+    run: echo hi
+`
+	if err := os.WriteFile(projectFile, []byte(castfile), 0o644); err != nil {
+		t.Fatalf("write castfile: %v", err)
+	}
+
+	p := &Project{}
+	if err := p.LoadFromYaml(projectFile); err != nil {
+		t.Fatalf("load project: %v", err)
+	}
+
+	updates, err := FindRefUpdates(p)
+	if err != nil {
+		t.Fatalf("failed to find updates: %v", err)
+	}
+	if len(updates) != 4 {
+		t.Fatalf("expected the import and three pinned uses, got %+v", updates)
+	}
+	for _, update := range updates {
+		if update.Error != "" {
+			t.Fatalf("unexpected lookup error: %+v", update)
+		}
+	}
+	if updates[0].Kind != "import" || updates[0].Line != 3 || updates[0].Wanted != "v1.2.0" || updates[0].Latest != "v2.1.0" {
+		t.Fatalf("unexpected import update %+v", updates[0])
+	}
+	if deploy := updates[2]; deploy.Current != "v1" || deploy.Wanted != "v1" || deploy.Latest != "v2" {
+		t.Fatalf("expected the v1 family to only be outdated across majors, got %+v", deploy)
+	}
+
+	applied, err := ApplyRefUpdates(updates, false)
+	if err != nil {
+		t.Fatalf("failed to apply updates: %v", err)
+	}
+	if len(applied) != 3 {
+		t.Fatalf("expected three in-major upgrades, got %+v", applied)
+	}
+
+	data, err := os.ReadFile(projectFile)
+	if err != nil {
+		t.Fatalf("read castfile: %v", err)
+	}
+	want := strings.NewReplacer(
+		repo+"@v1.0.0 # shared", repo+"@v1.2.0 # shared",
+		repo+`@v1.0.0/hello" # keep`, repo+`@v1.2.0/hello" # keep`,
+		repo+"@v1.0.0/hello#sha256=3b5d5c3712955042212316173ccf37be800ab4d6b4e2b1e1b5d7f0c2c7f3d4e1", repo+"@v1.2.0/hello",
+	).Replace(castfile)
+	if string(data) != want {
+		t.Fatalf("expected only the refs to change, got:\n%s", data)
+	}
+
+	updates, err = FindRefUpdates(p)
+	if err != nil {
+		t.Fatalf("failed to find updates: %v", err)
+	}
+	if _, err := ApplyRefUpdates(updates, true); err != nil {
+		t.Fatalf("failed to apply major updates: %v", err)
+	}
+	data, err = os.ReadFile(projectFile)
+	if err != nil {
+		t.Fatalf("read castfile: %v", err)
+	}
+	if !strings.Contains(string(data), repo+"@v2/hello\n") || strings.Count(string(data), "@v2.1.0") != 3 || !strings.Contains(string(data), "# keep this comment") {
+		t.Fatalf("expected every ref on the newest major, got:\n%s", data)
+	}
+}
@@ -0,0 +1,393 @@
+package projects
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/frostyeti/cast/internal/archive"
+	"github.com/frostyeti/cast/internal/errors"
+	"github.com/frostyeti/cast/internal/integrity"
+	"github.com/frostyeti/cast/internal/types"
+)
+
+// PackManifestName is the manifest written at the root of every packed
+// spell tarball.
+const PackManifestName = "cast.manifest.json"
+
+var castTaskInputNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// PackManifest describes a packed spell. Files maps each packed path to the
+// hex sha256 of its content; the manifest itself is not listed.
+type PackManifest struct {
+	Name        string            `json:"name"`
+	Version     string            `json:"version"`
+	Description string            `json:"description,omitempty"`
+	Entry       string            `json:"entry"`
+	Using       string            `json:"using"`
+	Files       map[string]string `json:"files"`
+}
+
+// PackOptions controls PackCastTask. When Key is set the packed content is
+// signed into a detached `<tarball>.sig`.
+type PackOptions struct {
+	Version string
+	OutDir  string
+	Key     ed25519.PrivateKey
+}
+
+// PackResult is the tarball written by PackCastTask. Sha256 is the digest of
+// the tarball, as listed in registry indexes, and Integrity is the pin of
+// the extracted tree for `#sha256=` refs. Signature is the detached
+// signature file, if one was written.
+type PackResult struct {
+	Manifest  PackManifest
+	Tarball   string
+	Sha256    string
+	Integrity string
+	Signature string
+}
+
+// ValidateCastTask loads the cast.task definition in dir and reports every
+// problem that would stop it from running: unknown runs.using values,
+// missing main files or images, malformed inputs and composite steps that
+// cannot be resolved from inside the packed spell.
+func ValidateCastTask(dir string) (string, *types.CastTask, error) {
+	file := remoteEntryFile(dir)
+	if file == dir || !IsCastTaskDefinitionFile(file) {
+		return "", nil, errors.Newf("no cast.task definition found in %s", dir)
+	}
+
+	if err := checkFileRequires(file); err != nil {
+		return "", nil, err
+	}
+	def := &types.CastTask{}
+	if err := def.ReadFromYaml(file); err != nil {
+		return "", nil, errors.Newf("failed to read %s: %w", file, err)
+	}
+
+	problems := []string{}
+	if strings.TrimSpace(def.Name) == "" {
+		problems = append(problems, "name is required")
+	}
+
+	for name, input := range def.Inputs {
+		if !castTaskInputNamePattern.MatchString(name) {
+			problems = append(problems, fmt.Sprintf("input %q must start with a letter or underscore and contain only letters, digits, '-' and '_'", name))
+		}
+		if input.Required && input.Default != "" {
+			problems = append(problems, fmt.Sprintf("input %q is required but also has a default", name))
+		}
+	}
+
+	switch using := strings.TrimSpace(def.Runs.Using); using {
+	case "deno", "bun":
+		main := def.Runs.Main
+		if main == "" {
+			main = "mod.ts"
+		}
+		if _, err := os.Stat(filepath.Join(dir, main)); err != nil {
+			problems = append(problems, fmt.Sprintf("runs.main %s does not exist", main))
+		}
+	case "docker":
+		if strings.TrimSpace(def.Runs.Image) == "" {
+			problems = append(problems, "runs.image is required when runs.using is docker")
+		}
+	case "composite":
+		if len(def.Runs.Steps) == 0 {
+			problems = append(problems, "runs.steps is required when runs.using is composite")
+		}
+		for i, step := range def.Runs.Steps {
+			if problem := compositeStepProblem(step); problem != "" {
+				problems = append(problems, fmt.Sprintf("step %d %s", i+1, problem))
+			}
+		}
+	case "":
+		problems = append(problems, "runs.using is required")
+	default:
+		problems = append(problems, fmt.Sprintf("runs.using %q is not one of deno, bun, docker or composite", using))
+	}
+
+	if len(problems) > 0 {
+		return file, def, errors.Newf("%s is not a valid cast.task:\n  - %s", file, strings.Join(problems, "\n  - "))
+	}
+
+	return file, def, nil
+}
+
+// compositeStepProblem mirrors the handler lookup of runCastTaskComposite
+// and describes why step could not run, or returns an empty string.
+func compositeStepProblem(step types.Task) string {
+	uses := ""
+	if step.Uses != nil {
+		uses = strings.TrimSpace(*step.Uses)
+	}
+
+	if uses == "" {
+		if step.Run == nil || strings.TrimSpace(*step.Run) == "" {
+			return "requires uses or run"
+		}
+		return ""
+	}
+
+	if _, ok := GetTaskHandler(uses); ok {
+		return ""
+	}
+
+	if !IsRemoteTask(uses) {
+		return fmt.Sprintf("uses unknown handler %q", uses)
+	}
+
+	ref, pin := integrity.Split(uses)
+	if pin != "" {
+		if err := integrity.Validate(pin); err != nil {
+			return fmt.Sprintf("uses %s with an invalid integrity pin: %v", uses, err)
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(ref, "./") || strings.HasPrefix(ref, "../") || filepath.IsAbs(ref):
+		return fmt.Sprintf("uses local path %s, which resolves against the calling project; publish it as its own spell", uses)
+	case strings.HasPrefix(ref, "jsr:") || strings.HasPrefix(ref, "npm:"):
+		return ""
+	case IsRegistryRef(ref):
+		if _, _, err := parseRegistryRef(ref); err != nil {
+			return err.Error()
+		}
+	default:
+		if _, err := parseRemoteGitTarget(ref); err != nil {
+			return err.Error()
+		}
+	}
+
+	return ""
+}
+
+// PackCastTask validates the cast.task in dir and writes
+// `<name>-<version>.tar.gz` and a `.sha256` checksum file into opts.OutDir.
+// The tarball contains every file under dir, except version control and
+// cast cache directories, plus a manifest.
+func PackCastTask(dir string, opts PackOptions) (*PackResult, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	file, def, err := ValidateCastTask(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	version := strings.TrimSpace(opts.Version)
+	if version == "" {
+		return nil, errors.New("a version is required to pack a spell; pass --version")
+	}
+	if _, _, _, ok := parseVersionParts(version); !ok || strings.ContainsAny(version, "/\\ ") {
+		return nil, errors.Newf("invalid spell version %q, expected a semantic version such as 1.2.0", version)
+	}
+
+	if _, _, err := parseRegistryRef(registryPrefix + def.Name + "@" + version); err != nil || strings.ContainsAny(def.Name, " \t") {
+		return nil, errors.Newf("spell name %q cannot be published; use lowercase letters, digits, '-' and '/'", def.Name)
+	}
+
+	outDir := opts.OutDir
+	if outDir == "" {
+		outDir = "."
+	}
+	outDir, err = filepath.Abs(outDir)
+	if err != nil {
+		return nil, err
+	}
+
+	baseName := strings.ReplaceAll(def.Name, "/", "-") + "-" + version
+	files, err := packFiles(dir, outDir, strings.ReplaceAll(def.Name, "/", "-")+"-")
+	if err != nil {
+		return nil, err
+	}
+
+	staging, err := os.MkdirTemp("", "cast-pack-*")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = os.RemoveAll(staging)
+	}()
+
+	manifest := PackManifest{
+		Name:        def.Name,
+		Version:     version,
+		Description: def.Description,
+		Entry:       filepath.Base(file),
+		Using:       strings.TrimSpace(def.Runs.Using),
+		Files:       map[string]string{},
+	}
+	for _, rel := range files {
+		sum, err := copyPackFile(filepath.Join(dir, filepath.FromSlash(rel)), filepath.Join(staging, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, err
+		}
+		manifest.Files[rel] = sum
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(staging, PackManifestName), append(data, '\n'), 0o644); err != nil {
+		return nil, err
+	}
+
+	tree, err := integrity.HashTree(staging)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return nil, err
+	}
+
+	tarball := filepath.Join(outDir, baseName+".tar.gz")
+	out, err := os.Create(tarball)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.New()
+	err = archive.WriteTarGz(io.MultiWriter(out, sum), staging, append(files, PackManifestName))
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tarball)
+		return nil, err
+	}
+
+	digest := hex.EncodeToString(sum.Sum(nil))
+	checksum := fmt.Sprintf("%s  %s\n", digest, filepath.Base(tarball))
+	if err := os.WriteFile(tarball+".sha256", []byte(checksum), 0o644); err != nil {
+		return nil, err
+	}
+
+	result := &PackResult{
+		Manifest:  manifest,
+		Tarball:   tarball,
+		Sha256:    digest,
+		Integrity: integrity.Pin(tree),
+	}
+
+	// a signature left by an earlier pack may cover different content.
+	sigPath := tarball + signatureSuffix
+	if err := os.Remove(sigPath); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if opts.Key != nil {
+		if err := signTreeTo(staging, sigPath, opts.Key); err != nil {
+			return nil, err
+		}
+		result.Signature = sigPath
+	}
+
+	return result, nil
+}
+
+// packFiles lists the files under dir as slash separated relative paths. It
+// skips .git and .cast, the output directory, earlier tarballs of the same
+// spell and a cast.sig, which cannot cover the manifest added to the tarball.
+func packFiles(dir, outDir, tarballPrefix string) ([]string, error) {
+	files := []string{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if path != dir && (d.Name() == ".git" || d.Name() == ".cast" || path == outDir) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		name := d.Name()
+		if name == PackManifestName || path == filepath.Join(dir, integrity.SignatureFile) {
+			return nil
+		}
+		if strings.HasPrefix(name, tarballPrefix) && (strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tar.gz.sha256") || strings.HasSuffix(name, ".tar.gz"+signatureSuffix)) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+
+	return files, err
+}
+
+func copyPackFile(src, dst string) (string, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return "", err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return "", err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.New()
+	_, err = io.Copy(io.MultiWriter(out, sum), in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
+
+// ReadPackManifest reads the manifest of a packed spell tarball.
+func ReadPackManifest(tarball string) (*PackManifest, error) {
+	data, err := archive.ReadFile(tarball, PackManifestName)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &PackManifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, errors.Newf("failed to parse %s in %s: %w", PackManifestName, tarball, err)
+	}
+	if manifest.Name == "" || manifest.Version == "" {
+		return nil, errors.Newf("%s in %s must set name and version", PackManifestName, tarball)
+	}
+	// both name the published files and directories of the registry.
+	if !isRegistrySpellName(manifest.Name) {
+		return nil, errors.Newf("%s in %s has an invalid name %q", PackManifestName, tarball, manifest.Name)
+	}
+	if !isRegistryVersion(manifest.Version) {
+		return nil, errors.Newf("%s in %s has an invalid version %q", PackManifestName, tarball, manifest.Version)
+	}
+
+	return manifest, nil
+}
@@ -0,0 +1,279 @@
+package projects
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/frostyeti/cast/internal/archive"
+	"github.com/frostyeti/cast/internal/integrity"
+)
+
+func writeSpellDir(t *testing.T, castTask string, extra map[string]string) string {
+	t.Helper()
+
+	dir := filepath.Join(t.TempDir(), "spell")
+	if err := os.MkdirAll(filepath.Join(dir, ".git"), 0o755); err != nil {
+		t.Fatalf("mkdir spell: %v", err)
+	}
+	files := map[string]string{"cast.task": castTask, ".git/HEAD": "ref: refs/heads/main\n"}
+	for name, content := range extra {
+		files[name] = content
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	return dir
+}
+
+const packedCompositeTask = `name: greet
+description: Greet someone
+inputs:
+  who:
+    required: true
+runs:
+  using: composite
+  steps:
+    - run: ./scripts/greet.sh
+    - uses: bash
+      run: echo done
+`
+
+func TestValidateCastTaskReportsEveryProblem(t *testing.T) {
+	dir := writeSpellDir(t, `name: broken
+inputs:
+  "bad name":
+    required: true
+    default: x
+runs:
+  using: composite
+  steps:
+    - name: empty
+    - uses: ./local-task
+    - uses: nope
+    - uses: reg:greet
+`, nil)
+
+	_, _, err := ValidateCastTask(dir)
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, want := range []string{
+		`input "bad name" must start with`,
+		`input "bad name" is required but also has a default`,
+		"step 1 requires uses or run",
+		"step 2 uses local path ./local-task",
+		`step 3 uses unknown handler "nope"`,
+		"step 4 invalid registry task identifier",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q in validation error, got: %v", want, err)
+		}
+	}
+
+	denoDir := writeSpellDir(t, "name: script\nruns:\n  using: deno\n  main: main.ts\n", nil)
+	if _, _, err := ValidateCastTask(denoDir); err == nil || !strings.Contains(err.Error(), "runs.main main.ts does not exist") {
+		t.Fatalf("expected a missing main error, got: %v", err)
+	}
+}
+
+func TestPackCastTaskWritesDeterministicTarball(t *testing.T) {
+	dir := writeSpellDir(t, packedCompositeTask, map[string]string{"scripts/greet.sh": "echo hello $INPUT_WHO\n"})
+	outDir := t.TempDir()
+
+	if _, err := PackCastTask(dir, PackOptions{OutDir: outDir}); err == nil || !strings.Contains(err.Error(), "--version") {
+		t.Fatalf("expected a missing version error, got: %v", err)
+	}
+
+	result, err := PackCastTask(dir, PackOptions{Version: "1.0.0", OutDir: outDir})
+	if err != nil {
+		t.Fatalf("failed to pack: %v", err)
+	}
+	if filepath.Base(result.Tarball) != "greet-1.0.0.tar.gz" {
+		t.Fatalf("unexpected tarball name %s", result.Tarball)
+	}
+
+	checksum, err := os.ReadFile(result.Tarball + ".sha256")
+	if err != nil || string(checksum) != result.Sha256+"  greet-1.0.0.tar.gz\n" {
+		t.Fatalf("unexpected checksum file %q: %v", checksum, err)
+	}
+
+	manifest, err := ReadPackManifest(result.Tarball)
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+	if manifest.Name != "greet" || manifest.Version != "1.0.0" || manifest.Entry != "cast.task" || manifest.Using != "composite" {
+		t.Fatalf("unexpected manifest %+v", manifest)
+	}
+	if _, ok := manifest.Files["scripts/greet.sh"]; !ok || len(manifest.Files) != 2 {
+		t.Fatalf("expected cast.task and scripts/greet.sh without .git, got %v", manifest.Files)
+	}
+
+	extracted := t.TempDir()
+	if err := archive.Download(result.Tarball, extracted, result.Sha256); err != nil {
+		t.Fatalf("failed to extract tarball: %v", err)
+	}
+	if err := integrity.Verify(extracted, result.Integrity); err != nil {
+		t.Fatalf("expected the integrity pin to match the extracted tree: %v", err)
+	}
+
+	again, err := PackCastTask(dir, PackOptions{Version: "1.0.0", OutDir: outDir})
+	if err != nil {
+		t.Fatalf("failed to pack again: %v", err)
+	}
+	if again.Sha256 != result.Sha256 {
+		t.Fatalf("expected packing to be reproducible, got %s and %s", result.Sha256, again.Sha256)
+	}
+}
+
+func TestPublishSpellToDirectoryRegistry(t *testing.T) {
+	dir := writeSpellDir(t, packedCompositeTask, map[string]string{"scripts/greet.sh": "echo hello\n"})
+	registryDir := filepath.Join(t.TempDir(), "registry")
+
+	packed, err := PackCastTask(dir, PackOptions{Version: "1.0.0", OutDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("failed to pack: %v", err)
+	}
+
+	result, err := PublishSpell(registryDir, packed.Tarball, PublishOptions{})
+	if err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+	if result.Unchanged || result.Release.URL != "greet/greet-1.0.0.tar.gz" {
+		t.Fatalf("unexpected publish result %+v", result)
+	}
+
+	again, err := PublishSpell(registryDir, packed.Tarball, PublishOptions{})
+	if err != nil || !again.Unchanged {
+		t.Fatalf("expected republishing the same tarball to be a no-op, got %+v, %v", again, err)
+	}
+
+	p := newRegistryProject(t, registryDir)
+	entry, err := FetchRemoteTask(p, "reg:greet@1", nil, io.Discard)
+	if err != nil {
+		t.Fatalf("failed to fetch the published spell: %v", err)
+	}
+	if filepath.Base(entry) != "cast.task" {
+		t.Fatalf("expected the cast.task entry, got %s", entry)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "scripts", "greet.sh"), []byte("echo changed\n"), 0o644); err != nil {
+		t.Fatalf("modify spell: %v", err)
+	}
+	changed, err := PackCastTask(dir, PackOptions{Version: "1.0.0", OutDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("failed to repack: %v", err)
+	}
+	if _, err := PublishSpell(registryDir, changed.Tarball, PublishOptions{}); err == nil || !strings.Contains(err.Error(), "already published") {
+		t.Fatalf("expected a changed tarball to be refused, got: %v", err)
+	}
+	if _, err := PublishSpell(registryDir, changed.Tarball, PublishOptions{Force: true}); err != nil {
+		t.Fatalf("expected --force to replace the version, got: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(registryDir, "index.json"))
+	if err != nil {
+		t.Fatalf("read index: %v", err)
+	}
+	index := RegistryIndex{}
+	if err := json.Unmarshal(data, &index); err != nil {
+		t.Fatalf("parse index: %v", err)
+	}
+	if len(index.Spells) != 1 || len(index.Spells[0].Versions) != 1 || index.Spells[0].Versions[0].Sha256 != changed.Sha256 {
+		t.Fatalf("expected the forced version in the index, got %+v", index)
+	}
+}
+
+func TestPublishSpellToHTTPRegistry(t *testing.T) {
+	t.Setenv(CastRegistryTokenEnv, "secret")
+
+	var mu sync.Mutex
+	stored := map[string][]byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.Method {
+		case http.MethodGet:
+			content, ok := stored[r.URL.Path]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write(content)
+		case http.MethodPut:
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			content, _ := io.ReadAll(r.Body)
+			stored[r.URL.Path] = content
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer server.Close()
+
+	dir := writeSpellDir(t, packedCompositeTask, map[string]string{"scripts/greet.sh": "echo hello\n"})
+	packed, err := PackCastTask(dir, PackOptions{Version: "2.1.0", OutDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("failed to pack: %v", err)
+	}
+
+	registry := server.URL + "/spells/"
+	if _, err := PublishSpell(registry, packed.Tarball, PublishOptions{}); err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+	if _, ok := stored["/spells/greet/greet-2.1.0.tar.gz"]; !ok {
+		t.Fatalf("expected the tarball to be uploaded, got %v", stored)
+	}
+
+	p := newRegistryProject(t, registry)
+	if _, err := FetchRemoteTask(p, "reg:greet@2", nil, io.Discard); err != nil {
+		t.Fatalf("failed to fetch the published spell over HTTP: %v", err)
+	}
+}
+
+func TestPublishSpellRejectsManifestPathsOutsideRegistry(t *testing.T) {
+	registryDir := filepath.Join(t.TempDir(), "registry")
+	for _, manifest := range []PackManifest{{Name: "../evil", Version: "1.0.0"}, {Name: "greet", Version: "../1.0.0"}} {
+		src := t.TempDir()
+		data, err := json.Marshal(manifest)
+		if err != nil {
+			t.Fatalf("marshal manifest: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(src, PackManifestName), data, 0o644); err != nil {
+			t.Fatalf("write manifest: %v", err)
+		}
+		tarball := filepath.Join(t.TempDir(), "evil.tar.gz")
+		out, err := os.Create(tarball)
+		if err != nil {
+			t.Fatalf("create tarball: %v", err)
+		}
+		if err := archive.WriteTarGz(out, src, []string{PackManifestName}); err != nil {
+			t.Fatalf("write tarball: %v", err)
+		}
+		_ = out.Close()
+
+		if _, err := PublishSpell(registryDir, tarball, PublishOptions{}); err == nil || !strings.Contains(err.Error(), "invalid") {
+			t.Fatalf("expected manifest %+v to be rejected, got: %v", manifest, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Dir(registryDir)); err == nil {
+		if entries, _ := os.ReadDir(filepath.Dir(registryDir)); len(entries) != 0 {
+			t.Fatalf("expected nothing to be published, got %v", entries)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package projects
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/frostyeti/cast/internal/errors"
+	"github.com/frostyeti/cast/internal/types"
+	"github.com/frostyeti/go/exec"
+)
+
+// PrefetchRemoteTasks fetches every ref in uses into the local caches together
+// with the remote tasks their composite steps reference, so later runs can
+// resolve them offline. When refresh is set, branch and head refs are fetched
+// again.
+func PrefetchRemoteTasks(p *Project, uses []string, stdout io.Writer, refresh bool) error {
+	seen := map[string]struct{}{}
+	for _, ref := range uses {
+		if err := prefetchRemoteTask(p, ref, stdout, refresh, seen); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func prefetchRemoteTask(p *Project, uses string, stdout io.Writer, refresh bool, seen map[string]struct{}) error {
+	if _, ok := seen[uses]; ok {
+		return nil
+	}
+	seen[uses] = struct{}{}
+
+	entry, err := fetchRemoteTaskWithOptions(p, uses, p.Schema.TrustedSources, FetchRemoteTaskOptions{
+		Stdout:       stdout,
+		ForceRefresh: refresh && IsVolatileRemoteTaskRef(uses),
+	})
+	if err != nil {
+		return err
+	}
+
+	if strings.HasPrefix(uses, "jsr:") || strings.HasPrefix(uses, "npm:") {
+		return cacheDenoModule(uses, stdout)
+	}
+
+	if !IsCastTaskDefinitionFile(entry) {
+		return nil
+	}
+
+	var def types.CastTask
+	if err := def.ReadFromYaml(entry); err != nil {
+		return err
+	}
+	if strings.TrimSpace(def.Runs.Using) != "composite" {
+		return nil
+	}
+
+	for _, step := range def.Runs.Steps {
+		if step.Uses == nil {
+			continue
+		}
+
+		stepUses := strings.TrimSpace(*step.Uses)
+		if _, ok := GetTaskHandler(stepUses); ok || !IsRemoteTask(stepUses) {
+			continue
+		}
+		if strings.HasPrefix(stepUses, "./") || strings.HasPrefix(stepUses, "../") || filepath.IsAbs(stepUses) {
+			continue
+		}
+
+		if err := prefetchRemoteTask(p, stepUses, stdout, refresh, seen); err != nil {
+			return errors.Newf("failed to prefetch %s for %s: %w", stepUses, uses, err)
+		}
+	}
+
+	return nil
+}
+
+// cacheDenoModule stores a jsr: or npm: module in the deno cache. It is a
+// no-op when deno is not installed.
+func cacheDenoModule(uses string, stdout io.Writer) error {
+	denoExe, _ := exec.Find("deno", nil)
+	if denoExe == "" {
+		return nil
+	}
+
+	_, _ = fmt.Fprintf(stdout, "Caching module: %s\n", uses)
+	cmd := exec.New(denoExe, "cache", uses)
+	cmd.WithStdout(stdout)
+	cmd.WithStderr(stdout)
+	out, err := cmd.Run()
+	if err != nil {
+		return err
+	}
+	if out.Code != 0 {
+		return errors.Newf("deno cache %s exited with code %d", uses, out.Code)
+	}
+
+	return nil
+}
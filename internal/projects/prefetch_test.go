@@ -0,0 +1,45 @@
+package projects
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/frostyeti/cast/internal/offline"
+)
+
+func TestPrefetchRemoteTasks_FetchesCompositeSteps(t *testing.T) {
+	innerRepo, projectDir, projectFile := setupLockfileRemote(t)
+	inner := "file://" + filepath.ToSlash(innerRepo) + "@v1.0.0/hello"
+
+	outerRepo := filepath.Join(filepath.Dir(innerRepo), "outer-repo")
+	if err := os.MkdirAll(outerRepo, 0o755); err != nil {
+		t.Fatalf("mkdir outer repo: %v", err)
+	}
+	castTask := "name: outer\nruns:\n  using: composite\n  steps:\n    - uses: " + inner + "\n    - run: echo done\n"
+	if err := os.WriteFile(filepath.Join(outerRepo, "cast.task"), []byte(castTask), 0o644); err != nil {
+		t.Fatalf("write cast.task: %v", err)
+	}
+	runGit(t, outerRepo, "init")
+	runGit(t, outerRepo, "config", "user.name", "Test User")
+	runGit(t, outerRepo, "config", "user.email", "test@example.com")
+	runGit(t, outerRepo, "add", ".")
+	runGit(t, outerRepo, "commit", "-m", "initial")
+	runGit(t, outerRepo, "tag", "v2.0.0")
+	outer := "file://" + filepath.ToSlash(outerRepo) + "@v2.0.0"
+
+	p := newLockfileProject(projectDir, projectFile, LockWrite)
+	if err := PrefetchRemoteTasks(p, []string{outer}, io.Discard, false); err != nil {
+		t.Fatalf("failed to prefetch: %v", err)
+	}
+	if _, ok := p.lockRecorded.Tasks[inner]; !ok {
+		t.Fatalf("expected the composite step to be locked, got %v", p.lockRecorded.Tasks)
+	}
+
+	t.Setenv(offline.Env, "1")
+	offlineProject := newLockfileProject(projectDir, projectFile, LockAuto)
+	if _, err := FetchRemoteTask(offlineProject, inner, nil, io.Discard); err != nil {
+		t.Fatalf("expected the prefetched step to resolve offline, got: %v", err)
+	}
+}
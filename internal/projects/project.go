@@ -11,7 +11,6 @@ import (
 	"github.com/frostyeti/cast/internal/eval"
 	"github.com/frostyeti/cast/internal/id"
 	"github.com/frostyeti/cast/internal/logx"
-	"github.com/frostyeti/cast/internal/modules"
 	"github.com/frostyeti/cast/internal/paths"
 	"github.com/frostyeti/cast/internal/types"
 	"github.com/frostyeti/go/dotenv"
@@ -31,12 +30,19 @@ type Project struct {
 	File             string
 	Dir              string
 	CastDir          string
+	LockMode         LockMode
 	imported         map[string]types.Module
 	importedOrder    []string
 	init             bool
 	cleanupEnv       bool
 	cleanupPath      bool
 	cleanupOutputs   bool
+	envTracker       *envTracker
+	contextChain     []types.Context
+	overridden       map[string]string
+	moduleVersions   map[string]moduleRef
+	lock             *Lockfile
+	lockRecorded     *Lockfile
 	Workspace        map[string]*ProjectInfo
 	WorkspaceEntries []*ProjectInfo
 }
@@ -267,6 +273,9 @@ func resolveWorkspaceProjectPath(baseDir, value string) (string, error) {
 }
 
 func (p *Project) LoadFromYaml(file string) error {
+	if err := checkFileRequires(file); err != nil {
+		return err
+	}
 	err := p.Schema.ReadFromYaml(file)
 	if err != nil {
 		return err
@@ -294,7 +303,14 @@ func (p *Project) Init() error {
 		}
 	}
 
+	contextChain, err := p.Schema.Contexts.Chain(p.ContextName)
+	if err != nil {
+		return err
+	}
+	p.contextChain = contextChain
+
 	p.imported = make(map[string]types.Module)
+	p.overridden = make(map[string]string)
 
 	targetDir := p.Dir
 	if !filepath.IsAbs(targetDir) {
@@ -354,7 +370,7 @@ func (p *Project) Init() error {
 		}
 	}
 
-	err := resolveModules(p, p.Schema.Imports)
+	err = resolveModules(p, p.Schema.Imports, p.File)
 	if err != nil {
 		return err
 	}
@@ -370,12 +386,21 @@ func (p *Project) Init() error {
 		"meta": p.Schema.Meta.ToMap(),
 	})
 
+	vars, err := resolveVars(p.contextVars(), p.Scope.ToMap(), nil)
+	if err != nil {
+		return errors.Newf("failed to evaluate project vars: %w", err)
+	}
+	p.Scope.Set("vars", vars)
+
 	err = loadModules(p)
 	if err != nil {
 		return err
 	}
 
-	err = loadInventories(p)
+	// the context inventories are only loaded for this run; the parsed
+	// schema is left as written.
+	inventories := append(slices.Clone(p.Schema.Inventories), p.contextInventories()...)
+	err = loadInventories(p, inventories)
 	if err != nil {
 		return err
 	}
@@ -546,107 +571,26 @@ func (p *Project) Init() error {
 			task.Id = id.Convert(task.Name)
 		}
 
-		p.Tasks.Set(&task)
-	}
-
-	for _, task := range p.Tasks.Values() {
-		extends := task.Extends
-		if extends != nil {
-			baseTask, ok := p.Tasks.Get(*extends)
-			if !ok {
-				return errors.Newf("task %s extends undefined task %s", task.Name, *extends)
-			}
-
-			if task.Desc == nil && baseTask.Desc != nil {
-				task.Desc = baseTask.Desc
-			}
-
-			if (task.Uses == nil || *task.Uses == "") && baseTask.Uses != nil {
-				task.Uses = baseTask.Uses
-			}
-
-			if (task.Cwd == nil || *task.Cwd == "") && baseTask.Cwd != nil {
-				task.Cwd = baseTask.Cwd
-			}
-
-			if (task.Run == nil || *task.Run == "") && baseTask.Run != nil {
-				task.Run = baseTask.Run
-			}
-
-			if (task.Help == nil || *task.Help == "") && baseTask.Help != nil {
-				task.Help = baseTask.Help
-			}
-
-			if (task.Timeout == nil || *task.Timeout == "") && baseTask.Timeout != nil {
-				task.Timeout = baseTask.Timeout
-			}
-
-			if (task.Template == nil || *task.Template == "") && baseTask.Template != nil {
-				task.Template = baseTask.Template
-			}
-
-			if len(task.Needs) == 0 && len(baseTask.Needs) > 0 {
-				task.Needs = baseTask.Needs
-			}
-
-			if task.Hooks == nil && baseTask.Hooks != nil {
-				task.Hooks = baseTask.Hooks
-			}
-
-			if len(task.Hosts) == 0 && len(baseTask.Hosts) > 0 {
-				task.Hosts = baseTask.Hosts
-			} else {
-				hosts := append([]string{}, baseTask.Hosts...)
-				for _, h := range task.Hosts {
-					if !slices.Contains(hosts, h) {
-						hosts = append(hosts, h)
-					}
-				}
-				task.Hosts = hosts
-			}
-
-			if (task.Force == nil || *task.Force == "") && baseTask.Force != nil {
-				task.Force = baseTask.Force
-			}
-
-			if len(task.DotEnv) == 0 && len(baseTask.DotEnv) > 0 {
-				task.DotEnv = baseTask.DotEnv
-			} else if len(task.DotEnv) > 0 && len(baseTask.DotEnv) > 0 {
-				dotenv := append([]string{}, baseTask.DotEnv...)
-
-				for _, de := range task.DotEnv {
-					replaceIndex := -1
-					for i, existingDe := range dotenv {
-						if de == existingDe {
-							replaceIndex = i
-						}
-					}
+		if base, ok := p.overridden[task.Name]; ok {
+			resolveSuper(&task, base)
+		} else if usesSuper(task) {
+			return errors.Newf("task %s references %s but does not override an imported task", task.Name, "super")
+		}
 
-					if replaceIndex > -1 {
-						dotenv[replaceIndex] = de
-					} else {
-						dotenv = append(dotenv, de)
-					}
-				}
+		// defaults are applied before extends resolution, so a defaulted
+		// field counts as set by the task and a base task's defaulted
+		// fields reach the tasks extending it through the normal merge.
+		p.applyTaskDefaults(&task)
 
-				task.DotEnv = dotenv
-			}
-
-			e := baseTask.Env.Clone()
-			for k, v := range task.Env.Iter() {
-				e.Set(k, v)
-			}
-			task.Env = e
+		p.Tasks.Set(&task)
+	}
 
-			meta := baseTask.With.Clone()
-			for _, k := range task.With.Keys() {
-				v, _ := task.With.Get(k)
-				meta.Set(k, v)
-			}
-			task.With = meta
+	if err := p.resolveTaskExtends(); err != nil {
+		return err
+	}
 
-			p.Tasks.Set(&task)
-		}
+	if err := p.Tasks.Validate(); err != nil {
+		return err
 	}
 
 	defaultTaskUses := resolveDefaultTaskUses(p.Schema.Config)
@@ -662,81 +606,8 @@ func (p *Project) Init() error {
 		p.Tasks.Set(&task)
 	}
 
-	if p.Schema.Jobs != nil {
-		for _, job := range p.Schema.Jobs.Values() {
-			extends := job.Extends
-			if extends != nil {
-				baseJob, ok := p.Schema.Jobs.Get(*extends)
-				if !ok {
-					return errors.Newf("job %s extends undefined job %s", job.Name, *extends)
-				}
-
-				if job.Desc == "" && baseJob.Desc != "" {
-					job.Desc = baseJob.Desc
-				}
-
-				if job.If == nil && baseJob.If != nil {
-					job.If = baseJob.If
-				}
-
-				if (job.Timeout == nil || *job.Timeout == "") && baseJob.Timeout != nil {
-					job.Timeout = baseJob.Timeout
-				}
-
-				if (job.Cron == nil || *job.Cron == "") && baseJob.Cron != nil {
-					job.Cron = baseJob.Cron
-				}
-
-				if (job.Cwd == nil || *job.Cwd == "") && baseJob.Cwd != nil {
-					job.Cwd = baseJob.Cwd
-				}
-
-				if job.Needs == nil && baseJob.Needs != nil {
-					job.Needs = baseJob.Needs
-				}
-
-				if len(job.Steps) == 0 && len(baseJob.Steps) > 0 {
-					job.Steps = baseJob.Steps
-				}
-
-				if job.DotEnv == nil && baseJob.DotEnv != nil {
-					job.DotEnv = baseJob.DotEnv
-				} else if job.DotEnv != nil && baseJob.DotEnv != nil {
-					dotenv := append(types.DotEnvs{}, (*baseJob.DotEnv)...)
-
-					for _, de := range *job.DotEnv {
-						replaceIndex := -1
-						for i, existingDe := range dotenv {
-							if de.Path == existingDe.Path {
-								replaceIndex = i
-							}
-						}
-
-						if replaceIndex > -1 {
-							dotenv[replaceIndex] = de
-						} else {
-							dotenv = append(dotenv, de)
-						}
-					}
-
-					job.DotEnv = &dotenv
-				}
-
-				if baseJob.Env != nil {
-					if job.Env == nil {
-						job.Env = baseJob.Env.Clone()
-					} else {
-						e := baseJob.Env.Clone()
-						for k, v := range job.Env.Iter() {
-							e.Set(k, v)
-						}
-						job.Env = e
-					}
-				}
-
-				p.Schema.Jobs.Set(&job)
-			}
-		}
+	if err := p.resolveJobExtends(); err != nil {
+		return err
 	}
 
 	return nil
@@ -924,13 +795,23 @@ func loadModules(p *Project) error {
 			ns := mod.Namespace
 
 			if mod.Tasks != nil && mod.Tasks.Len() > 0 {
-				mod.TaskNames = mod.Tasks.Keys()
+				names, err := moduleTaskNames(mod)
+				if err != nil {
+					return err
+				}
+				mod.TaskNames = names
+
 				for _, taskName := range mod.TaskNames {
 					task, ok := mod.Tasks.Get(taskName)
 					if !ok {
 						return errors.Newf("module %s does not have task %s", path, taskName)
 					}
 
+					if renamed, ok := mod.Rename[task.Name]; ok {
+						task.Name = renamed
+						task.Id = id.Convert(renamed)
+					}
+
 					name := task.Name
 					if ns != "" {
 						name = ns + ":" + name
@@ -938,12 +819,45 @@ func loadModules(p *Project) error {
 						if task.Id != "" {
 							task.Id = ns + "-" + task.Id
 						}
+						aliases := make([]string, 0, len(task.Aliases))
+						for _, alias := range task.Aliases {
+							aliases = append(aliases, ns+":"+alias)
+						}
+						task.Aliases = aliases
+					}
+
+					// bases defined in the same module keep resolving once the
+					// module tasks are renamed and namespaced.
+					extends := slices.Clone(task.Extends)
+					for i, base := range extends {
+						if baseTask, ok := mod.Tasks.Get(base); ok {
+							extends[i] = moduleTaskName(mod, baseTask.Name)
+						}
 					}
+					task.Extends = extends
 
 					if task.Id == "" {
 						task.Id = id.Convert(name)
 					}
 
+					// a project task with the same name replaces the imported
+					// one. With `override: true` the imported task stays
+					// available as <name>:base, and `override: false` forbids
+					// redefining it.
+					if projectTask, ok := p.Schema.Tasks.Get(name); ok {
+						if mod.Override == nil {
+							continue
+						}
+						if !*mod.Override {
+							return errors.Newf("task %s from module %s is redefined at %s, but the import sets `override: false`", name, mod.From, projectTask.Location())
+						}
+
+						task.Name = name + ":base"
+						task.Id = id.Convert(task.Name)
+						task.Aliases = nil
+						p.overridden[name] = task.Name
+					}
+
 					if task.Env == nil {
 						task.Env = types.NewEnv()
 					}
@@ -981,7 +895,7 @@ func resolveDefaultTaskUses(cfg *types.ProjectConfig) string {
 	return "shell"
 }
 
-func resolveModules(p *Project, imports *types.Imports) error {
+func resolveModules(p *Project, imports *types.Imports, importer string) error {
 
 	if imports == nil || len(*imports) == 0 {
 		return nil
@@ -1005,34 +919,16 @@ func resolveModules(p *Project, imports *types.Imports) error {
 
 	for _, importMod := range *imports {
 		path := importMod.From
-		if strings.HasPrefix(path, "github.com/") || strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") || strings.HasPrefix(path, "git@") {
-			fetchedPath, err := modules.FetchModule(p.Dir, path)
-			if err != nil {
-				return errors.Newf("failed to fetch remote module %s: %v", path, err)
+		if isRemoteModuleRef(path) {
+			if err := p.checkModuleVersion(path, importer); err != nil {
+				return err
 			}
-			path = fetchedPath
 
-			// Some modules might have cast.module.yaml instead of just being a directory
-			// Check if it's a directory
-			stat, err := os.Stat(path)
-			if err == nil && stat.IsDir() {
-				// Look for cast.module.yaml or castfile.yaml
-				if _, err := os.Stat(filepath.Join(path, "cast.module.yaml")); err == nil {
-					path = filepath.Join(path, "cast.module.yaml")
-				} else if _, err := os.Stat(filepath.Join(path, "cast.module.yml")); err == nil {
-					path = filepath.Join(path, "cast.module.yml")
-				} else if _, err := os.Stat(filepath.Join(path, "mod.yaml")); err == nil {
-					path = filepath.Join(path, "mod.yaml")
-				} else if _, err := os.Stat(filepath.Join(path, "mod.yml")); err == nil {
-					path = filepath.Join(path, "mod.yml")
-				} else if _, err := os.Stat(filepath.Join(path, "castfile.yaml")); err == nil {
-					path = filepath.Join(path, "castfile.yaml")
-				} else if _, err := os.Stat(filepath.Join(path, "castfile.yml")); err == nil {
-					path = filepath.Join(path, "castfile.yml")
-				} else if _, err := os.Stat(filepath.Join(path, "castfile")); err == nil {
-					path = filepath.Join(path, "castfile")
-				}
+			fetchedPath, err := p.fetchRemoteModule(path)
+			if err != nil {
+				return err
 			}
+			path = fetchedPath
 		} else if !filepath.IsAbs(path) {
 			if path[0] == '.' {
 				absPath, err := filepath.Abs(filepath.Join(p.Dir, path))
@@ -1074,24 +970,7 @@ func resolveModules(p *Project, imports *types.Imports) error {
 		// For all resolved paths, check if it's a directory and resolve the module file
 		stat, err := os.Stat(path)
 		if err == nil && stat.IsDir() {
-			// Look for cast.module.yaml, cast.mod, or castfile.yaml
-			if _, err := os.Stat(filepath.Join(path, "cast.mod")); err == nil {
-				path = filepath.Join(path, "cast.mod")
-			} else if _, err := os.Stat(filepath.Join(path, "cast.module.yaml")); err == nil {
-				path = filepath.Join(path, "cast.module.yaml")
-			} else if _, err := os.Stat(filepath.Join(path, "cast.module.yml")); err == nil {
-				path = filepath.Join(path, "cast.module.yml")
-			} else if _, err := os.Stat(filepath.Join(path, "mod.yaml")); err == nil {
-				path = filepath.Join(path, "mod.yaml")
-			} else if _, err := os.Stat(filepath.Join(path, "mod.yml")); err == nil {
-				path = filepath.Join(path, "mod.yml")
-			} else if _, err := os.Stat(filepath.Join(path, "castfile.yaml")); err == nil {
-				path = filepath.Join(path, "castfile.yaml")
-			} else if _, err := os.Stat(filepath.Join(path, "castfile.yml")); err == nil {
-				path = filepath.Join(path, "castfile.yml")
-			} else if _, err := os.Stat(filepath.Join(path, "castfile")); err == nil {
-				path = filepath.Join(path, "castfile")
-			}
+			path = moduleFileInDir(path)
 		}
 
 		// Now also handle when path is passed without extension (e.g. "docker" -> "docker.yaml")
@@ -1112,6 +991,9 @@ func resolveModules(p *Project, imports *types.Imports) error {
 			continue
 		}
 
+		if err := checkFileRequires(path); err != nil {
+			return err
+		}
 		mod := types.Module{}
 		err = mod.ReadFromYaml(path)
 		if err != nil {
@@ -1119,7 +1001,7 @@ func resolveModules(p *Project, imports *types.Imports) error {
 		}
 
 		if mod.Imports != nil && len(*mod.Imports) > 0 {
-			err = resolveModules(p, mod.Imports)
+			err = resolveModules(p, mod.Imports, path)
 			if err != nil {
 				return err
 			}
@@ -1127,6 +1009,10 @@ func resolveModules(p *Project, imports *types.Imports) error {
 
 		mod.Namespace = importMod.Namespace
 		mod.TaskNames = importMod.Tasks
+		mod.Exclude = importMod.Exclude
+		mod.Rename = importMod.Rename
+		mod.Override = importMod.Override
+		mod.From = importMod.From
 		p.imported[path] = mod
 		p.importedOrder = append(p.importedOrder, path)
 	}
@@ -1137,12 +1023,20 @@ func resolveModules(p *Project, imports *types.Imports) error {
 func setupEnv(p *Project) error {
 
 	e := types.NewEnv()
-	e.Merge(globalEnv)
+	t := newEnvTracker()
 
-	e.Set("CAST_FILE", p.File)
-	e.Set("CAST_DIR", p.Dir)
-	e.Set("CAST_PARENT_DIR", p.Dir)
-	e.Set("CAST_PARENT_FILE", p.File)
+	_ = t.track(e, "process", "", nil, func() error {
+		e.Merge(globalEnv)
+		return nil
+	})
+
+	_ = t.track(e, "cast", p.File, nil, func() error {
+		e.Set("CAST_FILE", p.File)
+		e.Set("CAST_DIR", p.Dir)
+		e.Set("CAST_PARENT_DIR", p.Dir)
+		e.Set("CAST_PARENT_FILE", p.File)
+		return nil
+	})
 
 	sub := true
 	if p.Schema.Config != nil && p.Schema.Config.Substitution != nil {
@@ -1154,14 +1048,19 @@ func setupEnv(p *Project) error {
 		if mod.Paths == nil {
 			continue
 		}
-		err := loadPaths(*mod.Paths, e, mod.Dir)
+		err := t.track(e, "paths", mod.File, nil, func() error {
+			return loadPaths(*mod.Paths, e, mod.Dir)
+		})
 		if err != nil {
 			return err
 		}
 	}
 
 	if p.Schema.Paths != nil {
-		if err := loadPaths(*p.Schema.Paths, e, p.Dir); err != nil {
+		err := t.track(e, "paths", p.File, nil, func() error {
+			return loadPaths(*p.Schema.Paths, e, p.Dir)
+		})
+		if err != nil {
 			return err
 		}
 	}
@@ -1178,16 +1077,22 @@ func setupEnv(p *Project) error {
 				return err
 			}
 
-			paths := strings.Split(string(content), "\n")
-			for _, p := range paths {
-				p = strings.TrimSpace(p)
-				if p == "" {
-					continue
-				}
-				err := e.PrependPath(p)
-				if err != nil {
-					return err
+			err = t.track(e, "cast-path", f, nil, func() error {
+				paths := strings.Split(string(content), "\n")
+				for _, p := range paths {
+					p = strings.TrimSpace(p)
+					if p == "" {
+						continue
+					}
+					err := e.PrependPath(p)
+					if err != nil {
+						return err
+					}
 				}
+				return nil
+			})
+			if err != nil {
+				return err
 			}
 		}
 
@@ -1203,14 +1108,20 @@ func setupEnv(p *Project) error {
 		if err := f.Close(); err != nil {
 			return err
 		}
-		e.Set("CAST_PATH", f.Name())
+		_ = t.track(e, "cast", "", nil, func() error {
+			e.Set("CAST_PATH", f.Name())
+			return nil
+		})
 		p.cleanupPath = true
 	}
 
-	if err := e.PrependPath("./bin"); err != nil {
-		return err
-	}
-	if err := e.PrependPath("./node_modules/.bin"); err != nil {
+	err := t.track(e, "cast", "", nil, func() error {
+		if err := e.PrependPath("./bin"); err != nil {
+			return err
+		}
+		return e.PrependPath("./node_modules/.bin")
+	})
+	if err != nil {
 		return err
 	}
 
@@ -1220,14 +1131,14 @@ func setupEnv(p *Project) error {
 			continue
 		}
 
-		_, err := loadDotEnvFiles(*mod.DotEnv, e, p.ContextName, sub, mod.Dir)
+		err := trackDotEnvFiles(t, *mod.DotEnv, e, p.ContextName, sub, mod.Dir)
 		if err != nil {
 			return err
 		}
 	}
 
 	if p.Schema.DotEnv != nil {
-		_, err := loadDotEnvFiles(*p.Schema.DotEnv, e, p.ContextName, sub, p.Dir)
+		err := trackDotEnvFiles(t, *p.Schema.DotEnv, e, p.ContextName, sub, p.Dir)
 		if err != nil {
 			return err
 		}
@@ -1235,19 +1146,27 @@ func setupEnv(p *Project) error {
 
 	for _, path := range p.importedOrder {
 		mod := p.imported[path]
-		err := loadEnv(mod.Env, e, sub)
+		err := t.track(e, "env", mod.File, nil, func() error {
+			return loadEnv(mod.Env, e, sub)
+		})
 		if err != nil {
 			return err
 		}
 	}
 
 	if p.Schema.Env != nil {
-		err := loadEnv(p.Schema.Env, e, sub)
+		err := t.track(e, "env", p.File, nil, func() error {
+			return loadEnv(p.Schema.Env, e, sub)
+		})
 		if err != nil {
 			return err
 		}
 	}
 
+	if err := loadContextEnv(p, t, e, sub); err != nil {
+		return err
+	}
+
 	f = e.Get("CAST_ENV")
 	if f != "" {
 		envFile := f
@@ -1276,24 +1195,30 @@ func setupEnv(p *Project) error {
 				CommandSubstitution: sub,
 			}
 
-			for _, node := range dotenvDoc.ToArray() {
-				if node.Type != dotenv.VARIABLE {
-					continue
-				}
+			err = t.track(e, "cast-env", envFile, nil, func() error {
+				for _, node := range dotenvDoc.ToArray() {
+					if node.Type != dotenv.VARIABLE {
+						continue
+					}
 
-				key := node.Key
-				if key == nil || len(*key) == 0 {
-					continue
-				}
+					key := node.Key
+					if key == nil || len(*key) == 0 {
+						continue
+					}
 
-				value := node.Value
+					value := node.Value
 
-				v, err := env.ExpandWithOptions(value, opts)
-				if err != nil {
-					return err
-				}
+					v, err := env.ExpandWithOptions(value, opts)
+					if err != nil {
+						return err
+					}
 
-				e.Set(*key, v)
+					e.Set(*key, v)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
 			}
 		}
 
@@ -1309,7 +1234,10 @@ func setupEnv(p *Project) error {
 		if err := f.Close(); err != nil {
 			return err
 		}
-		e.Set("CAST_ENV", f.Name())
+		_ = t.track(e, "cast", "", nil, func() error {
+			e.Set("CAST_ENV", f.Name())
+			return nil
+		})
 		p.cleanupEnv = true
 	}
 
@@ -1326,22 +1254,44 @@ func setupEnv(p *Project) error {
 		if err := f.Close(); err != nil {
 			return err
 		}
-		e.Set("CAST_OUTPUTS", f.Name())
+		_ = t.track(e, "cast", "", nil, func() error {
+			e.Set("CAST_OUTPUTS", f.Name())
+			return nil
+		})
 		p.cleanupOutputs = true
 	}
 
 	p.Env = e
 
 	// Set CAST_CONTEXT environment variable so tasks know which context is active
-	if p.ContextName != "" {
-		p.Env.Set("CAST_CONTEXT", p.ContextName)
-	} else {
-		p.Env.Set("CAST_CONTEXT", "default")
-	}
+	_ = t.track(e, "context", "", nil, func() error {
+		if p.ContextName != "" {
+			p.Env.Set("CAST_CONTEXT", p.ContextName)
+		} else {
+			p.Env.Set("CAST_CONTEXT", "default")
+		}
+		return nil
+	})
+
+	p.envTracker = t
 
 	return nil
 }
 
+// trackDotEnvFiles loads a dotenv section and attributes each key to the last
+// file in the section that defines it.
+func trackDotEnvFiles(t *envTracker, section types.DotEnvs, e *types.Env, contextName string, substitution bool, basePath string) error {
+	files, err := resolveDotEnvFiles(section, contextName, basePath)
+	if err != nil {
+		return err
+	}
+
+	return t.track(e, "dotenv", "", dotenvKeyFiles(files), func() error {
+		_, err := loadDotEnvFiles(section, e, contextName, substitution, basePath)
+		return err
+	})
+}
+
 func loadPaths(src types.Paths, dest *types.Env, basePath string) error {
 
 	for _, p := range src {
@@ -1393,31 +1343,18 @@ func loadEnv(src *types.Env, dest *types.Env, substitution bool) error {
 		}
 
 		dest.Set(key, v)
+		if src.IsSecret(key) {
+			dest.MarkSecret(key)
+		}
 	}
 
 	return nil
 }
 
 func loadDotEnvFiles(dotenvSection types.DotEnvs, e *types.Env, contextName string, subsitution bool, basePath string) (*types.Env, error) {
-	dotenvFiles := []string{}
-	for _, section := range dotenvSection {
-		if section.OS != "" && section.OS != "*" {
-			if section.OS != runtime.GOOS {
-				continue
-			}
-		}
-
-		if len(section.Contexts) == 0 && (contextName == "" || contextName == "*" || contextName == "default") {
-			dotenvFiles = append(dotenvFiles, section.Path)
-			continue
-		}
-
-		for _, ctx := range section.Contexts {
-			if ctx == contextName || ctx == "*" {
-				dotenvFiles = append(dotenvFiles, section.Path)
-				break
-			}
-		}
+	dotenvFiles, err := resolveDotEnvFiles(dotenvSection, contextName, basePath)
+	if err != nil {
+		return nil, err
 	}
 
 	opts := &env.ExpandOptions{
@@ -1433,24 +1370,6 @@ func loadDotEnvFiles(dotenvSection types.DotEnvs, e *types.Env, contextName stri
 	globalDoc := dotenv.NewDoc()
 
 	for _, file := range dotenvFiles {
-		absFile, err := paths.ResolvePath(basePath, file)
-		if err != nil {
-			return nil, err
-		}
-
-		file = absFile
-		if strings.HasSuffix(file, "?") {
-			if _, err := os.Stat(file[:len(file)-1]); err != nil {
-				continue
-			}
-
-			file = file[:len(file)-1]
-		} else {
-			if _, err := os.Stat(file); err != nil {
-				return nil, err
-			}
-		}
-
 		bytes, err := os.ReadFile(file)
 		if err != nil {
 			return nil, err
@@ -1486,3 +1405,53 @@ func loadDotEnvFiles(dotenvSection types.DotEnvs, e *types.Env, contextName stri
 
 	return e, nil
 }
+
+// resolveDotEnvFiles returns the absolute dotenv files that apply to the
+// current OS and context, skipping optional files that do not exist.
+func resolveDotEnvFiles(dotenvSection types.DotEnvs, contextName string, basePath string) ([]string, error) {
+	dotenvFiles := []string{}
+	for _, section := range dotenvSection {
+		if section.OS != "" && section.OS != "*" {
+			if section.OS != runtime.GOOS {
+				continue
+			}
+		}
+
+		if len(section.Contexts) == 0 && (contextName == "" || contextName == "*" || contextName == "default") {
+			dotenvFiles = append(dotenvFiles, section.Path)
+			continue
+		}
+
+		for _, ctx := range section.Contexts {
+			if ctx == contextName || ctx == "*" {
+				dotenvFiles = append(dotenvFiles, section.Path)
+				break
+			}
+		}
+	}
+
+	resolved := []string{}
+	for _, file := range dotenvFiles {
+		absFile, err := paths.ResolvePath(basePath, file)
+		if err != nil {
+			return nil, err
+		}
+
+		file = absFile
+		if strings.HasSuffix(file, "?") {
+			if _, err := os.Stat(file[:len(file)-1]); err != nil {
+				continue
+			}
+
+			file = file[:len(file)-1]
+		} else {
+			if _, err := os.Stat(file); err != nil {
+				return nil, err
+			}
+		}
+
+		resolved = append(resolved, file)
+	}
+
+	return resolved, nil
+}
@@ -115,3 +115,24 @@ imports:
 		t.Fatalf("expected CAST_MODULE_FILE=%s, got %s", moduleFile, got)
 	}
 }
+
+func TestLooksSecret(t *testing.T) {
+	cases := map[string]bool{
+		"GITHUB_TOKEN":  true,
+		"DB_PASSWORD":   true,
+		"AUTH":          true,
+		"BASIC_AUTH":    true,
+		"AUTH_HEADER":   true,
+		"AUTHORIZATION": true,
+		"AUTHOR":        false,
+		"OAUTH_URL":     false,
+		"AUTHORITY":     false,
+		"HOME":          false,
+	}
+
+	for name, want := range cases {
+		if got := projects.LooksSecret(name); got != want {
+			t.Errorf("LooksSecret(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package projects
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/frostyeti/cast/internal/errors"
+)
+
+// CastRegistryTokenEnv holds a bearer token sent with uploads to HTTP
+// registries.
+const CastRegistryTokenEnv = "CAST_REGISTRY_TOKEN"
+
+// PublishOptions controls PublishSpell.
+type PublishOptions struct {
+	// Force replaces a published version whose tarball differs.
+	Force bool
+}
+
+// PublishResult describes a published spell version. Unchanged is set when
+// the registry already listed the same tarball.
+type PublishResult struct {
+	Name      string
+	Release   RegistryRelease
+	Unchanged bool
+}
+
+// PublishSpell copies a tarball written by PackCastTask, and its detached
+// signature when there is one, into registry and adds it to the registry
+// index. A registry is either a directory, or a
+// file:// URL, or an HTTP location that accepts PUT requests for the
+// tarball and the updated index.json.
+func PublishSpell(registry, tarball string, opts PublishOptions) (*PublishResult, error) {
+	if registry == "" {
+		return nil, errors.Newf("no spell registry is configured; pass --registry or set `registry:` in the castfile or %s", CastRegistryEnv)
+	}
+
+	manifest, err := ReadPackManifest(tarball)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(tarball)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+
+	release := RegistryRelease{
+		Version: manifest.Version,
+		URL:     manifest.Name + "/" + filepath.Base(tarball),
+		Sha256:  hex.EncodeToString(sum[:]),
+	}
+
+	// a detached signature written by `cast task sign` or `pack --key` is
+	// published next to the tarball.
+	sigData, err := os.ReadFile(tarball + signatureSuffix)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		sigData = nil
+	} else {
+		release.Signature = release.URL + signatureSuffix
+	}
+
+	result := &PublishResult{Name: manifest.Name, Release: release}
+
+	store, err := newRegistryStore(registry)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := store.readIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	spellIdx := -1
+	for i, spell := range index.Spells {
+		if spell.Name == manifest.Name {
+			spellIdx = i
+			break
+		}
+	}
+	if spellIdx < 0 {
+		index.Spells = append(index.Spells, RegistrySpell{Name: manifest.Name})
+		spellIdx = len(index.Spells) - 1
+	}
+
+	spell := &index.Spells[spellIdx]
+	if manifest.Description != "" {
+		spell.Description = manifest.Description
+	}
+
+	replaced := false
+	for i, existing := range spell.Versions {
+		if existing.Version != release.Version {
+			continue
+		}
+		if strings.EqualFold(existing.Sha256, release.Sha256) {
+			if sigData == nil {
+				result.Release = existing
+				result.Unchanged = true
+				return result, nil
+			}
+		} else if !opts.Force {
+			return nil, errors.Newf("%s %s is already published with sha256 %s; bump the version or pass --force to replace it",
+				manifest.Name, release.Version, existing.Sha256)
+		}
+		spell.Versions[i] = release
+		replaced = true
+	}
+	if !replaced {
+		spell.Versions = append(spell.Versions, release)
+	}
+
+	sortRegistryIndex(index)
+
+	// upload the tarball first so the index never lists a missing file.
+	if err := store.write(release.URL, data); err != nil {
+		return nil, err
+	}
+	if sigData != nil {
+		if err := store.write(release.Signature, sigData); err != nil {
+			return nil, err
+		}
+	}
+
+	indexData, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := store.write(registryIndexFile, append(indexData, '\n')); err != nil {
+		return nil, err
+	}
+
+	delete(registryIndexes, store.indexURL)
+	return result, nil
+}
+
+func sortRegistryIndex(index *RegistryIndex) {
+	sort.Slice(index.Spells, func(a, b int) bool {
+		return index.Spells[a].Name < index.Spells[b].Name
+	})
+
+	for i := range index.Spells {
+		versions := index.Spells[i].Versions
+		sort.SliceStable(versions, func(a, b int) bool {
+			pa, okA := parseConstraintVersion(versions[a].Version)
+			pb, okB := parseConstraintVersion(versions[b].Version)
+			if !okA || !okB {
+				return versions[a].Version < versions[b].Version
+			}
+			return compareVersionParts(pa, pb) < 0
+		})
+	}
+}
+
+// registryStore reads and writes files relative to the directory that
+// serves a registry index.
+type registryStore struct {
+	indexURL string
+	baseDir  string
+	baseURL  *url.URL
+}
+
+func newRegistryStore(registry string) (*registryStore, error) {
+	indexURL := registryIndexURL(registry)
+	store := &registryStore{indexURL: indexURL}
+
+	switch {
+	case strings.HasPrefix(indexURL, "http://") || strings.HasPrefix(indexURL, "https://"):
+		u, err := url.Parse(indexURL)
+		if err != nil {
+			return nil, err
+		}
+		store.baseURL = u
+	case strings.HasPrefix(indexURL, "file://"):
+		u, err := url.Parse(indexURL)
+		if err != nil {
+			return nil, err
+		}
+		store.baseDir = filepath.Dir(filepath.FromSlash(u.Path))
+	default:
+		store.baseDir = filepath.Dir(indexURL)
+	}
+
+	if store.baseURL == nil && filepath.Base(indexURL) != registryIndexFile {
+		return nil, errors.Newf("registry index %s must be named %s to publish to it", indexURL, registryIndexFile)
+	}
+
+	return store, nil
+}
+
+func (s *registryStore) readIndex() (*RegistryIndex, error) {
+	var data []byte
+	if s.baseURL != nil {
+		resp, err := s.do(http.MethodGet, s.indexURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = resp.Body.Close()
+		}()
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+			data, err = io.ReadAll(resp.Body)
+			if err != nil {
+				return nil, err
+			}
+		case http.StatusNotFound:
+			return &RegistryIndex{Version: registryIndexVersion}, nil
+		default:
+			return nil, errors.Newf("failed to read registry index %s: status %d", s.indexURL, resp.StatusCode)
+		}
+	} else {
+		content, err := os.ReadFile(filepath.Join(s.baseDir, registryIndexFile))
+		if err != nil {
+			if os.IsNotExist(err) {
+				return &RegistryIndex{Version: registryIndexVersion}, nil
+			}
+			return nil, err
+		}
+		data = content
+	}
+
+	index := &RegistryIndex{}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, errors.Newf("failed to parse registry index %s: %w", s.indexURL, err)
+	}
+	if index.Version > registryIndexVersion {
+		return nil, errors.Newf("registry index %s uses version %d but cast supports version %d; upgrade with `cast self upgrade`",
+			s.indexURL, index.Version, registryIndexVersion)
+	}
+	if index.Version == 0 {
+		index.Version = registryIndexVersion
+	}
+
+	return index, nil
+}
+
+// write stores data at rel, a slash separated path relative to the index.
+func (s *registryStore) write(rel string, data []byte) error {
+	if s.baseURL != nil {
+		target := s.baseURL.ResolveReference(&url.URL{Path: rel}).String()
+		resp, err := s.do(http.MethodPut, target, data)
+		if err != nil {
+			return err
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return errors.Newf("failed to upload %s: status %d", target, resp.StatusCode)
+		}
+		return nil
+	}
+
+	path := filepath.Join(s.baseDir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// write through a temp file so readers never see a partial index.
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".publish-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *registryStore) do(method, target string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	if token := strings.TrimSpace(os.Getenv(CastRegistryTokenEnv)); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return http.DefaultClient.Do(req)
+}
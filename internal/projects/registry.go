@@ -0,0 +1,390 @@
+package projects
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/frostyeti/cast/internal/archive"
+	"github.com/frostyeti/cast/internal/errors"
+	"github.com/frostyeti/cast/internal/integrity"
+	"github.com/frostyeti/cast/internal/offline"
+)
+
+// CastRegistryEnv sets the spell registry used when the castfile does not
+// declare one.
+const CastRegistryEnv = "CAST_REGISTRY"
+
+const (
+	registryPrefix        = "reg:"
+	registryIndexFile     = "index.json"
+	registryIndexVersion  = 1
+	registryCacheDirName  = "registry"
+	registrySpellsDirName = "spells"
+)
+
+// RegistryIndex is the JSON document a spell registry serves. Any static file
+// server can host one next to the tarballs it lists.
+type RegistryIndex struct {
+	Version int             `json:"version"`
+	Spells  []RegistrySpell `json:"spells"`
+
+	url string
+}
+
+// RegistrySpell is a named spell and every version published for it.
+type RegistrySpell struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Versions    []RegistryRelease `json:"versions"`
+}
+
+// RegistryRelease is one published version of a spell. URL may be relative
+// to the index and Sha256 is the hex digest of the tarball. Signature, when
+// set, locates the detached cast.sig of the tarball content.
+type RegistryRelease struct {
+	Version   string `json:"version"`
+	URL       string `json:"url"`
+	Sha256    string `json:"sha256"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// registryIndexes caches loaded indexes by URL for the life of the process.
+var registryIndexes = map[string]*RegistryIndex{}
+
+// IsRegistryRef reports whether uses resolves through the spell registry.
+func IsRegistryRef(uses string) bool {
+	return strings.HasPrefix(uses, registryPrefix)
+}
+
+// ResolveRegistryURL returns the registry configured by the castfile of p,
+// falling back to CAST_REGISTRY. p may be nil.
+func ResolveRegistryURL(p *Project) string {
+	if p != nil && strings.TrimSpace(p.Schema.Registry) != "" {
+		return strings.TrimSpace(p.Schema.Registry)
+	}
+
+	return strings.TrimSpace(os.Getenv(CastRegistryEnv))
+}
+
+// registryIndexURL returns the index location for a registry, which is either
+// the index file itself or the directory serving index.json.
+func registryIndexURL(registry string) string {
+	if strings.HasSuffix(registry, ".json") {
+		return registry
+	}
+
+	return strings.TrimSuffix(registry, "/") + "/" + registryIndexFile
+}
+
+func registryCacheDir(projectDir, indexURL string) string {
+	hash := sha256.Sum256([]byte(indexURL))
+	return filepath.Join(stableRemoteTasksDir(projectDir), registryCacheDirName, hex.EncodeToString(hash[:])[:12])
+}
+
+// LoadRegistryIndex downloads the index of registry. A copy is kept in the
+// stable task cache so searches and version lookups keep working offline.
+func LoadRegistryIndex(projectDir, registry string) (*RegistryIndex, error) {
+	if registry == "" {
+		return nil, errors.Newf("no spell registry is configured; set `registry:` in the castfile or %s", CastRegistryEnv)
+	}
+
+	indexURL := registryIndexURL(registry)
+	if index, ok := registryIndexes[indexURL]; ok {
+		return index, nil
+	}
+
+	cachedIndex := filepath.Join(registryCacheDir(projectDir, indexURL), registryIndexFile)
+
+	var data []byte
+	if offline.Enabled() {
+		cached, err := os.ReadFile(cachedIndex)
+		if err != nil {
+			return nil, offline.NotCachedError("registry index " + indexURL)
+		}
+		data = cached
+	} else {
+		body, err := archive.Open(indexURL)
+		if err != nil {
+			return nil, errors.Newf("failed to load registry index %s: %w", indexURL, err)
+		}
+		data, err = io.ReadAll(body)
+		_ = body.Close()
+		if err != nil {
+			return nil, errors.Newf("failed to load registry index %s: %w", indexURL, err)
+		}
+	}
+
+	index := &RegistryIndex{}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, errors.Newf("failed to parse registry index %s: %w", indexURL, err)
+	}
+	if index.Version > registryIndexVersion {
+		return nil, errors.Newf("registry index %s uses version %d but cast supports version %d; upgrade with `cast self upgrade`",
+			indexURL, index.Version, registryIndexVersion)
+	}
+	index.url = indexURL
+
+	if !offline.Enabled() {
+		if err := os.MkdirAll(filepath.Dir(cachedIndex), 0o755); err == nil {
+			_ = os.WriteFile(cachedIndex, data, 0o644)
+		}
+	}
+
+	registryIndexes[indexURL] = index
+	return index, nil
+}
+
+// Find returns the spell called name.
+func (i *RegistryIndex) Find(name string) (RegistrySpell, bool) {
+	for _, spell := range i.Spells {
+		if spell.Name == name {
+			return spell, true
+		}
+	}
+
+	return RegistrySpell{}, false
+}
+
+// Search returns the spells whose name or description contains query,
+// ignoring case, sorted by name. An empty query lists every spell.
+func (i *RegistryIndex) Search(query string) []RegistrySpell {
+	query = strings.ToLower(strings.TrimSpace(query))
+	matches := []RegistrySpell{}
+	for _, spell := range i.Spells {
+		if query == "" || strings.Contains(strings.ToLower(spell.Name), query) || strings.Contains(strings.ToLower(spell.Description), query) {
+			matches = append(matches, spell)
+		}
+	}
+
+	sort.Slice(matches, func(a, b int) bool {
+		return matches[a].Name < matches[b].Name
+	})
+
+	return matches
+}
+
+// ReleaseURL resolves the tarball URL of release against the index location.
+func (i *RegistryIndex) ReleaseURL(release RegistryRelease) string {
+	if strings.Contains(release.URL, "://") || filepath.IsAbs(release.URL) {
+		return release.URL
+	}
+
+	if strings.HasPrefix(i.url, "http://") || strings.HasPrefix(i.url, "https://") || strings.HasPrefix(i.url, "file://") {
+		base, err := url.Parse(i.url)
+		if err != nil {
+			return release.URL
+		}
+		ref, err := url.Parse(release.URL)
+		if err != nil {
+			return release.URL
+		}
+		return base.ResolveReference(ref).String()
+	}
+
+	return filepath.Join(filepath.Dir(i.url), filepath.FromSlash(release.URL))
+}
+
+// Resolve returns the release matching version, which may be exact or a
+// semver family such as `1` or `1.2`.
+func (s RegistrySpell) Resolve(version string) (RegistryRelease, bool) {
+	names := make([]string, 0, len(s.Versions))
+	for _, release := range s.Versions {
+		if release.Version == version {
+			return release, true
+		}
+		names = append(names, release.Version)
+	}
+
+	best := chooseBestTag(version, "", names)
+	for _, release := range s.Versions {
+		if best != "" && release.Version == best {
+			return release, true
+		}
+	}
+
+	return RegistryRelease{}, false
+}
+
+// Latest returns the highest published version.
+func (s RegistrySpell) Latest() (RegistryRelease, bool) {
+	var latest RegistryRelease
+	var latestParts [3]int
+	found := false
+	for _, release := range s.Versions {
+		parts, ok := parseConstraintVersion(release.Version)
+		if !ok {
+			continue
+		}
+		if !found || compareVersionParts(parts, latestParts) > 0 {
+			latest, latestParts, found = release, parts, true
+		}
+	}
+
+	return latest, found
+}
+
+// parseRegistryRef splits `reg:<name>@<version>` into its name and version.
+func parseRegistryRef(uses string) (string, string, error) {
+	ref := strings.TrimPrefix(uses, registryPrefix)
+	idx := strings.LastIndex(ref, "@")
+	if idx <= 0 || idx == len(ref)-1 {
+		return "", "", errors.Newf("invalid registry task identifier, expected reg:<name>@<version>: %s", uses)
+	}
+
+	name, version := ref[:idx], ref[idx+1:]
+	if !isRegistrySpellName(name) {
+		return "", "", errors.Newf("invalid registry spell name %q in %s", name, uses)
+	}
+	if _, ok := parseConstraintVersion(version); !ok || hasPathSyntax(version) {
+		return "", "", errors.Newf("invalid registry version %q in %s", version, uses)
+	}
+
+	return name, version, nil
+}
+
+// isRegistrySpellName reports whether name is a clean relative path, such as
+// `lint` or `acme/lint`, that stays inside the registry cache.
+func isRegistrySpellName(name string) bool {
+	return name != "" && path.Clean(name) == name && !strings.HasPrefix(name, "/") && !strings.HasPrefix(name, "..") && !strings.Contains(name, `\`)
+}
+
+// isRegistryVersion reports whether version is a plain semver release, which
+// names its cache directory. Versions come from registry indexes and pack
+// manifests, so anything that could escape the cache is rejected.
+func isRegistryVersion(version string) bool {
+	_, _, _, ok := parseVersionParts(version)
+	return ok && !hasPathSyntax(version)
+}
+
+func hasPathSyntax(value string) bool {
+	return strings.ContainsAny(value, `/\`) || strings.Contains(value, "..")
+}
+
+// fetchRegistryTask resolves a `reg:` ref through the registry index and
+// extracts its tarball into the stable task cache. Published versions are
+// immutable, so a cached version is never fetched again. It returns the
+// extracted directory and the version it resolved to.
+func fetchRegistryTask(p *Project, uses string, stdout io.Writer) (string, string, error) {
+	name, version, err := parseRegistryRef(uses)
+	if err != nil {
+		return "", "", err
+	}
+
+	registry := ResolveRegistryURL(p)
+	if registry == "" {
+		return "", "", errors.Newf("remote task '%s' needs a spell registry; set `registry:` in the castfile or %s", uses, CastRegistryEnv)
+	}
+
+	spellDir := registrySpellDir(p.Dir, registry, name)
+	if offline.Enabled() {
+		resolved := cachedRegistryVersion(spellDir, version)
+		if resolved == "" {
+			return "", "", offline.NotCachedError(fmt.Sprintf("remote task '%s'", uses))
+		}
+		return filepath.Join(spellDir, resolved), resolved, nil
+	}
+
+	index, err := LoadRegistryIndex(p.Dir, registry)
+	if err != nil {
+		return "", "", err
+	}
+
+	spell, ok := index.Find(name)
+	if !ok {
+		return "", "", errors.Newf("spell %q is not in registry %s", name, index.url)
+	}
+
+	release, ok := spell.Resolve(version)
+	if !ok {
+		return "", "", errors.Newf("spell %q has no version matching %s in registry %s", name, version, index.url)
+	}
+	if !isRegistryVersion(release.Version) {
+		return "", "", errors.Newf("spell %s in registry %s has an invalid version %q", name, index.url, release.Version)
+	}
+	if release.Sha256 == "" {
+		return "", "", errors.Newf("spell %s@%s in registry %s has no sha256", name, release.Version, index.url)
+	}
+
+	taskDir := filepath.Join(spellDir, release.Version)
+	if _, err := os.Stat(taskDir); err == nil {
+		return taskDir, release.Version, nil
+	}
+
+	_, _ = fmt.Fprintf(stdout, "Fetching task: %s\n", uses)
+	err = archive.Stage(taskDir, func(tmpDir string) error {
+		if err := archive.Download(index.ReleaseURL(release), tmpDir, release.Sha256); err != nil {
+			return errors.Newf("failed to fetch %s@%s: %w", name, release.Version, err)
+		}
+
+		if release.Signature != "" {
+			sigURL := index.ReleaseURL(RegistryRelease{URL: release.Signature})
+			if err := downloadFile(sigURL, filepath.Join(tmpDir, integrity.SignatureFile)); err != nil {
+				return errors.Newf("failed to fetch the signature of %s@%s: %w", name, release.Version, err)
+			}
+		}
+
+		return verifySignature(p, "task", uses, tmpDir)
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return taskDir, release.Version, nil
+}
+
+func registrySpellDir(projectDir, registry, name string) string {
+	return filepath.Join(registryCacheDir(projectDir, registryIndexURL(registry)), registrySpellsDirName, filepath.FromSlash(name))
+}
+
+// cachedRegistryVersion returns the cached version of a spell that matches
+// version, or an empty string when none is cached.
+func cachedRegistryVersion(spellDir, version string) string {
+	if _, err := os.Stat(filepath.Join(spellDir, version)); err == nil {
+		return version
+	}
+
+	entries, err := os.ReadDir(spellDir)
+	if err != nil {
+		return ""
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+
+	return chooseBestTag(version, "", names)
+}
+
+// downloadFile copies the content at uri to path.
+func downloadFile(uri, path string) error {
+	body, err := archive.Open(uri)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = body.Close()
+	}()
+
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, body)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
@@ -0,0 +1,208 @@
+package projects
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/frostyeti/cast/internal/offline"
+)
+
+func spellTarball(t *testing.T, name string) []byte {
+	t.Helper()
+
+	content := "name: " + name + "\nruns:\n  using: composite\n  steps:\n    - run: echo " + name + "\n"
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Name: "cast.task", Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatalf("write header: %v", err)
+	}
+	if _, err := tw.Write([]byte(content)); err != nil {
+		t.Fatalf("write content: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("close gzip: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func newRegistryServer(t *testing.T, tamper bool) *httptest.Server {
+	t.Helper()
+
+	files := map[string][]byte{}
+	index := RegistryIndex{Version: 1}
+	spell := RegistrySpell{Name: "hello", Description: "Say hello"}
+	for _, version := range []string{"1.0.0", "1.2.0", "2.0.0"} {
+		archive := spellTarball(t, "hello-"+version)
+		sum := sha256.Sum256(archive)
+		path := "spells/hello-" + version + ".tar.gz"
+		files["/"+path] = archive
+		spell.Versions = append(spell.Versions, RegistryRelease{Version: version, URL: path, Sha256: hex.EncodeToString(sum[:])})
+	}
+	index.Spells = append(index.Spells, spell, RegistrySpell{Name: "lint", Description: "Lint the repo"})
+
+	data, err := json.Marshal(index)
+	if err != nil {
+		t.Fatalf("marshal index: %v", err)
+	}
+	files["/index.json"] = data
+	if tamper {
+		files["/spells/hello-1.2.0.tar.gz"] = spellTarball(t, "tampered")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(content)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newRegistryProject(t *testing.T, registry string) *Project {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	t.Setenv(CastRemoteTasksDirEnv, filepath.Join(tmpDir, "stable"))
+	t.Setenv(CastVolatileRemoteTasksDirEnv, filepath.Join(tmpDir, "volatile"))
+	t.Setenv("CI", "")
+
+	p := newLockfileProject(tmpDir, filepath.Join(tmpDir, "castfile.yaml"), LockAuto)
+	p.Schema.Registry = registry
+	return p
+}
+
+func TestFetchRemoteTask_ResolvesRegistryRefs(t *testing.T) {
+	server := newRegistryServer(t, false)
+	p := newRegistryProject(t, server.URL)
+
+	entry, err := FetchRemoteTask(p, "reg:hello@1", nil, io.Discard)
+	if err != nil {
+		t.Fatalf("failed to fetch registry task: %v", err)
+	}
+	if filepath.Base(entry) != "cast.task" || filepath.Base(filepath.Dir(entry)) != "1.2.0" {
+		t.Fatalf("expected reg:hello@1 to resolve to the 1.2.0 cast.task, got %s", entry)
+	}
+
+	_, err = FetchRemoteTask(p, "reg:hello@3", nil, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "no version matching 3") {
+		t.Fatalf("expected a missing version error, got: %v", err)
+	}
+
+	_, err = FetchRemoteTask(p, "reg:missing@1", nil, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "is not in registry") {
+		t.Fatalf("expected a missing spell error, got: %v", err)
+	}
+
+	t.Setenv(offline.Env, "1")
+	delete(registryIndexes, registryIndexURL(server.URL))
+	cached, err := FetchRemoteTask(p, "reg:hello@1", nil, io.Discard)
+	if err != nil {
+		t.Fatalf("expected the cached spell to resolve offline, got: %v", err)
+	}
+	if cached != entry {
+		t.Fatalf("expected cached entry %s, got %s", entry, cached)
+	}
+
+	index, err := LoadRegistryIndex(p.Dir, server.URL)
+	if err != nil {
+		t.Fatalf("expected the cached index to load offline, got: %v", err)
+	}
+	if got := index.Search("LINT"); len(got) != 1 || got[0].Name != "lint" {
+		t.Fatalf("expected search to find lint, got %v", got)
+	}
+}
+
+func TestFetchRemoteTask_RejectsRegistryDigestMismatch(t *testing.T) {
+	server := newRegistryServer(t, true)
+	p := newRegistryProject(t, server.URL+"/index.json")
+
+	_, err := FetchRemoteTask(p, "reg:hello@1.2.0", nil, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "sha256 mismatch") {
+		t.Fatalf("expected a digest mismatch, got: %v", err)
+	}
+
+	if _, err := FetchRemoteTask(p, "reg:hello@1.0.0", nil, io.Discard); err != nil {
+		t.Fatalf("expected an untampered version to fetch, got: %v", err)
+	}
+
+	p.Schema.Registry = ""
+	t.Setenv(CastRegistryEnv, "")
+	_, err = FetchRemoteTask(p, "reg:hello@1.0.0", nil, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "needs a spell registry") {
+		t.Fatalf("expected a missing registry error, got: %v", err)
+	}
+}
+
+func TestRegistrySpellLatestAndParseRef(t *testing.T) {
+	spell := RegistrySpell{Versions: []RegistryRelease{{Version: "1.10.0"}, {Version: "1.9.3"}, {Version: "0.4.0"}}}
+	if latest, ok := spell.Latest(); !ok || latest.Version != "1.10.0" {
+		t.Fatalf("expected latest 1.10.0, got %v", latest)
+	}
+
+	name, version, err := parseRegistryRef("reg:acme/lint@1.2")
+	if err != nil || name != "acme/lint" || version != "1.2" {
+		t.Fatalf("parseRegistryRef = %q %q %v", name, version, err)
+	}
+
+	for _, ref := range []string{"reg:lint", "reg:@1", "reg:../lint@1", "reg:lint@", "reg:lint@../../x/1.0.0", `reg:lint@1.0\..\x`, "reg:lint@main"} {
+		if _, _, err := parseRegistryRef(ref); err == nil {
+			t.Fatalf("expected %s to be rejected", ref)
+		}
+	}
+}
+
+func TestFetchRemoteTask_RejectsRegistryVersionOutsideCache(t *testing.T) {
+	archive := spellTarball(t, "escaped")
+	sum := sha256.Sum256(archive)
+	version := "../../../../escaped/1.0.0-rc"
+	index := RegistryIndex{Version: 1, Spells: []RegistrySpell{{
+		Name:     "hello",
+		Versions: []RegistryRelease{{Version: version, URL: "hello.tar.gz", Sha256: hex.EncodeToString(sum[:])}},
+	}}}
+	data, err := json.Marshal(index)
+	if err != nil {
+		t.Fatalf("marshal index: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.json":
+			_, _ = w.Write(data)
+		case "/hello.tar.gz":
+			_, _ = w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	p := newRegistryProject(t, server.URL)
+	_, err = FetchRemoteTask(p, "reg:hello@1.0.0-rc", nil, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "invalid version") {
+		t.Fatalf("expected the index version to be rejected, got: %v", err)
+	}
+
+	escaped := filepath.Join(registrySpellDir(p.Dir, server.URL, "hello"), version)
+	if _, err := os.Stat(escaped); !os.IsNotExist(err) {
+		t.Fatalf("expected nothing to be written outside the cache, got: %v", err)
+	}
+}
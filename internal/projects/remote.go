@@ -11,12 +11,16 @@ import (
 	"strings"
 
 	"github.com/frostyeti/cast/internal/errors"
+	"github.com/frostyeti/cast/internal/integrity"
+	"github.com/frostyeti/cast/internal/offline"
 	"github.com/frostyeti/go/exec"
 )
 
 const (
-	CastRemoteTasksDirEnv         = "CAST_REMOTE_TASKS_DIR"
-	CastVolatileRemoteTasksDirEnv = "CAST_VOLATILE_REMOTE_TASKS_DIR"
+	CastRemoteTasksDirEnv           = "CAST_REMOTE_TASKS_DIR"
+	CastVolatileRemoteTasksDirEnv   = "CAST_VOLATILE_REMOTE_TASKS_DIR"
+	CastRemoteModulesDirEnv         = "CAST_REMOTE_MODULES_DIR"
+	CastVolatileRemoteModulesDirEnv = "CAST_VOLATILE_REMOTE_MODULES_DIR"
 )
 
 type FetchRemoteTaskOptions struct {
@@ -72,6 +76,24 @@ func ResolveVolatileRemoteTasksDir(projectDir string) string {
 	return resolveConfiguredRemoteTasksDir(projectDir, CastVolatileRemoteTasksDirEnv, defaultDir)
 }
 
+func stableRemoteModulesDir(projectDir string) string {
+	homeDir, _ := os.UserHomeDir()
+	defaultDir := filepath.Join(homeDir, ".local", "cast", "modules")
+	return resolveConfiguredRemoteTasksDir(projectDir, CastRemoteModulesDirEnv, defaultDir)
+}
+
+// ResolveRemoteModulesDir resolves the stable remote modules directory.
+func ResolveRemoteModulesDir(projectDir string) string {
+	return stableRemoteModulesDir(projectDir)
+}
+
+// ResolveVolatileRemoteModulesDir resolves the volatile remote modules
+// directory.
+func ResolveVolatileRemoteModulesDir(projectDir string) string {
+	defaultDir := filepath.Join(projectDir, ".cast", "cache", "modules")
+	return resolveConfiguredRemoteTasksDir(projectDir, CastVolatileRemoteModulesDirEnv, defaultDir)
+}
+
 func isVolatileRemoteReference(version string, mode gitResolveMode) bool {
 	if version == "" || isHeadRef(version) {
 		return true
@@ -463,6 +485,7 @@ func parseHostedRemoteTarget(repoPart string) (string, []string, bool) {
 
 func parseRemoteGitTarget(uses string) (remoteGitTarget, error) {
 	result := remoteGitTarget{version: "v1.0.0"}
+	uses, _ = integrity.Split(uses)
 
 	var repoPart, refPart string
 	if idx := strings.LastIndex(uses, "@"); idx > -1 {
@@ -537,6 +560,7 @@ func parseRemoteGitTarget(uses string) (remoteGitTarget, error) {
 func IsRemoteTask(uses string) bool {
 	return strings.HasPrefix(uses, "spell:") ||
 		strings.HasPrefix(uses, "task:") ||
+		strings.HasPrefix(uses, registryPrefix) ||
 		strings.HasPrefix(uses, "cast:") ||
 		strings.HasPrefix(uses, "github:") ||
 		strings.HasPrefix(uses, "gh:") ||
@@ -559,22 +583,226 @@ func IsRemoteTask(uses string) bool {
 		filepath.IsAbs(uses)
 }
 
+// remoteGitTaskPlan describes where a git based remote task resolves to and
+// where it is cached, without fetching it.
+type remoteGitTaskPlan struct {
+	target          remoteGitTarget
+	resolvedVersion string
+	mode            gitResolveMode
+	cacheDir        string
+	layout          remoteCacheLayout
+	archive         bool
+}
+
+func planRemoteGitTask(p *Project, uses string) (remoteGitTaskPlan, error) {
+	return planRemoteGit(uses, stableRemoteTasksDir(p.Dir), volatileRemoteTasksDir(p), remoteFetchMode(p))
+}
+
+// planRemoteGit resolves uses and places it under stableDir when it is
+// pinned to a tag or commit, or under volatileDir when it follows a branch.
+// fetch picks between cloning with git and downloading an archive.
+func planRemoteGit(uses, stableDir, volatileDir, fetch string) (remoteGitTaskPlan, error) {
+	plan := remoteGitTaskPlan{}
+
+	target, err := parseRemoteGitTarget(uses)
+	if err != nil {
+		return plan, err
+	}
+
+	if len(target.version) == 0 {
+		return plan, errors.Newf("invalid remote task identifier, version required: %s", uses)
+	}
+
+	normalizedSubPath, err := normalizeRemoteSubPath(target.subPath)
+	if err != nil {
+		return plan, err
+	}
+	target.subPath = normalizedSubPath
+
+	useArchive, err := useArchiveFetch(fetch, target.repoURL)
+	if err != nil {
+		return plan, err
+	}
+
+	resolvedVersion, mode := resolveReference(target.version, target.subPath, func() ([]string, error) {
+		return listVersionTags(fetch, target.repoURL)
+	})
+	cacheDir := volatileDir
+	if !isVolatileRemoteReference(target.version, mode) {
+		cacheDir = stableDir
+	}
+
+	hash := sha256.Sum256([]byte(uses))
+	hashStr := hex.EncodeToString(hash[:])
+	layout, err := buildRemoteTaskCacheLayout(cacheDir, hashStr, target, resolvedVersion)
+	if err != nil {
+		return plan, err
+	}
+
+	if offline.Enabled() && mode == gitResolveBranch && looksLikeRemoteVersion(target.version) {
+		if _, err := os.Stat(layout.repoDir); err != nil {
+			if cached := cachedRemoteVersion(cacheDir, hashStr, target); cached != "" {
+				resolvedVersion = cached
+				layout, err = buildRemoteTaskCacheLayout(cacheDir, hashStr, target, resolvedVersion)
+				if err != nil {
+					return plan, err
+				}
+			}
+		}
+	}
+
+	plan.target = target
+	plan.resolvedVersion = resolvedVersion
+	plan.mode = mode
+	plan.cacheDir = cacheDir
+	plan.layout = layout
+	plan.archive = useArchive
+	return plan, nil
+}
+
+// cachedRemoteVersion returns the best cached tag matching target.version,
+// or an empty string when none is cached. It stands in for the ls-remote
+// lookup of resolveGitReference while offline.
+func cachedRemoteVersion(cacheDir, hashStr string, target remoteGitTarget) string {
+	if len(target.cacheParts) == 0 && target.subPath == "" {
+		// hash keyed entries have no version directory to choose from.
+		return ""
+	}
+
+	prefixes := []string{""}
+	if target.subPath != "" {
+		prefixes = []string{target.subPath + "/", ""}
+	}
+
+	for _, prefix := range prefixes {
+		probe, err := buildRemoteTaskCacheLayout(cacheDir, hashStr, target, prefix+"_")
+		if err != nil {
+			return ""
+		}
+
+		versionDir := probe.repoDir
+		if target.subPath != "" {
+			versionDir = filepath.Dir(versionDir)
+		}
+
+		entries, err := os.ReadDir(filepath.Dir(versionDir))
+		if err != nil {
+			continue
+		}
+
+		names := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			if entry.IsDir() {
+				names = append(names, entry.Name())
+			}
+		}
+
+		if best := chooseBestTag(target.version, "", names); best != "" {
+			return prefix + best
+		}
+	}
+
+	return ""
+}
+
+// isTrustedSource reports whether uses matches one of the trusted_sources
+// patterns. Local paths and projects without trusted_sources are trusted.
+func isTrustedSource(uses string, trustedSources []string) bool {
+	if len(trustedSources) == 0 || strings.HasPrefix(uses, "./") || strings.HasPrefix(uses, "../") || filepath.IsAbs(uses) {
+		return true
+	}
+
+	for _, pattern := range trustedSources {
+		if matchesTrustedSource(uses, pattern) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesTrustedSource reports whether uses matches a trusted_sources glob or
+// starts with it.
+func matchesTrustedSource(uses, pattern string) bool {
+	match, _ := filepath.Match(pattern, uses)
+	return match || strings.HasPrefix(uses, pattern)
+}
+
+// fetchRemoteGitPlan clones the repository described by plan into its cache
+// directory unless it is already cached. kind names what is fetched in the
+// progress message, such as "task" or "module".
+func fetchRemoteGitPlan(plan remoteGitTaskPlan, kind, uses string, forceRefresh bool, stdout io.Writer) error {
+	if err := os.MkdirAll(plan.cacheDir, 0o755); err != nil {
+		return err
+	}
+
+	layout := plan.layout
+	repoDir := layout.repoDir
+	isOffline := offline.Enabled()
+	if forceRefresh && !isOffline {
+		_ = os.RemoveAll(repoDir)
+	}
+
+	if _, err := os.Stat(repoDir); !os.IsNotExist(err) {
+		return nil
+	}
+
+	if isOffline {
+		return offline.NotCachedError(fmt.Sprintf("remote %s '%s'", kind, uses))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(repoDir), 0o755); err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(stdout, "Fetching %s: %s\n", kind, uses)
+
+	var err error
+	switch {
+	case plan.archive:
+		err = fetchRemoteArchive(plan)
+	case layout.subPath != "":
+		err = cloneRemoteTaskRepositorySparse(plan.target.repoURL, plan.resolvedVersion, plan.mode, repoDir, layout.subPath, stdout)
+	default:
+		err = cloneRemoteTaskRepository(plan.target.repoURL, plan.resolvedVersion, plan.mode, repoDir, stdout)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintln(stdout)
+	return nil
+}
+
+// verifyCachedTree checks the cached task or module in dir against its
+// integrity pin. Cached entries are verified on every load, not only after a
+// fetch, and an entry that fails is removed so the next run fetches it again.
+func verifyCachedTree(dir, pin, kind, ref string) error {
+	if pin == "" {
+		return nil
+	}
+
+	if err := integrity.Verify(dir, pin); err != nil {
+		_ = os.RemoveAll(dir)
+		return errors.Newf("remote %s '%s' failed verification: %w", kind, ref, err)
+	}
+
+	return nil
+}
+
 // FetchRemoteTask resolves and downloads a remote task, returning the local file path to the entrypoint module.
 func fetchRemoteTaskWithOptions(p *Project, uses string, trustedSources []string, opts FetchRemoteTaskOptions) (string, error) {
 	stdoutWriter := remoteTaskStdoutWriter(opts.Stdout)
 
-	// First, check trusted sources
-	isTrusted := len(trustedSources) == 0 || strings.HasPrefix(uses, "./") || strings.HasPrefix(uses, "../") || filepath.IsAbs(uses)
-
-	for _, pattern := range trustedSources {
-		match, _ := filepath.Match(pattern, uses)
-		if match || strings.HasPrefix(uses, pattern) {
-			isTrusted = true
-			break
+	written := uses
+	uses, pin := integrity.Split(uses)
+	if pin != "" {
+		if err := integrity.Validate(pin); err != nil {
+			return "", errors.Newf("remote task '%s' has an invalid integrity pin: %w", written, err)
 		}
 	}
 
-	if len(trustedSources) > 0 && !isTrusted {
+	if !isTrustedSource(uses, trustedSources) {
 		return "", errors.Newf("remote task '%s' is not in trusted_sources", uses)
 	}
 
@@ -608,8 +836,6 @@ func fetchRemoteTaskWithOptions(p *Project, uses string, trustedSources []string
 
 	VerifyChecksumAndRefresh(p)
 
-	hash := sha256.Sum256([]byte(uses))
-	hashStr := hex.EncodeToString(hash[:])
 	taskDir := ""
 
 	// If it's a JSR or NPM package, Deno handles it natively via import "jsr:..." or "npm:..."
@@ -617,81 +843,66 @@ func fetchRemoteTaskWithOptions(p *Project, uses string, trustedSources []string
 	// "Download dependencies to a central cache directory (e.g., .cast/cache/tasks/)."
 	// "Git Tasks: Perform a shallow git clone or download a tarball for the specified tag/version."
 
-	if strings.HasPrefix(uses, "git@") || strings.HasPrefix(uses, "ssh://") || strings.HasPrefix(uses, "git+ssh://") || strings.HasPrefix(uses, "github:") || strings.HasPrefix(uses, "gh:") || strings.HasPrefix(uses, "gitlab:") || strings.HasPrefix(uses, "gl:") || strings.HasPrefix(uses, "azdo:") || strings.HasPrefix(uses, "spell:") || strings.HasPrefix(uses, "task:") || strings.HasPrefix(uses, "file://") || strings.HasPrefix(uses, "https://") || strings.HasPrefix(uses, "http://") || strings.HasPrefix(uses, "github.com/") || strings.HasPrefix(uses, "gitlab.com/") || strings.HasPrefix(uses, "dev.azure.com/") {
-		target, err := parseRemoteGitTarget(uses)
+	if IsRegistryRef(uses) {
+		taskDir, resolved, err := fetchRegistryTask(p, uses, stdoutWriter)
 		if err != nil {
 			return "", err
 		}
 
-		if len(target.version) == 0 {
-			return "", errors.Newf("invalid remote task identifier, version required: %s", uses)
+		if err := verifyCachedTree(taskDir, pin, "task", uses); err != nil {
+			return "", err
 		}
-
-		normalizedSubPath, err := normalizeRemoteSubPath(target.subPath)
-		if err != nil {
+		if err := verifySignature(p, "task", uses, taskDir); err != nil {
+			_ = os.RemoveAll(taskDir)
 			return "", err
 		}
-		target.subPath = normalizedSubPath
 
-		resolvedVersion, cloneMode := resolveGitReference(target.repoURL, target.version, target.subPath)
-		cacheDir := volatileRemoteTasksDir(p)
-		if !isVolatileRemoteReference(target.version, cloneMode) {
-			cacheDir = stableRemoteTasksDir(p.Dir)
-		}
+		recordCacheUse(taskDir, uses, resolved)
 
-		if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		if err := p.lockRef("tasks", written, resolved, taskDir); err != nil {
 			return "", err
 		}
 
-		layout, err := buildRemoteTaskCacheLayout(cacheDir, hashStr, target, resolvedVersion)
+		return remoteEntryFile(taskDir), nil
+	}
+
+	if strings.HasPrefix(uses, "git@") || strings.HasPrefix(uses, "ssh://") || strings.HasPrefix(uses, "git+ssh://") || strings.HasPrefix(uses, "github:") || strings.HasPrefix(uses, "gh:") || strings.HasPrefix(uses, "gitlab:") || strings.HasPrefix(uses, "gl:") || strings.HasPrefix(uses, "azdo:") || strings.HasPrefix(uses, "spell:") || strings.HasPrefix(uses, "task:") || strings.HasPrefix(uses, "file://") || strings.HasPrefix(uses, "https://") || strings.HasPrefix(uses, "http://") || strings.HasPrefix(uses, "github.com/") || strings.HasPrefix(uses, "gitlab.com/") || strings.HasPrefix(uses, "dev.azure.com/") {
+		plan, err := planRemoteGitTask(p, uses)
 		if err != nil {
 			return "", err
 		}
-		taskDir = layout.repoDir
 
-		repoURL := target.repoURL
+		if err := fetchRemoteGitPlan(plan, "task", uses, opts.ForceRefresh, stdoutWriter); err != nil {
+			return "", err
+		}
 
-		if opts.ForceRefresh {
+		layout := plan.layout
+		taskDir = layout.repoDir
+		if err := verifyCachedTree(taskDir, pin, "task", uses); err != nil {
+			return "", err
+		}
+		// a subpath spell is signed on its own directory.
+		if err := verifySignature(p, "task", uses, layout.entryDir); err != nil {
 			_ = os.RemoveAll(taskDir)
+			return "", err
 		}
 
-		if _, err := os.Stat(taskDir); os.IsNotExist(err) {
-			if err := os.MkdirAll(filepath.Dir(taskDir), 0o755); err != nil {
-				return "", err
-			}
+		recordCacheUse(taskDir, uses, plan.resolvedVersion)
 
-			_, _ = fmt.Fprintf(stdoutWriter, "Fetching task: %s\n", uses)
-
-			if layout.subPath != "" {
-				err = cloneRemoteTaskRepositorySparse(repoURL, resolvedVersion, cloneMode, taskDir, layout.subPath, stdoutWriter)
-			} else {
-				err = cloneRemoteTaskRepository(repoURL, resolvedVersion, cloneMode, taskDir, stdoutWriter)
-			}
-			if err != nil {
-				return "", err
-			}
-
-			_, _ = fmt.Fprintln(stdoutWriter)
+		if err := p.lockRef("tasks", written, plan.resolvedVersion, taskDir); err != nil {
+			return "", err
 		}
 
-		entryFile := layout.entryDir
-
-		// Check if it's a directory, if so look for cast.task or standard entrypoints
-		stat, err := os.Stat(entryFile)
-		if err == nil && stat.IsDir() {
-			tryFiles := []string{
-				"cast.task", "cast", "cast.yaml", "cast.yml", "spell", "spell.yaml", "spell.yml",
-				"mod.ts", "main.ts", "index.ts",
-				"mod.js", "main.js", "index.js",
-			}
-			for _, ep := range tryFiles {
-				if _, err := os.Stat(filepath.Join(entryFile, ep)); err == nil {
-					return filepath.Join(entryFile, ep), nil
-				}
-			}
-		}
-		return entryFile, nil
+		return remoteEntryFile(layout.entryDir), nil
 	} else if strings.HasPrefix(uses, "jsr:") || strings.HasPrefix(uses, "npm:") {
+		keys, err := signingKeys(p, uses)
+		if err != nil {
+			return "", err
+		}
+		if len(keys) > 0 {
+			return "", errors.Newf("remote task '%s' must be signed, but jsr: and npm: packages cannot carry a %s", uses, integrity.SignatureFile)
+		}
+
 		// For JSR/NPM, we can just return the URI itself and let Deno's native module resolution handle it in the wrapper
 		// Or we can cache it. "Fetch the manifest and module using standard HTTP requests or Deno's tooling."
 		// Returning the string allows the Deno wrapper to just import it!
@@ -701,6 +912,26 @@ func fetchRemoteTaskWithOptions(p *Project, uses string, trustedSources []string
 	return "", errors.Newf("unsupported remote task URI: %s", uses)
 }
 
+// remoteEntryFile returns the entrypoint of a fetched remote task. When
+// entryFile is a directory it looks for cast.task or a standard entrypoint.
+func remoteEntryFile(entryFile string) string {
+	stat, err := os.Stat(entryFile)
+	if err == nil && stat.IsDir() {
+		tryFiles := []string{
+			"cast.task", "cast", "cast.yaml", "cast.yml", "spell", "spell.yaml", "spell.yml",
+			"mod.ts", "main.ts", "index.ts",
+			"mod.js", "main.js", "index.js",
+		}
+		for _, ep := range tryFiles {
+			if _, err := os.Stat(filepath.Join(entryFile, ep)); err == nil {
+				return filepath.Join(entryFile, ep)
+			}
+		}
+	}
+
+	return entryFile
+}
+
 // FetchRemoteTask resolves and downloads a remote task, returning the local file path to the entrypoint module.
 func FetchRemoteTask(p *Project, uses string, trustedSources []string, stdout io.Writer) (string, error) {
 	return fetchRemoteTaskWithOptions(p, uses, trustedSources, FetchRemoteTaskOptions{Stdout: stdout})
@@ -0,0 +1,236 @@
+package projects
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	stdexec "os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/frostyeti/cast/internal/archive"
+	"github.com/frostyeti/cast/internal/errors"
+	"github.com/frostyeti/cast/internal/types"
+)
+
+const (
+	// CastFetchEnv sets how remote git tasks and modules are fetched when the
+	// castfile has no `fetch:`.
+	CastFetchEnv = "CAST_FETCH"
+	// GitHubTokenEnv and GitLabTokenEnv authorize archive downloads and tag
+	// lookups, for private repositories and higher rate limits.
+	GitHubTokenEnv = "GITHUB_TOKEN"
+	GitLabTokenEnv = "GITLAB_TOKEN"
+
+	archiveTagsPerPage = 100
+)
+
+// hosting provider endpoints, replaced by tests.
+var (
+	githubWebURL = "https://github.com"
+	githubAPIURL = "https://api.github.com"
+	gitlabAPIURL = "https://gitlab.com/api/v4"
+)
+
+var gitInstalled = func() bool {
+	_, err := stdexec.LookPath("git")
+	return err == nil
+}
+
+// remoteArchiveSource is a GitHub or GitLab repository that can be
+// downloaded as a tarball instead of cloned.
+type remoteArchiveSource struct {
+	host  string
+	owner string
+	repo  string
+}
+
+// remoteFetchMode returns the `fetch:` of the castfile of p, falling back to
+// CAST_FETCH and then auto. p may be nil.
+func remoteFetchMode(p *Project) string {
+	if p != nil && p.Schema.Fetch != "" {
+		return p.Schema.Fetch
+	}
+	if mode := strings.ToLower(strings.TrimSpace(os.Getenv(CastFetchEnv))); mode != "" {
+		return mode
+	}
+
+	return types.FetchAuto
+}
+
+// parseRemoteArchiveSource returns the GitHub or GitLab repository of
+// repoURL, as written by parseRemoteGitTarget.
+func parseRemoteArchiveSource(repoURL string) (remoteArchiveSource, bool) {
+	for _, host := range []string{"github", "gitlab"} {
+		path, ok := strings.CutPrefix(repoURL, "https://"+host+".com/")
+		if !ok {
+			continue
+		}
+		parts := strings.Split(trimGitSuffix(strings.Trim(path, "/")), "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return remoteArchiveSource{}, false
+		}
+		return remoteArchiveSource{host: host, owner: parts[0], repo: parts[1]}, true
+	}
+
+	return remoteArchiveSource{}, false
+}
+
+// useArchiveFetch reports whether repoURL is downloaded as a tarball under
+// the fetch mode: always for archive, never for git, and for auto only when
+// git is not installed.
+func useArchiveFetch(mode, repoURL string) (bool, error) {
+	_, supported := parseRemoteArchiveSource(repoURL)
+	switch mode {
+	case types.FetchGit:
+		return false, nil
+	case types.FetchArchive:
+		if !supported {
+			return false, errors.Newf("fetch: archive only supports GitHub and GitLab repositories, not %s", repoURL)
+		}
+		return true, nil
+	case types.FetchAuto, "":
+		if gitInstalled() {
+			return false, nil
+		}
+		if !supported {
+			return false, errors.Newf("git is not installed; only GitHub and GitLab repositories can be fetched without it, not %s", repoURL)
+		}
+		return true, nil
+	default:
+		return false, errors.Newf("invalid %s %q; use %s, %s or %s", CastFetchEnv, mode, types.FetchAuto, types.FetchGit, types.FetchArchive)
+	}
+}
+
+func (s remoteArchiveSource) header() http.Header {
+	header := http.Header{}
+	switch s.host {
+	case "github":
+		if token := strings.TrimSpace(os.Getenv(GitHubTokenEnv)); token != "" {
+			header.Set("Authorization", "Bearer "+token)
+		}
+	case "gitlab":
+		if token := strings.TrimSpace(os.Getenv(GitLabTokenEnv)); token != "" {
+			header.Set("PRIVATE-TOKEN", token)
+		}
+	}
+
+	return header
+}
+
+func (s remoteArchiveSource) gitlabProject() string {
+	return gitlabAPIURL + "/projects/" + url.PathEscape(s.owner+"/"+s.repo)
+}
+
+// tarballURL returns where the tarball of ref is downloaded from. GitHub
+// archives of public repositories are served without the API rate limit, so
+// the API is only used when a token is set.
+func (s remoteArchiveSource) tarballURL(ref string) string {
+	if s.host == "gitlab" {
+		uri := s.gitlabProject() + "/repository/archive.tar.gz"
+		if !isHeadRef(ref) {
+			// without a sha GitLab archives the default branch.
+			uri += "?sha=" + url.QueryEscape(ref)
+		}
+		return uri
+	}
+	if s.header().Get("Authorization") != "" {
+		return fmt.Sprintf("%s/repos/%s/%s/tarball/%s", githubAPIURL, s.owner, s.repo, escapeRefPath(ref))
+	}
+
+	return fmt.Sprintf("%s/%s/%s/archive/%s.tar.gz", githubWebURL, s.owner, s.repo, escapeRefPath(ref))
+}
+
+// escapeRefPath escapes each segment of ref, keeping the slashes of tags
+// such as `deploy/v1.2.0` and branches such as `feature/x`.
+func escapeRefPath(ref string) string {
+	segments := strings.Split(ref, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// listTags returns the tag names of the repository from the hosting
+// provider's API, standing in for `git ls-remote --tags`.
+func (s remoteArchiveSource) listTags() ([]string, error) {
+	tags := []string{}
+	for page := 1; ; page++ {
+		uri := fmt.Sprintf("%s/repos/%s/%s/tags?per_page=%d&page=%d", githubAPIURL, s.owner, s.repo, archiveTagsPerPage, page)
+		if s.host == "gitlab" {
+			uri = fmt.Sprintf("%s/repository/tags?per_page=%d&page=%d", s.gitlabProject(), archiveTagsPerPage, page)
+		}
+
+		body, err := archive.OpenWithHeader(uri, s.header())
+		if err != nil {
+			return nil, errors.Newf("failed to list tags of %s/%s: %w", s.owner, s.repo, err)
+		}
+		var found []struct {
+			Name string `json:"name"`
+		}
+		err = json.NewDecoder(body).Decode(&found)
+		_ = body.Close()
+		if err != nil {
+			return nil, errors.Newf("failed to list tags of %s/%s: %w", s.owner, s.repo, err)
+		}
+
+		for _, tag := range found {
+			tags = append(tags, tag.Name)
+		}
+		if len(found) < archiveTagsPerPage {
+			return tags, nil
+		}
+	}
+}
+
+// listVersionTags lists the tags of repoURL through the hosting provider
+// when it is fetched as an archive under mode, and `git ls-remote` otherwise.
+func listVersionTags(mode, repoURL string) ([]string, error) {
+	useArchive, err := useArchiveFetch(mode, repoURL)
+	if err != nil {
+		return nil, err
+	}
+	if !useArchive {
+		return listRemoteTags(repoURL)
+	}
+
+	source, _ := parseRemoteArchiveSource(repoURL)
+	return source.listTags()
+}
+
+// fetchRemoteArchive downloads plan as a tarball into its cache layout. Only
+// the subpath of the plan is extracted.
+func fetchRemoteArchive(plan remoteGitTaskPlan) error {
+	source, ok := parseRemoteArchiveSource(plan.target.repoURL)
+	if !ok {
+		return errors.Newf("%s cannot be fetched as an archive", plan.target.repoURL)
+	}
+
+	return archive.Stage(plan.layout.repoDir, func(tmpDir string) error {
+		uri := source.tarballURL(plan.resolvedVersion)
+		body, err := archive.OpenWithHeader(uri, source.header())
+		if err != nil {
+			return errors.Newf("failed to download the archive of %s@%s: %w", plan.target.repoURL, plan.resolvedVersion, err)
+		}
+		defer func() {
+			_ = body.Close()
+		}()
+
+		// both providers wrap the repository in a single root directory.
+		opts := archive.ExtractOptions{StripComponents: 1, Only: plan.layout.subPath}
+		if err := archive.ExtractTarGzWithOptions(body, tmpDir, opts); err != nil {
+			return errors.Newf("failed to extract %s: %w", uri, err)
+		}
+
+		if plan.layout.subPath != "" {
+			if _, err := os.Stat(filepath.Join(tmpDir, filepath.FromSlash(plan.layout.subPath))); err != nil {
+				return errors.Newf("subpath %s not found in %s@%s", plan.layout.subPath, plan.target.repoURL, plan.resolvedVersion)
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,180 @@
+package projects
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/frostyeti/cast/internal/types"
+)
+
+// repoTarball builds a tarball shaped like a hosting provider archive, with
+// every file under root.
+func repoTarball(t *testing.T, root string, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Name: "pax_global_header", Typeflag: tar.TypeXGlobalHeader, PAXRecords: map[string]string{"comment": "0123456789abcdef0123456789abcdef01234567"}}); err != nil {
+		t.Fatalf("write global header: %v", err)
+	}
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: root + "/" + name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("write header: %v", err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatalf("write content: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("close gzip: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func setupArchiveRemote(t *testing.T) (string, *atomic.Int32) {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	t.Setenv(CastRemoteTasksDirEnv, filepath.Join(tmpDir, "stable"))
+	t.Setenv(CastVolatileRemoteTasksDirEnv, filepath.Join(tmpDir, "volatile"))
+	t.Setenv(GitHubTokenEnv, "")
+	t.Setenv(CastFetchEnv, "")
+
+	spell := "name: hello\nruns:\n  using: composite\n  steps:\n    - uses: bash\n      run: echo hello\n"
+	tarball := repoTarball(t, "spells-1.2.0", map[string]string{
+		"hello/spell":     spell,
+		"other/spell":     "name: other\n",
+		"README.md":       "# spells\n",
+		"../../escape.sh": "echo escaped\n",
+	})
+
+	downloads := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/acme/spells/tags":
+			_, _ = io.WriteString(w, `[{"name":"v2.0.0-beta.1"},{"name":"v1.2.0"},{"name":"v1.0.0"}]`)
+		case "/acme/spells/archive/v1.2.0.tar.gz":
+			downloads.Add(1)
+			_, _ = w.Write(tarball)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	origWeb, origAPI, origGit := githubWebURL, githubAPIURL, gitInstalled
+	githubWebURL, githubAPIURL = server.URL, server.URL
+	t.Cleanup(func() {
+		githubWebURL, githubAPIURL, gitInstalled = origWeb, origAPI, origGit
+	})
+
+	projectDir := filepath.Join(tmpDir, "project")
+	if err := os.MkdirAll(projectDir, 0o755); err != nil {
+		t.Fatalf("mkdir project: %v", err)
+	}
+
+	return projectDir, downloads
+}
+
+func TestFetchRemoteTaskFromArchiveWithoutGit(t *testing.T) {
+	projectDir, downloads := setupArchiveRemote(t)
+	gitInstalled = func() bool { return false }
+
+	project := &Project{Dir: projectDir, File: filepath.Join(projectDir, "castfile.yaml")}
+	entry, err := FetchRemoteTaskWithOptions(project, "gh:acme/spells@v1/hello", nil, FetchRemoteTaskOptions{Stdout: io.Discard})
+	if err != nil {
+		t.Fatalf("failed to fetch remote task archive: %v", err)
+	}
+
+	wantDir := filepath.Join(stableRemoteTasksDir(projectDir), "github", "acme", "spells", "hello", "v1.2.0", "repo")
+	if entry != filepath.Join(wantDir, "hello", "spell") {
+		t.Fatalf("expected the v1.2.0 hello spell in the usual cache layout, got %s", entry)
+	}
+	for _, name := range []string{"other", "README.md", ".git"} {
+		if _, err := os.Stat(filepath.Join(wantDir, name)); !os.IsNotExist(err) {
+			t.Fatalf("expected only the hello subpath to be extracted, found %s: %v", name, err)
+		}
+	}
+	_ = filepath.WalkDir(filepath.Dir(projectDir), func(path string, d os.DirEntry, err error) error {
+		if err == nil && d.Name() == "escape.sh" {
+			t.Fatalf("expected entries outside the archive root to be skipped, found %s", path)
+		}
+		return nil
+	})
+
+	// a second load is served from the cache.
+	if _, err := FetchRemoteTaskWithOptions(project, "gh:acme/spells@v1/hello", nil, FetchRemoteTaskOptions{Stdout: io.Discard}); err != nil {
+		t.Fatalf("failed to load cached archive: %v", err)
+	}
+	if got := downloads.Load(); got != 1 {
+		t.Fatalf("expected one archive download, got %d", got)
+	}
+
+	// the tag lookup also backs `cast task outdated`.
+	versions, err := publishedVersions(project, "gh:acme/spells@v1.0.0")
+	if err != nil || strings.Join(versions, ",") != "v2.0.0-beta.1,v1.2.0,v1.0.0" {
+		t.Fatalf("unexpected published versions %v: %v", versions, err)
+	}
+
+	if _, err := FetchRemoteTaskWithOptions(project, "https://example.com/acme/spells.git@v1.0.0", nil, FetchRemoteTaskOptions{Stdout: io.Discard}); err == nil || !strings.Contains(err.Error(), "git is not installed") {
+		t.Fatalf("expected a missing git error for a host without archives, got %v", err)
+	}
+	project.Schema.Fetch = types.FetchArchive
+	if _, err := FetchRemoteTaskWithOptions(project, "gh:acme/spells@v1/missing", nil, FetchRemoteTaskOptions{Stdout: io.Discard}); err == nil || !strings.Contains(err.Error(), "subpath missing not found") {
+		t.Fatalf("expected a missing subpath error, got %v", err)
+	}
+}
+
+func TestFetchArchiveModeOverridesGit(t *testing.T) {
+	projectDir, downloads := setupArchiveRemote(t)
+	gitInstalled = func() bool { return true }
+	t.Setenv(CastFetchEnv, types.FetchArchive)
+
+	project := &Project{Dir: projectDir, File: filepath.Join(projectDir, "castfile.yaml")}
+	if _, err := FetchRemoteTaskWithOptions(project, "gh:acme/spells@v1.2.0/hello", nil, FetchRemoteTaskOptions{Stdout: io.Discard}); err != nil {
+		t.Fatalf("failed to fetch with CAST_FETCH=archive: %v", err)
+	}
+	if got := downloads.Load(); got != 1 {
+		t.Fatalf("expected the archive to be downloaded, got %d downloads", got)
+	}
+
+	project.Schema.Fetch = types.FetchGit
+	if archive, err := useArchiveFetch(remoteFetchMode(project), "https://github.com/acme/spells.git"); err != nil || archive {
+		t.Fatalf("expected fetch: git in the castfile to win over %s, got %v, %v", CastFetchEnv, archive, err)
+	}
+}
+
+func TestRemoteArchiveTarballURL(t *testing.T) {
+	t.Setenv(GitHubTokenEnv, "")
+
+	github := remoteArchiveSource{host: "github", owner: "acme", repo: "spells"}
+	if got := github.tarballURL("deploy/v1.2.0"); got != githubWebURL+"/acme/spells/archive/deploy/v1.2.0.tar.gz" {
+		t.Fatalf("unexpected github archive url %s", got)
+	}
+	t.Setenv(GitHubTokenEnv, "secret")
+	if got := github.tarballURL("v1.2.0"); got != githubAPIURL+"/repos/acme/spells/tarball/v1.2.0" {
+		t.Fatalf("expected the api tarball url with a token, got %s", got)
+	}
+
+	gitlab := remoteArchiveSource{host: "gitlab", owner: "acme", repo: "spells"}
+	if got := gitlab.tarballURL("v1.2.0"); got != gitlabAPIURL+"/projects/acme%2Fspells/repository/archive.tar.gz?sha=v1.2.0" {
+		t.Fatalf("unexpected gitlab archive url %s", got)
+	}
+	if got := gitlab.tarballURL("HEAD"); strings.Contains(got, "sha=") {
+		t.Fatalf("expected HEAD to archive the default branch, got %s", got)
+	}
+}
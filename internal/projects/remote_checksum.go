@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+
+	"github.com/frostyeti/cast/internal/offline"
 )
 
 var projectChecksumCache = map[string]bool{} // Dir -> matches
@@ -69,6 +71,12 @@ func VerifyChecksumAndRefresh(p *Project) bool {
 		state.Files = make(map[string]string)
 	}
 
+	if !matches && offline.Enabled() {
+		// keep the caches while offline; the next online run refreshes them.
+		projectChecksumCache[p.Dir] = false
+		return false
+	}
+
 	if !matches {
 		// Clear local cache for remote tasks if checksum doesn't match
 		if err := os.RemoveAll(filepath.Join(cacheDir, "tasks")); err != nil {
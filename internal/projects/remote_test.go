@@ -3,11 +3,15 @@ package projects
 import (
 	"bytes"
 	stdErrors "errors"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/frostyeti/cast/internal/integrity"
+	"github.com/frostyeti/cast/internal/offline"
 )
 
 func TestParseRemoteGitTarget(t *testing.T) {
@@ -266,3 +270,87 @@ func runGitOutput(t *testing.T, dir string, args ...string) string {
 	}
 	return string(out)
 }
+
+func TestFetchRemoteTask_VerifiesIntegrityPin(t *testing.T) {
+	repoDir, projectDir, projectFile := setupLockfileRemote(t)
+	uses := "file://" + filepath.ToSlash(repoDir) + "@v1.0.0/hello"
+	p := newLockfileProject(projectDir, projectFile, LockAuto)
+
+	entry, err := FetchRemoteTaskWithOptions(p, uses, nil, FetchRemoteTaskOptions{Stdout: io.Discard})
+	if err != nil {
+		t.Fatalf("failed to fetch remote task: %v", err)
+	}
+	cacheDir := filepath.Dir(filepath.Dir(entry))
+	hash, err := integrity.HashTree(cacheDir)
+	if err != nil {
+		t.Fatalf("failed to hash cache: %v", err)
+	}
+
+	pinned := uses + "#" + integrity.Pin(hash)
+	if _, err := FetchRemoteTaskWithOptions(p, pinned, nil, FetchRemoteTaskOptions{Stdout: io.Discard}); err != nil {
+		t.Fatalf("expected the pinned task to verify, got: %v", err)
+	}
+
+	_, err = FetchRemoteTaskWithOptions(p, uses+"#sha256=abc", nil, FetchRemoteTaskOptions{Stdout: io.Discard})
+	if err == nil || !strings.Contains(err.Error(), "invalid integrity pin") {
+		t.Fatalf("expected an invalid pin error, got: %v", err)
+	}
+
+	if err := os.WriteFile(entry, []byte("name: tampered\n"), 0o644); err != nil {
+		t.Fatalf("failed to modify cached task: %v", err)
+	}
+	_, err = FetchRemoteTaskWithOptions(p, pinned, nil, FetchRemoteTaskOptions{Stdout: io.Discard})
+	if err == nil || !strings.Contains(err.Error(), "integrity mismatch") {
+		t.Fatalf("expected the cached task to fail verification, got: %v", err)
+	}
+	if _, err := os.Stat(cacheDir); !os.IsNotExist(err) {
+		t.Fatalf("expected the mismatched cache entry to be removed, got: %v", err)
+	}
+
+	if _, err := FetchRemoteTaskWithOptions(p, pinned, nil, FetchRemoteTaskOptions{Stdout: io.Discard}); err != nil {
+		t.Fatalf("expected a fresh clone to verify, got: %v", err)
+	}
+
+	wrong := uses + "#sha256=" + strings.Repeat("0", 64)
+	_, err = FetchRemoteTaskWithOptions(p, wrong, nil, FetchRemoteTaskOptions{Stdout: io.Discard})
+	if err == nil || !strings.Contains(err.Error(), "failed verification") {
+		t.Fatalf("expected a mismatched pin to be refused, got: %v", err)
+	}
+}
+
+func TestFetchRemoteTask_OfflineResolvesFromCache(t *testing.T) {
+	repoDir, projectDir, projectFile := setupLockfileRemote(t)
+	uses := "file://" + filepath.ToSlash(repoDir) + "@v1/hello"
+	p := newLockfileProject(projectDir, projectFile, LockAuto)
+
+	entry, err := FetchRemoteTaskWithOptions(p, uses, nil, FetchRemoteTaskOptions{Stdout: io.Discard})
+	if err != nil {
+		t.Fatalf("failed to fetch remote task: %v", err)
+	}
+	if !strings.Contains(filepath.ToSlash(entry), "/v1.0.0/") {
+		t.Fatalf("expected v1 to resolve to the v1.0.0 tag, got %s", entry)
+	}
+
+	origLsRemote := gitLsRemote
+	gitLsRemote = func(repoURL string) (string, int, error) {
+		t.Fatalf("ls-remote must not run offline: %s", repoURL)
+		return "", 1, nil
+	}
+	defer func() {
+		gitLsRemote = origLsRemote
+	}()
+
+	t.Setenv(offline.Env, "1")
+	cached, err := FetchRemoteTaskWithOptions(p, uses, nil, FetchRemoteTaskOptions{Stdout: io.Discard, ForceRefresh: true})
+	if err != nil {
+		t.Fatalf("expected the cached task to resolve offline, got: %v", err)
+	}
+	if cached != entry {
+		t.Fatalf("expected cached entry %s, got %s", entry, cached)
+	}
+
+	_, err = FetchRemoteTaskWithOptions(p, "file://"+filepath.ToSlash(repoDir)+"@v2/hello", nil, FetchRemoteTaskOptions{Stdout: io.Discard})
+	if err == nil || !strings.Contains(err.Error(), "cast is offline") {
+		t.Fatalf("expected an offline error for an uncached task, got: %v", err)
+	}
+}
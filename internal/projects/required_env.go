@@ -0,0 +1,380 @@
+package projects
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/frostyeti/cast/internal/errors"
+	"github.com/frostyeti/cast/internal/paths"
+	"github.com/frostyeti/cast/internal/types"
+	"github.com/frostyeti/go/dotenv"
+	"github.com/frostyeti/go/env"
+)
+
+// RequiredEnvProblem describes a single missing or invalid required variable.
+type RequiredEnvProblem struct {
+	Name   string
+	Reason string
+	Scope  string
+	File   string
+	Line   int
+	Column int
+	Tasks  []string
+}
+
+// RequiredEnvError aggregates every required variable that is missing or
+// invalid for a run so they can all be fixed at once.
+type RequiredEnvError struct {
+	Problems []RequiredEnvProblem
+}
+
+func (e *RequiredEnvError) Error() string {
+	sb := &strings.Builder{}
+	sb.WriteString("required env variables are missing or invalid:\n")
+	for _, problem := range e.Problems {
+		_, _ = fmt.Fprintf(sb, " - %s %s (declared by %s", problem.Name, problem.Reason, problem.Scope)
+		switch {
+		case problem.File != "" && problem.Line > 0:
+			_, _ = fmt.Fprintf(sb, " at %s:%d:%d", problem.File, problem.Line, problem.Column)
+		case problem.File != "":
+			_, _ = fmt.Fprintf(sb, " in %s", problem.File)
+		case problem.Line > 0:
+			_, _ = fmt.Fprintf(sb, " on line %d, column %d", problem.Line, problem.Column)
+		}
+		if len(problem.Tasks) > 0 && problem.Scope != "task "+problem.Tasks[0] {
+			_, _ = fmt.Fprintf(sb, "; needed by %s", strings.Join(problem.Tasks, ", "))
+		}
+		sb.WriteString(")\n")
+	}
+	return sb.String()
+}
+
+type requiredEnvScope struct {
+	label string
+	file  string
+	reqs  types.EnvRequirements
+}
+
+// pendingEnv records the variables of a task env that cannot be known before
+// the run: values from command substitutions and task outputs, and anything
+// a dotenv file that does not exist yet, for example one an earlier task
+// generates, would set.
+type pendingEnv struct {
+	keys map[string]bool
+	all  bool
+}
+
+func (u *pendingEnv) has(name string) bool {
+	return u.all || u.keys[name] || strings.HasPrefix(name, "OUTPUTS_")
+}
+
+// referencedBy reports whether value references a pending variable as
+// `$KEY`, `${KEY}` or `${KEY:-default}`, so its expansion is not known
+// before the run either.
+func (u *pendingEnv) referencedBy(value string) bool {
+	if !strings.Contains(value, "$") {
+		return false
+	}
+
+	for key := range u.keys {
+		ref := regexp.MustCompile(`\$(` + regexp.QuoteMeta(key) + `\b|\{` + regexp.QuoteMeta(key) + `[}:?=+-])`)
+		if ref.MatchString(value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isRuntimeValue reports whether an env value is only known when the task
+// runs.
+func isRuntimeValue(value string) bool {
+	return strings.Contains(value, "$(") || strings.Contains(value, "`") || strings.Contains(value, "OUTPUTS_")
+}
+
+// checkRequiredEnv validates the project, task and any extra scope declarations
+// against the environment each task would receive and returns a single
+// aggregated error. The environment is resolved without running command
+// substitutions; variables only known at run time are checked when their
+// task starts instead.
+func (p *Project) checkRequiredEnv(projectEnv *types.Env, tasks []types.Task, scopes ...requiredEnvScope) error {
+	problems, err := p.collectRequiredEnvProblems(projectEnv, tasks, scopes...)
+	if err != nil {
+		return err
+	}
+
+	if len(problems) > 0 {
+		return &RequiredEnvError{Problems: problems}
+	}
+
+	return nil
+}
+
+func (p *Project) collectRequiredEnvProblems(projectEnv *types.Env, tasks []types.Task, scopes ...requiredEnvScope) ([]RequiredEnvProblem, error) {
+	if len(tasks) == 0 {
+		return p.requiredEnvProblems(p.requiredEnvScopes(nil, scopes), projectEnv.TryGet, nil, ""), nil
+	}
+
+	problems := []RequiredEnvProblem{}
+	for _, task := range tasks {
+		taskScopes := p.requiredEnvScopes(&task, scopes)
+		if len(taskScopes) == 0 {
+			continue
+		}
+
+		e := projectEnv.Clone()
+		pending := &pendingEnv{keys: map[string]bool{}}
+		if err := resolveStaticTaskEnv(p.taskDir(task), task, e, pending); err != nil {
+			return nil, err
+		}
+
+		problems = appendRequiredEnvProblems(problems, p.requiredEnvProblems(taskScopes, e.TryGet, pending, task.Name)...)
+	}
+
+	return problems, nil
+}
+
+// checkTaskRequiredEnv validates the declarations that apply to task against
+// the environment it runs with, once outputs and command substitutions are
+// known.
+func (p *Project) checkTaskRequiredEnv(task types.Task, taskEnv map[string]string, scopes ...requiredEnvScope) error {
+	lookup := func(name string) (string, bool) {
+		value, ok := taskEnv[name]
+		return value, ok
+	}
+
+	problems := p.requiredEnvProblems(p.requiredEnvScopes(&task, scopes), lookup, nil, task.Name)
+	if len(problems) > 0 {
+		return &RequiredEnvError{Problems: problems}
+	}
+
+	return nil
+}
+
+// requiredEnvScopes returns the project declarations, the extra scopes and,
+// when task is set, the declarations of the task.
+func (p *Project) requiredEnvScopes(task *types.Task, scopes []requiredEnvScope) []requiredEnvScope {
+	all := []requiredEnvScope{}
+	if len(p.Schema.EnvRequired) > 0 {
+		all = append(all, requiredEnvScope{label: "project", file: p.File, reqs: p.Schema.EnvRequired})
+	}
+	for _, scope := range scopes {
+		if len(scope.reqs) > 0 {
+			all = append(all, scope)
+		}
+	}
+	if task != nil && len(task.EnvRequired) > 0 {
+		file := task.File
+		if file == "" {
+			file = p.taskFile(*task)
+		}
+		all = append(all, requiredEnvScope{label: "task " + task.Name, file: file, reqs: task.EnvRequired})
+	}
+
+	return all
+}
+
+// requiredEnvProblems checks scopes against lookup, leaving out variables
+// that are still pending.
+func (p *Project) requiredEnvProblems(scopes []requiredEnvScope, lookup func(string) (string, bool), pending *pendingEnv, taskName string) []RequiredEnvProblem {
+	problems := []RequiredEnvProblem{}
+	for _, scope := range scopes {
+		for _, req := range scope.reqs {
+			if pending != nil && pending.has(req.Name) {
+				continue
+			}
+
+			value, ok := lookup(req.Name)
+			reason := req.Check(value, ok, p.Dir)
+			if reason == "" {
+				continue
+			}
+
+			problem := RequiredEnvProblem{
+				Name:   req.Name,
+				Reason: reason,
+				Scope:  scope.label,
+				File:   scope.file,
+				Line:   req.Line,
+				Column: req.Column,
+			}
+			if taskName != "" {
+				problem.Tasks = []string{taskName}
+			}
+			problems = appendRequiredEnvProblems(problems, problem)
+		}
+	}
+
+	return problems
+}
+
+// appendRequiredEnvProblems merges problems that share a variable, scope and
+// reason so a project-level declaration is reported once for all its tasks.
+func appendRequiredEnvProblems(problems []RequiredEnvProblem, next ...RequiredEnvProblem) []RequiredEnvProblem {
+	for _, problem := range next {
+		found := false
+		for i := range problems {
+			existing := &problems[i]
+			if existing.Name != problem.Name || existing.Scope != problem.Scope || existing.Reason != problem.Reason {
+				continue
+			}
+
+			for _, taskName := range problem.Tasks {
+				if !slices.Contains(existing.Tasks, taskName) {
+					existing.Tasks = append(existing.Tasks, taskName)
+				}
+			}
+			found = true
+			break
+		}
+
+		if !found {
+			problems = append(problems, problem)
+		}
+	}
+
+	return problems
+}
+
+// resolveTaskEnv applies a task's dotenv files and env block on top of e using
+// the same rules RunTask uses before invoking a handler. When tracker is set,
+// each change is attributed to its dotenv file or to taskFile.
+func resolveTaskEnv(dir string, task types.Task, e *types.Env, tracker *envTracker, taskFile string) error {
+	return applyTaskEnv(dir, task, e, tracker, taskFile, nil)
+}
+
+// resolveStaticTaskEnv resolves a task env like resolveTaskEnv without running
+// command substitutions. Values only known at run time and missing dotenv
+// files are recorded in pending instead.
+func resolveStaticTaskEnv(dir string, task types.Task, e *types.Env, pending *pendingEnv) error {
+	return applyTaskEnv(dir, task, e, nil, "", pending)
+}
+
+func applyTaskEnv(dir string, task types.Task, e *types.Env, tracker *envTracker, taskFile string, pending *pendingEnv) error {
+	opts := &env.ExpandOptions{
+		Get: e.Get,
+		Set: func(key, value string) error {
+			e.Set(key, value)
+			return nil
+		},
+		CommandSubstitution: pending == nil,
+		Keys:                e.Keys(),
+	}
+
+	expand := func(key, value string) (string, bool, error) {
+		if pending != nil {
+			if isRuntimeValue(value) || pending.referencedBy(value) {
+				pending.keys[key] = true
+				return "", false, nil
+			}
+			delete(pending.keys, key)
+		}
+
+		v, err := env.ExpandWithOptions(value, opts)
+		return v, err == nil, err
+	}
+
+	for _, envFile := range task.DotEnv {
+		optional := false
+		if strings.HasPrefix(envFile, "?") {
+			optional = true
+			envFile = envFile[1:]
+		} else if strings.HasSuffix(envFile, "?") {
+			optional = true
+			envFile = envFile[:len(envFile)-1]
+		}
+
+		if !filepath.IsAbs(envFile) {
+			absPath, err := paths.ResolvePath(dir, envFile)
+			if err != nil {
+				return errors.Newf("failed to resolve dotenv file %s for task %s: %w", envFile, task.Name, err)
+			}
+			envFile = absPath
+		}
+
+		if !paths.IsFile(envFile) {
+			if optional {
+				continue
+			}
+			if pending != nil {
+				pending.all = true
+				continue
+			}
+			return errors.Newf("dotenv file %s does not exist for task %s", envFile, task.Name)
+		}
+
+		data, err := os.ReadFile(envFile)
+		if err != nil {
+			return errors.Newf("failed to read dotenv file %s for task %s: %w", envFile, task.Name, err)
+		}
+
+		doc, err := dotenv.Parse(string(data))
+		if err != nil {
+			return errors.Newf("failed to parse dotenv file %s for task %s: %w", envFile, task.Name, err)
+		}
+
+		err = tracker.track(e, "task-dotenv", envFile, nil, func() error {
+			for _, node := range doc.ToArray() {
+				if node.Type != dotenv.VARIABLE || node.Key == nil || *node.Key == "" {
+					continue
+				}
+
+				v, ok, err := expand(*node.Key, node.Value)
+				if err != nil {
+					return errors.Newf("failed to expand variable %s from dotenv file %s for task %s: %w", *node.Key, envFile, task.Name, err)
+				}
+				if ok {
+					e.Set(*node.Key, v)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return tracker.track(e, "task-env", taskFile, nil, func() error {
+		for _, k := range task.Env.Keys() {
+			v, ok, err := expand(k, task.Env.Get(k))
+			if err != nil {
+				return errors.Newf("failed to expand env variable %s for task %s: %w", k, task.Name, err)
+			}
+			if !ok {
+				continue
+			}
+			e.Set(k, v)
+			if task.Env.IsSecret(k) {
+				e.MarkSecret(k)
+			}
+		}
+		return nil
+	})
+}
+
+func mergeEnvRequirements(base, override types.EnvRequirements) types.EnvRequirements {
+	if len(base) == 0 {
+		return override
+	}
+
+	merged := append(types.EnvRequirements{}, base...)
+	for _, req := range override {
+		replaced := false
+		for i, existing := range merged {
+			if existing.Name == req.Name {
+				merged[i] = req
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, req)
+		}
+	}
+
+	return merged
+}
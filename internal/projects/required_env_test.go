@@ -0,0 +1,291 @@
+package projects_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/frostyeti/cast/internal/projects"
+)
+
+func TestRunTask_RequiredEnvAggregatesProblemsBeforeRunning(t *testing.T) {
+	projectDir := t.TempDir()
+	projectFile := filepath.Join(projectDir, "castfile.yaml")
+	marker := filepath.Join(projectDir, "ran")
+
+	content := `
+name: required-env
+env-required:
+  - REGISTRY_URL
+env:
+  PORT: abc
+tasks:
+  build:
+    uses: bash
+    run: touch "` + marker + `"
+  deploy:
+    uses: bash
+    needs: [build]
+    env-required:
+      PORT: int
+      MODE:
+        values: [dev, prod]
+    run: echo deploy
+`
+	if err := os.WriteFile(projectFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write castfile: %v", err)
+	}
+
+	proj := &projects.Project{}
+	if err := proj.LoadFromYaml(projectFile); err != nil {
+		t.Fatalf("failed to load project: %v", err)
+	}
+
+	var stdout bytes.Buffer
+	_, err := proj.RunTask(projects.RunTasksParams{
+		Targets:     []string{"deploy"},
+		Context:     context.Background(),
+		ContextName: "default",
+		Stdout:      &stdout,
+		Stderr:      &stdout,
+	})
+	if err == nil {
+		t.Fatalf("expected required env error")
+	}
+
+	msg := err.Error()
+	for _, want := range []string{
+		"REGISTRY_URL is not set (declared by project at " + projectFile + ":4:5; needed by build, deploy)",
+		"PORT is not a valid int (declared by task deploy",
+		"MODE is not set (declared by task deploy",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Fatalf("expected error to contain %q, got: %s", want, msg)
+		}
+	}
+
+	if _, statErr := os.Stat(marker); statErr == nil {
+		t.Fatalf("expected no task to run when required env is missing")
+	}
+}
+
+func TestRunTask_RequiredEnvSatisfiedByTaskEnv(t *testing.T) {
+	projectDir := t.TempDir()
+	projectFile := filepath.Join(projectDir, "castfile.yaml")
+
+	content := `
+name: required-env
+env-required: [REGISTRY_URL]
+tasks:
+  deploy:
+    uses: bash
+    env:
+      REGISTRY_URL: https://registry.example.com
+    run: echo "pushing to $REGISTRY_URL"
+`
+	if err := os.WriteFile(projectFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write castfile: %v", err)
+	}
+
+	proj := &projects.Project{}
+	if err := proj.LoadFromYaml(projectFile); err != nil {
+		t.Fatalf("failed to load project: %v", err)
+	}
+
+	var stdout bytes.Buffer
+	if _, err := proj.RunTask(projects.RunTasksParams{
+		Targets:     []string{"deploy"},
+		Context:     context.Background(),
+		ContextName: "default",
+		Stdout:      &stdout,
+		Stderr:      &stdout,
+	}); err != nil {
+		t.Fatalf("failed to run task: %v", err)
+	}
+
+	if !strings.Contains(stdout.String(), "pushing to https://registry.example.com") {
+		t.Fatalf("expected task output, got: %s", stdout.String())
+	}
+}
+
+func TestRunTask_RequiredEnvChecksRuntimeValuesWhenTaskStarts(t *testing.T) {
+	projectDir := t.TempDir()
+	projectFile := filepath.Join(projectDir, "castfile.yaml")
+	marker := filepath.Join(projectDir, "marker")
+
+	content := `
+name: required-env
+tasks:
+  generate:
+    uses: bash
+    run: echo "TOKEN=$TOKEN_VALUE" > generated.env
+  deploy:
+    uses: bash
+    needs: [generate]
+    dotenv: [generated.env]
+    env:
+      STAMP: $(echo 1)
+      MARKER: $(mkdir "` + marker + `")
+    env-required:
+      TOKEN:
+        pattern: ^tok-
+      STAMP: int
+    run: echo "deploying with $TOKEN"
+`
+	if err := os.WriteFile(projectFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write castfile: %v", err)
+	}
+
+	run := func(token string) (string, []*projects.TaskResult, error) {
+		_ = os.Remove(filepath.Join(projectDir, "generated.env"))
+		_ = os.Remove(marker)
+		proj := &projects.Project{}
+		if err := proj.LoadFromYaml(projectFile); err != nil {
+			t.Fatalf("failed to load project: %v", err)
+		}
+
+		var stdout bytes.Buffer
+		results, err := proj.RunTask(projects.RunTasksParams{
+			Targets:     []string{"deploy"},
+			Context:     context.Background(),
+			ContextName: "default",
+			Env:         map[string]string{"TOKEN_VALUE": token},
+			Stdout:      &stdout,
+			Stderr:      &stdout,
+		})
+		return stdout.String(), results, err
+	}
+
+	output, _, err := run("tok-123")
+	if err != nil {
+		t.Fatalf("expected the generated dotenv file to satisfy the requirement, got: %v\nOutput: %s", err, output)
+	}
+	if !strings.Contains(output, "deploying with tok-123") {
+		t.Fatalf("expected deploy output, got: %s", output)
+	}
+	// mkdir fails when the substitution runs a second time.
+	if _, err := os.Stat(marker); err != nil {
+		t.Fatalf("expected the command substitution to run: %v", err)
+	}
+
+	output, results, _ := run("bad")
+	if !strings.Contains(output, "TOKEN ") {
+		t.Fatalf("expected the deploy task to report TOKEN, got: %s", output)
+	}
+	failed := false
+	for _, res := range results {
+		if res.Task != nil && res.Task.Name == "deploy" && res.Err != nil {
+			failed = true
+			if !strings.Contains(res.Err.Error(), "declared by task deploy at "+projectFile) {
+				t.Fatalf("expected the problem to name the castfile, got: %v", res.Err)
+			}
+		}
+	}
+	if !failed {
+		t.Fatalf("expected deploy to fail its required env check, got: %s", output)
+	}
+}
+
+func TestRunTask_RequiredEnvChecksLiteralsMentioningRuntimeKeysUpFront(t *testing.T) {
+	projectDir := t.TempDir()
+	projectFile := filepath.Join(projectDir, "castfile.yaml")
+	marker := filepath.Join(projectDir, "ran")
+
+	content := `
+name: required-env
+tasks:
+  build:
+    uses: bash
+    run: touch "` + marker + `"
+  deploy:
+    uses: bash
+    needs: [build]
+    env:
+      HOST: $(echo web)
+      LABEL: HOST-$HOSTNAME
+    env-required:
+      LABEL:
+        pattern: ^label-
+    run: echo deploy
+`
+	if err := os.WriteFile(projectFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write castfile: %v", err)
+	}
+
+	proj := &projects.Project{}
+	if err := proj.LoadFromYaml(projectFile); err != nil {
+		t.Fatalf("failed to load project: %v", err)
+	}
+
+	var stdout bytes.Buffer
+	_, err := proj.RunTask(projects.RunTasksParams{
+		Targets:     []string{"deploy"},
+		Context:     context.Background(),
+		ContextName: "default",
+		Env:         map[string]string{"HOSTNAME": "box"},
+		Stdout:      &stdout,
+		Stderr:      &stdout,
+	})
+	// LABEL only mentions HOST as text and in $HOSTNAME, so it is known
+	// before the run and checked before any task starts.
+	if err == nil || !strings.Contains(err.Error(), "LABEL") {
+		t.Fatalf("expected an up-front LABEL error, got: %v\nOutput: %s", err, stdout.String())
+	}
+	if _, statErr := os.Stat(marker); statErr == nil {
+		t.Fatalf("expected no task to run when required env is invalid")
+	}
+}
+
+func TestRunTask_RequiredEnvSkipsTasksForOtherPlatforms(t *testing.T) {
+	projectDir := t.TempDir()
+	projectFile := filepath.Join(projectDir, "castfile.yaml")
+
+	otherOS := "windows"
+	if runtime.GOOS == "windows" {
+		otherOS = "linux"
+	}
+
+	content := `
+name: required-env
+tasks:
+  build:
+    uses: bash
+    run: echo built
+  sign:
+    os: ` + otherOS + `
+    env-required: [SIGNING_KEY]
+    run: echo signing
+  release:
+    uses: bash
+    needs: [build, sign]
+    run: echo released
+`
+	if err := os.WriteFile(projectFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write castfile: %v", err)
+	}
+
+	proj := &projects.Project{}
+	if err := proj.LoadFromYaml(projectFile); err != nil {
+		t.Fatalf("failed to load project: %v", err)
+	}
+
+	var stdout bytes.Buffer
+	if _, err := proj.RunTask(projects.RunTasksParams{
+		Targets:     []string{"release"},
+		Context:     context.Background(),
+		ContextName: "default",
+		Stdout:      &stdout,
+		Stderr:      &stdout,
+	}); err != nil {
+		t.Fatalf("expected the skipped task's requirements to be ignored, got: %v", err)
+	}
+
+	output := stdout.String()
+	if !strings.Contains(output, "(skipped: requires os "+otherOS) || !strings.Contains(output, "released") {
+		t.Fatalf("expected sign to be skipped and release to run, got: %s", output)
+	}
+}
@@ -0,0 +1,179 @@
+package projects
+
+import (
+	"cmp"
+	"strconv"
+	"strings"
+
+	"github.com/frostyeti/cast/internal/errors"
+	"github.com/frostyeti/cast/internal/types"
+)
+
+// CastVersion is the version of the running cast binary. It is compared
+// against the `requires` block of castfiles, modules and cast.task files.
+var CastVersion = "0.2.0-alpha.4"
+
+// checkRequires fails when file needs a newer cast release or schema version
+// than the running binary provides.
+func checkRequires(file string, requires *types.Requires) error {
+	if requires == nil {
+		return nil
+	}
+
+	if requires.Schema > types.SchemaVersion {
+		return errors.Newf("%s requires castfile schema %d but cast %s supports schema %d; upgrade with `cast self upgrade`",
+			file, requires.Schema, CastVersion, types.SchemaVersion)
+	}
+
+	if requires.Cast == "" {
+		return nil
+	}
+
+	ok, err := versionSatisfies(CastVersion, requires.Cast)
+	if err != nil {
+		return errors.Newf("%s has an invalid requires cast constraint: %w", file, err)
+	}
+	if !ok {
+		return errors.Newf("%s requires cast %s but this is cast %s; upgrade with `cast self upgrade`",
+			file, requires.Cast, CastVersion)
+	}
+
+	return nil
+}
+
+// checkFileRequires reads only the `requires` block of file and checks it,
+// before the strict decode of the rest of the file can fail on fields added
+// by a newer cast.
+func checkFileRequires(file string) error {
+	requires, err := types.ReadRequires(file)
+	if err != nil {
+		return errors.Newf("%s has an invalid requires block: %w", file, err)
+	}
+
+	return checkRequires(file, requires)
+}
+
+// versionSatisfies reports whether version matches every comma separated
+// clause of constraint. A clause without an operator means at least that
+// version. A pre-release sorts before its release, so 0.9.0-alpha.1 does not
+// satisfy >=0.9.
+func versionSatisfies(version, constraint string) (bool, error) {
+	current, ok := parseConstraintVersion(version)
+	if !ok {
+		return false, errors.Newf("invalid cast version %q", version)
+	}
+	currentPre := versionPrerelease(version)
+
+	for _, clause := range strings.Split(constraint, ",") {
+		clause = strings.TrimSpace(clause)
+		if clause == "" {
+			continue
+		}
+
+		op := ">="
+		for _, candidate := range []string{">=", "<=", "==", ">", "<", "="} {
+			if strings.HasPrefix(clause, candidate) {
+				op = candidate
+				clause = strings.TrimSpace(strings.TrimPrefix(clause, candidate))
+				break
+			}
+		}
+
+		required, ok := parseConstraintVersion(clause)
+		if !ok {
+			return false, errors.Newf("invalid version %q in constraint %q", clause, constraint)
+		}
+
+		cmp := compareVersionParts(current, required)
+		if cmp == 0 {
+			cmp = comparePrerelease(currentPre, versionPrerelease(clause))
+		}
+		var matched bool
+		switch op {
+		case ">=":
+			matched = cmp >= 0
+		case "<=":
+			matched = cmp <= 0
+		case ">":
+			matched = cmp > 0
+		case "<":
+			matched = cmp < 0
+		default:
+			matched = cmp == 0
+		}
+
+		if !matched {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// versionPrerelease returns the pre-release of v, such as `alpha.4` for
+// `0.2.0-alpha.4`, without any build metadata.
+func versionPrerelease(v string) string {
+	v, _, _ = strings.Cut(strings.TrimSpace(v), "+")
+	_, pre, _ := strings.Cut(v, "-")
+	return pre
+}
+
+// comparePrerelease orders pre-releases by semver precedence: a release
+// sorts after any pre-release, numeric identifiers compare numerically and
+// sort before alphanumeric ones, and a shorter list of equal identifiers
+// sorts first.
+func comparePrerelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+
+	left, right := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(left) && i < len(right); i++ {
+		l, lErr := strconv.Atoi(left[i])
+		r, rErr := strconv.Atoi(right[i])
+		switch {
+		case lErr == nil && rErr == nil:
+			if l != r {
+				return cmp.Compare(l, r)
+			}
+		case lErr == nil:
+			return -1
+		case rErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(left[i], right[i]); c != 0 {
+				return c
+			}
+		}
+	}
+
+	return cmp.Compare(len(left), len(right))
+}
+
+func parseConstraintVersion(v string) ([3]int, bool) {
+	v = trimVersion(v)
+	if !strings.Contains(v, ".") {
+		v += ".0"
+	}
+
+	major, minor, patch, ok := parseVersionParts(v)
+	return [3]int{major, minor, patch}, ok
+}
+
+func compareVersionParts(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,123 @@
+package projects
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVersionSatisfies(t *testing.T) {
+	cases := []struct {
+		version    string
+		constraint string
+		want       bool
+	}{
+		{"0.9.0", ">=0.9", true},
+		{"0.8.4", ">=0.9", false},
+		{"1.2.0", "1.1", true},
+		{"v1.2.0", ">=1.0, <2", true},
+		{"2.0.0", ">=1.0, <2", false},
+		{"0.9.0-alpha.1", ">=0.9", false},
+		{"0.9.0-alpha.1", ">=0.9.0-alpha", true},
+		{"0.9.0-alpha.10", ">0.9.0-alpha.9", true},
+		{"0.9.0-alpha.1", "<0.9.0", true},
+		{"0.9.0-rc.1", ">=0.8", true},
+		{"1.0.0", "=1", true},
+		{"1.0.1", "==1.0.0", false},
+		{"1.0.1", ">1.0.0", true},
+		{"1.0.0", "<=0.9.9", false},
+	}
+
+	for _, tc := range cases {
+		got, err := versionSatisfies(tc.version, tc.constraint)
+		if err != nil {
+			t.Fatalf("versionSatisfies(%q, %q) returned error: %v", tc.version, tc.constraint, err)
+		}
+		if got != tc.want {
+			t.Errorf("versionSatisfies(%q, %q) = %v; want %v", tc.version, tc.constraint, got, tc.want)
+		}
+	}
+
+	if _, err := versionSatisfies("1.0.0", ">=latest"); err == nil {
+		t.Fatalf("expected an invalid constraint error")
+	}
+}
+
+func TestLoadFromYaml_RejectsNewerRequirements(t *testing.T) {
+	oldVersion := CastVersion
+	CastVersion = "0.8.0"
+	t.Cleanup(func() { CastVersion = oldVersion })
+
+	cases := map[string]string{
+		"requires: \">=0.9\"\n":               "requires cast >=0.9 but this is cast 0.8.0",
+		"requires:\n  cast: \">=0.7, <1\"\n":  "",
+		"requires:\n  schema: 99\n":           "requires castfile schema 99",
+		"requires:\n  cast: \">=0.8.1\"\n":    "upgrade with `cast self upgrade`",
+		"requires:\n  cast: \"at least 1\"\n": "invalid requires cast constraint",
+		"requires:\n  cast: \">=0.9\"\ntasks:\n  build:\n    run: echo build\n    retry: 3\n": "requires cast >=0.9 but this is cast 0.8.0",
+	}
+
+	for content, want := range cases {
+		file := filepath.Join(t.TempDir(), "castfile.yaml")
+		if err := os.WriteFile(file, []byte("name: requires\n"+content), 0o644); err != nil {
+			t.Fatalf("failed to write castfile: %v", err)
+		}
+
+		p := &Project{}
+		err := p.LoadFromYaml(file)
+		if want == "" {
+			if err != nil {
+				t.Fatalf("expected %q to load, got: %v", content, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q in error for %q, got: %v", want, content, err)
+		}
+	}
+}
+
+func TestInit_RejectsModuleWithNewerRequirements(t *testing.T) {
+	oldVersion := CastVersion
+	CastVersion = "0.8.0"
+	t.Cleanup(func() { CastVersion = oldVersion })
+
+	dir := t.TempDir()
+	// the module uses a field this cast does not know; the requirement must
+	// still be reported instead of the unknown field.
+	if err := os.WriteFile(filepath.Join(dir, "shared.yaml"), []byte("requires:\n  cast: \">=1.0\"\ntasks:\n  lint:\n    run: echo lint\n    retry: 3\n"), 0o644); err != nil {
+		t.Fatalf("failed to write module: %v", err)
+	}
+	file := filepath.Join(dir, "castfile.yaml")
+	if err := os.WriteFile(file, []byte("name: requires\nimports:\n  - ./shared.yaml\n"), 0o644); err != nil {
+		t.Fatalf("failed to write castfile: %v", err)
+	}
+
+	p := &Project{}
+	if err := p.LoadFromYaml(file); err != nil {
+		t.Fatalf("failed to load project: %v", err)
+	}
+
+	err := p.Init()
+	if err == nil || !strings.Contains(err.Error(), "shared.yaml requires cast >=1.0") {
+		t.Fatalf("expected module requirement error, got: %v", err)
+	}
+}
+
+func TestValidateCastTask_ReportsRequirementsBeforeUnknownFields(t *testing.T) {
+	oldVersion := CastVersion
+	CastVersion = "0.8.0"
+	t.Cleanup(func() { CastVersion = oldVersion })
+
+	dir := t.TempDir()
+	content := "name: future\nrequires: \">=2.0\"\nruns:\n  using: composite\n  retry: 3\n  steps:\n    - run: echo hi\n"
+	if err := os.WriteFile(filepath.Join(dir, "cast.task"), []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write cast.task: %v", err)
+	}
+
+	_, _, err := ValidateCastTask(dir)
+	if err == nil || !strings.Contains(err.Error(), "requires cast >=2.0 but this is cast 0.8.0") {
+		t.Fatalf("expected the requirement error, got: %v", err)
+	}
+}
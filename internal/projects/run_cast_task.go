@@ -39,6 +39,9 @@ func runCastTask(ctx TaskContext, casttaskPath string) *TaskResult {
 	res := NewTaskResult()
 	res.Start()
 
+	if err := checkFileRequires(casttaskPath); err != nil {
+		return res.Fail(err)
+	}
 	var def types.CastTask
 	if err := def.ReadFromYaml(casttaskPath); err != nil {
 		return res.Fail(err)
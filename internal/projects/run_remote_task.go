@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/frostyeti/cast/internal/errors"
+	"github.com/frostyeti/cast/internal/offline"
 	"github.com/frostyeti/go/exec"
 )
 
@@ -83,6 +84,10 @@ func runDenoWrapper(ctx TaskContext, modulePath string, jsRuntime string) *TaskR
 	}()
 
 	args := []string{"run", "-A"}
+	if offline.Enabled() {
+		// jsr: and npm: modules must already be in the deno cache.
+		args = append(args, "--cached-only")
+	}
 	exeName := "deno"
 
 	if jsRuntime == "bun" {
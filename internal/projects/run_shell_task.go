@@ -74,6 +74,7 @@ func runShell(ctx TaskContext) *TaskResult {
 
 		data := map[string]any{
 			"env":  ctx.Task.Env,
+			"vars": ctx.Task.Vars,
 			"os":   runtime.GOOS,
 			"arch": runtime.GOARCH,
 		}
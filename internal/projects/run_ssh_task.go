@@ -286,6 +286,7 @@ func runSSHTarget(ctx context.Context, taskContext TaskContext, target HostInfo)
 		envMap := taskContext.Task.Env
 		data := map[string]interface{}{
 			"env":    envMap,
+			"vars":   taskContext.Task.Vars,
 			"target": target,
 			"os":     runtime.GOOS,
 			"arch":   runtime.GOARCH,
@@ -0,0 +1,131 @@
+package projects
+
+import (
+	"crypto/ed25519"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/frostyeti/cast/internal/archive"
+	"github.com/frostyeti/cast/internal/errors"
+	"github.com/frostyeti/cast/internal/integrity"
+)
+
+const (
+	// CastSigningKeyEnv names the private key file `cast task sign` uses when
+	// --key is not passed.
+	CastSigningKeyEnv = "CAST_SIGNING_KEY"
+	// CastSigningKeyPassphraseEnv decrypts a passphrase protected signing key.
+	CastSigningKeyPassphraseEnv = "CAST_SIGNING_KEY_PASSPHRASE"
+)
+
+// signatureSuffix is appended to a tarball name for its detached signature.
+const signatureSuffix = ".sig"
+
+// LoadSigningKey reads the ed25519 private key at path, falling back to
+// CAST_SIGNING_KEY when path is empty.
+func LoadSigningKey(path string) (ed25519.PrivateKey, error) {
+	if path == "" {
+		path = strings.TrimSpace(os.Getenv(CastSigningKeyEnv))
+	}
+	if path == "" {
+		return nil, errors.Newf("no signing key given; pass --key or set %s", CastSigningKeyEnv)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := integrity.ParsePrivateKey(data, []byte(os.Getenv(CastSigningKeyPassphraseEnv)))
+	if err != nil {
+		return nil, errors.Newf("failed to read signing key %s: %w", path, err)
+	}
+
+	return key, nil
+}
+
+// SignCastTask signs target with key and returns the signature file it
+// wrote. A directory gets a cast.sig at its root, to commit and tag with the
+// spell; a packed tarball gets a detached `<tarball>.sig` for the registry.
+// Signatures by other keys over the same content are kept.
+func SignCastTask(target string, key ed25519.PrivateKey) (string, error) {
+	info, err := os.Stat(target)
+	if err != nil {
+		return "", err
+	}
+
+	if info.IsDir() {
+		sigPath := filepath.Join(target, integrity.SignatureFile)
+		return sigPath, signTreeTo(target, sigPath, key)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "cast-sign-*")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = os.RemoveAll(tmpDir)
+	}()
+
+	if err := archive.Download(target, tmpDir, ""); err != nil {
+		return "", errors.Newf("failed to read %s: %w", target, err)
+	}
+
+	sigPath := target + signatureSuffix
+	return sigPath, signTreeTo(tmpDir, sigPath, key)
+}
+
+func signTreeTo(dir, sigPath string, key ed25519.PrivateKey) error {
+	existing, err := integrity.ReadSignature(sigPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+		existing = nil
+	}
+
+	sig, err := integrity.SignTree(dir, key, existing)
+	if err != nil {
+		return err
+	}
+
+	return integrity.WriteSignature(sigPath, sig)
+}
+
+// signingKeys returns the keys of every signed trusted_sources entry whose
+// pattern matches uses. uses needs no signature when the result is empty.
+func signingKeys(p *Project, uses string) ([]ed25519.PublicKey, error) {
+	keys := []ed25519.PublicKey{}
+	for _, source := range p.Schema.SignedSources {
+		if !matchesTrustedSource(uses, source.Source) {
+			continue
+		}
+
+		for _, value := range source.Keys {
+			key, err := integrity.ParsePublicKey(value)
+			if err != nil {
+				return nil, errors.Newf("trusted_sources entry %s: %w", source.Source, err)
+			}
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+// verifySignature checks that dir, the fetched tree of the remote ref uses,
+// carries a cast.sig made by a key its trusted_sources entry lists. kind
+// names what was fetched, such as "task" or "module".
+func verifySignature(p *Project, kind, uses, dir string) error {
+	keys, err := signingKeys(p, uses)
+	if err != nil || len(keys) == 0 {
+		return err
+	}
+
+	if err := integrity.VerifyTree(dir, keys); err != nil {
+		return errors.Newf("remote %s '%s' failed signature verification: %w", kind, uses, err)
+	}
+
+	return nil
+}
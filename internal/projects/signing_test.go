@@ -0,0 +1,132 @@
+package projects
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/frostyeti/cast/internal/integrity"
+	"github.com/frostyeti/cast/internal/types"
+	"golang.org/x/crypto/ssh"
+)
+
+func newTestSigningKey(t *testing.T) (ed25519.PrivateKey, string) {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	return key, integrity.FormatPublicKey(key.Public().(ed25519.PublicKey))
+}
+
+func TestFetchRemoteTask_RequiresSignatureFromTrustedKey(t *testing.T) {
+	repoDir, projectDir, projectFile := setupLockfileRemote(t)
+	key, public := newTestSigningKey(t)
+
+	p := newLockfileProject(projectDir, projectFile, LockAuto)
+	p.Schema.SignedSources = []types.TrustedSource{{Source: "file://", Keys: []string{public}}}
+
+	unsigned := "file://" + filepath.ToSlash(repoDir) + "@v1.0.0/hello"
+	_, err := FetchRemoteTask(p, unsigned, nil, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "failed signature verification: no cast.sig found") {
+		t.Fatalf("expected an unsigned spell to be refused, got: %v", err)
+	}
+	plan, err := planRemoteGitTask(p, unsigned)
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if _, err := os.Stat(plan.layout.repoDir); !os.IsNotExist(err) {
+		t.Fatalf("expected the unverified checkout to be removed from the cache, got: %v", err)
+	}
+
+	if _, err := SignCastTask(filepath.Join(repoDir, "hello"), key); err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "sign")
+	runGit(t, repoDir, "tag", "v1.1.0")
+
+	signed := "file://" + filepath.ToSlash(repoDir) + "@v1.1.0/hello"
+	if _, err := FetchRemoteTask(p, signed, nil, io.Discard); err != nil {
+		t.Fatalf("expected the signed spell to load: %v", err)
+	}
+
+	_, other := newTestSigningKey(t)
+	p.Schema.SignedSources = []types.TrustedSource{{Source: "file://", Keys: []string{other}}}
+	if _, err := FetchRemoteTask(p, signed, nil, io.Discard); err == nil || !strings.Contains(err.Error(), "not signed by a trusted key") {
+		t.Fatalf("expected a cached spell to be verified against the configured keys, got: %v", err)
+	}
+
+	p.Schema.SignedSources = []types.TrustedSource{{Source: "jsr:", Keys: []string{public}}}
+	if _, err := FetchRemoteTask(p, "jsr:@std/hello", nil, io.Discard); err == nil || !strings.Contains(err.Error(), "cannot carry a cast.sig") {
+		t.Fatalf("expected signed jsr: sources to be refused, got: %v", err)
+	}
+}
+
+func TestFetchRemoteTask_VerifiesSignedRegistryTarballs(t *testing.T) {
+	key, public := newTestSigningKey(t)
+	dir := writeSpellDir(t, packedCompositeTask, map[string]string{"scripts/greet.sh": "echo hello\n"})
+
+	packed, err := PackCastTask(dir, PackOptions{Version: "1.0.0", OutDir: t.TempDir(), Key: key})
+	if err != nil {
+		t.Fatalf("failed to pack: %v", err)
+	}
+	if packed.Signature != packed.Tarball+".sig" {
+		t.Fatalf("expected a detached signature, got %q", packed.Signature)
+	}
+
+	registryDir := filepath.Join(t.TempDir(), "registry")
+	published, err := PublishSpell(registryDir, packed.Tarball, PublishOptions{})
+	if err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+	if published.Release.Signature != "greet/greet-1.0.0.tar.gz.sig" {
+		t.Fatalf("expected the signature in the index, got %+v", published.Release)
+	}
+
+	_, other := newTestSigningKey(t)
+	p := newRegistryProject(t, registryDir)
+	p.Schema.SignedSources = []types.TrustedSource{{Source: "reg:", Keys: []string{other}}}
+	if _, err := FetchRemoteTask(p, "reg:greet@1", nil, io.Discard); err == nil || !strings.Contains(err.Error(), "not signed by a trusted key") {
+		t.Fatalf("expected a tarball signed by another key to be refused, got: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(registrySpellDir(p.Dir, registryDir, "greet"), "1.0.0")); !os.IsNotExist(err) {
+		t.Fatalf("expected the refused tarball to stay out of the cache, got: %v", err)
+	}
+
+	p.Schema.SignedSources = []types.TrustedSource{{Source: "reg:", Keys: []string{other, public}}}
+	if _, err := FetchRemoteTask(p, "reg:greet@1", nil, io.Discard); err != nil {
+		t.Fatalf("expected the signed tarball to load: %v", err)
+	}
+}
+
+func TestLoadSigningKeyReadsOpenSSHKeys(t *testing.T) {
+	key, _ := newTestSigningKey(t)
+	block, err := ssh.MarshalPrivateKey(key, "")
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	keyFile := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+
+	t.Setenv(CastSigningKeyEnv, "")
+	if _, err := LoadSigningKey(""); err == nil || !strings.Contains(err.Error(), CastSigningKeyEnv) {
+		t.Fatalf("expected a missing key error, got: %v", err)
+	}
+
+	t.Setenv(CastSigningKeyEnv, keyFile)
+	loaded, err := LoadSigningKey("")
+	if err != nil || !loaded.Equal(key) {
+		t.Fatalf("expected the key from %s: %v", CastSigningKeyEnv, err)
+	}
+}
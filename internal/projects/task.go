@@ -9,6 +9,7 @@ type Task struct {
 	Uses     string
 	Run      string
 	Env      map[string]string
+	Vars     map[string]any
 	With     map[string]any
 	If       bool
 	Cwd      string
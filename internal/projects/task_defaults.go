@@ -0,0 +1,86 @@
+package projects
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/frostyeti/cast/internal/types"
+)
+
+// applyTaskDefaults fills the fields a project task leaves unset, first from
+// the defaults of the handler the task uses and then from the project-wide
+// task defaults. Default hosts are skipped when the active context provides
+// hosts so the context keeps precedence.
+func (p *Project) applyTaskDefaults(task *types.Task) {
+	defaults := p.Schema.Defaults
+	if defaults == nil {
+		return
+	}
+
+	uses := ""
+	if task.Uses != nil {
+		uses = strings.TrimSpace(*task.Uses)
+	}
+	if uses == "" && defaults.Uses != nil {
+		uses = strings.TrimSpace(*defaults.Uses)
+	}
+	if uses == "" {
+		uses = resolveDefaultTaskUses(p.Schema.Config)
+	}
+
+	// defaults are written in the project file, so the relative paths they
+	// add are rebased for tasks defined in included files.
+	rebase := task.File != "" && filepath.Dir(task.File) != p.Dir
+
+	contextHosts := len(p.contextHosts()) > 0
+	layers := []types.TaskDefaults{}
+	if handler, ok := defaults.Handlers[uses]; ok {
+		layers = append(layers, handler)
+	}
+	layers = append(layers, defaults.TaskDefaults)
+
+	for _, d := range layers {
+		if contextHosts {
+			d.Hosts = nil
+		}
+		if rebase {
+			d = rebaseDefaultPaths(d, p.Dir)
+		}
+		d.Apply(task)
+	}
+}
+
+// rebaseDefaultPaths makes the relative cwd and dotenv paths of d absolute
+// against dir. Paths with templates or variables are left alone because they
+// are only known when the task runs.
+func rebaseDefaultPaths(d types.TaskDefaults, dir string) types.TaskDefaults {
+	rebase := func(path string) string {
+		if path == "" || filepath.IsAbs(path) || strings.ContainsAny(path, "{$~") {
+			return path
+		}
+		return filepath.Join(dir, path)
+	}
+
+	if d.Cwd != nil {
+		cwd := rebase(*d.Cwd)
+		d.Cwd = &cwd
+	}
+
+	if len(d.DotEnv) > 0 {
+		dotenv := make([]string, 0, len(d.DotEnv))
+		for _, file := range d.DotEnv {
+			switch {
+			case strings.HasPrefix(file, "?"):
+				file = "?" + rebase(file[1:])
+			case strings.HasSuffix(file, "?"):
+				file = rebase(file[:len(file)-1]) + "?"
+			default:
+				file = rebase(file)
+			}
+			dotenv = append(dotenv, file)
+		}
+		d.DotEnv = dotenv
+	}
+
+	return d
+}
@@ -0,0 +1,222 @@
+package projects_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/frostyeti/cast/internal/projects"
+	"github.com/frostyeti/cast/internal/runstatus"
+)
+
+func TestRunTask_AppliesProjectAndHandlerDefaults(t *testing.T) {
+	projectDir := t.TempDir()
+	projectFile := filepath.Join(projectDir, "castfile.yaml")
+
+	for _, dir := range []string{"default-dir", "base-dir"} {
+		if err := os.Mkdir(filepath.Join(projectDir, dir), 0o755); err != nil {
+			t.Fatalf("failed to create %s: %v", dir, err)
+		}
+	}
+
+	content := `
+name: defaults-demo
+defaults:
+  uses: bash
+  cwd: default-dir
+  timeout: 1m
+  env:
+    LEVEL: project
+    SHARED: project
+  handlers:
+    bash:
+      template: gotmpl
+      env:
+        LEVEL: handler
+      with:
+        greeting: hello
+tasks:
+  show:
+    env:
+      SHARED: task
+    run: echo "show {{ .env.LEVEL }} $SHARED $(basename $PWD)"
+  base:
+    cwd: base-dir
+    run: echo "base $(basename $PWD)"
+  child:
+    extends: base
+    run: echo "child $LEVEL $(basename $PWD)"
+`
+
+	if err := os.WriteFile(projectFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write castfile: %v", err)
+	}
+
+	proj := &projects.Project{}
+	if err := proj.LoadFromYaml(projectFile); err != nil {
+		t.Fatalf("failed to load project: %v", err)
+	}
+
+	var stdout bytes.Buffer
+	_, err := proj.RunTask(projects.RunTasksParams{
+		Targets:     []string{"show", "child"},
+		Context:     context.Background(),
+		ContextName: "default",
+		Stdout:      &stdout,
+		Stderr:      &stdout,
+	})
+	if err != nil {
+		t.Fatalf("failed to run tasks: %v\nOutput: %s", err, stdout.String())
+	}
+
+	output := stdout.String()
+	for _, want := range []string{"show handler task default-dir", "child handler default-dir"} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("expected %q in output, got: %s", want, output)
+		}
+	}
+
+	task, ok := proj.Tasks.Get("show")
+	if !ok {
+		t.Fatalf("expected show task")
+	}
+	if task.Timeout == nil || *task.Timeout != "1m" {
+		t.Fatalf("expected default timeout, got %v", task.Timeout)
+	}
+	if greeting, _ := task.With.Get("greeting"); greeting != "hello" {
+		t.Fatalf("expected handler with default, got %v", greeting)
+	}
+}
+
+func TestInit_AppliesDefaultsBeforeExtends(t *testing.T) {
+	projectDir := t.TempDir()
+	projectFile := filepath.Join(projectDir, "castfile.yaml")
+
+	content := `
+name: defaults-extends
+defaults:
+  timeout: 1m
+  retry: 2
+  env:
+    LEVEL: project
+  handlers:
+    docker:
+      with:
+        volumes: cache
+tasks:
+  base:
+    uses: docker
+    timeout: 5m
+    retry: 0
+    env:
+      LEVEL: base
+    run: echo base
+  child:
+    extends: base
+    run: echo child
+`
+
+	if err := os.WriteFile(projectFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write castfile: %v", err)
+	}
+
+	proj := &projects.Project{}
+	if err := proj.LoadFromYaml(projectFile); err != nil {
+		t.Fatalf("failed to load project: %v", err)
+	}
+	if err := proj.Init(); err != nil {
+		t.Fatalf("failed to init project: %v", err)
+	}
+
+	// the child takes the project defaults as its own values before it
+	// extends base, and the docker handler default set on base reaches the
+	// child through the merge.
+	task, ok := proj.Tasks.Get("child")
+	if !ok {
+		t.Fatalf("expected child task")
+	}
+	if task.Timeout == nil || *task.Timeout != "1m" {
+		t.Fatalf("expected the default timeout, got %v", task.Timeout)
+	}
+	if task.Retry == nil || *task.Retry != 2 {
+		t.Fatalf("expected the default retry, got %v", task.Retry)
+	}
+	if level := task.Env.Get("LEVEL"); level != "project" {
+		t.Fatalf("expected the default LEVEL, got %q", level)
+	}
+	if task.Uses == nil || *task.Uses != "docker" {
+		t.Fatalf("expected uses inherited from base, got %v", task.Uses)
+	}
+	if volumes, _ := task.With.Get("volumes"); volumes != "cache" {
+		t.Fatalf("expected the docker handler default from base, got %v", volumes)
+	}
+}
+
+func TestRunTask_RetriesFailedTask(t *testing.T) {
+	projectDir := t.TempDir()
+	projectFile := filepath.Join(projectDir, "castfile.yaml")
+
+	content := `
+name: retry-demo
+defaults:
+  retry: 2
+tasks:
+  flaky:
+    uses: bash
+    run: |
+      n=$(cat count 2>/dev/null || echo 0)
+      n=$((n + 1))
+      echo "$n" > count
+      [ "$n" -ge 3 ]
+  broken:
+    uses: bash
+    retry: 1
+    run: |
+      echo attempt >> attempts
+      exit 1
+`
+
+	if err := os.WriteFile(projectFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write castfile: %v", err)
+	}
+
+	proj := &projects.Project{}
+	if err := proj.LoadFromYaml(projectFile); err != nil {
+		t.Fatalf("failed to load project: %v", err)
+	}
+
+	var stdout bytes.Buffer
+	run := func(target string) int {
+		results, err := proj.RunTask(projects.RunTasksParams{
+			Targets:     []string{target},
+			Context:     context.Background(),
+			ContextName: "default",
+			Stdout:      &stdout,
+			Stderr:      &stdout,
+		})
+		if err != nil || len(results) != 1 {
+			t.Fatalf("failed to run %s: %v\nOutput: %s", target, err, stdout.String())
+		}
+		return results[0].Status
+	}
+
+	if status := run("flaky"); status != runstatus.Ok {
+		t.Fatalf("expected flaky to pass on its third attempt, got %v\nOutput: %s", status, stdout.String())
+	}
+	if count, _ := os.ReadFile(filepath.Join(projectDir, "count")); strings.TrimSpace(string(count)) != "3" {
+		t.Fatalf("expected 3 attempts, got %q", count)
+	}
+
+	if status := run("broken"); status != runstatus.Error {
+		t.Fatalf("expected broken to fail after its retry\nOutput: %s", stdout.String())
+	}
+	if attempts, _ := os.ReadFile(filepath.Join(projectDir, "attempts")); strings.Count(string(attempts), "attempt") != 2 {
+		t.Fatalf("expected 2 attempts, got %q", attempts)
+	}
+	if !strings.Contains(stdout.String(), "(retry 1/1)") {
+		t.Fatalf("expected retry progress in output, got: %s", stdout.String())
+	}
+}
@@ -1,12 +1,38 @@
 package projects
 
-import "github.com/frostyeti/cast/internal/types"
+import (
+	"github.com/frostyeti/cast/internal/errors"
+	"github.com/frostyeti/cast/internal/types"
+)
 
+// ListTasks returns the tasks that can be invoked directly. Private tasks are
+// left out.
 func (p *Project) ListTasks() (types.TaskMap, error) {
 	err := p.Init()
 	if err != nil {
 		return types.TaskMap{}, err
 	}
 
-	return p.Tasks, nil
+	tasks := types.NewTaskMap()
+	for _, task := range p.Tasks.Values() {
+		if task.Private {
+			continue
+		}
+		tasks.Add(&task)
+	}
+
+	return *tasks, nil
+}
+
+// CheckPublicTargets returns an error when a target resolves to a private
+// task. Private tasks may only run as needs, hooks or job steps, so the CLI
+// checks targets before running them.
+func (p *Project) CheckPublicTargets(targets []string, contextName string) error {
+	for _, target := range targets {
+		if task, ok := p.Tasks.Resolve(target, contextName); ok && task.Private {
+			return errors.Newf("task %s is private and can only run as a dependency, hook or job step", task.Name)
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,70 @@
+package projects_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/frostyeti/cast/internal/projects"
+)
+
+func TestPrivateTasksAreHiddenButRunAsNeeds(t *testing.T) {
+	projectDir := t.TempDir()
+	projectFile := filepath.Join(projectDir, "castfile.yaml")
+	content := `
+name: private-demo
+tasks:
+  setup:
+    private: true
+    uses: bash
+    run: echo setup-ran
+  build:
+    aliases: [b]
+    needs: [setup]
+    uses: bash
+    run: echo build-ran
+`
+	if err := os.WriteFile(projectFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write castfile: %v", err)
+	}
+
+	proj := &projects.Project{}
+	if err := proj.LoadFromYaml(projectFile); err != nil {
+		t.Fatalf("failed to load project: %v", err)
+	}
+
+	listed, err := proj.ListTasks()
+	if err != nil {
+		t.Fatalf("failed to list tasks: %v", err)
+	}
+	if keys := listed.Keys(); len(keys) != 1 || keys[0] != "build" {
+		t.Fatalf("expected only build to be listed, got %v", keys)
+	}
+
+	if err := proj.CheckPublicTargets([]string{"setup"}, "default"); err == nil {
+		t.Fatalf("expected private target to be rejected")
+	}
+	if err := proj.CheckPublicTargets([]string{"b"}, "default"); err != nil {
+		t.Fatalf("expected alias of public task to be accepted: %v", err)
+	}
+
+	var stdout bytes.Buffer
+	_, err = proj.RunTask(projects.RunTasksParams{
+		Targets:     []string{"b"},
+		Context:     context.Background(),
+		ContextName: "default",
+		Stdout:      &stdout,
+		Stderr:      &stdout,
+	})
+	if err != nil {
+		t.Fatalf("failed to run tasks: %v\nOutput: %s", err, stdout.String())
+	}
+
+	output := stdout.String()
+	if !strings.Contains(output, "setup-ran") || !strings.Contains(output, "build-ran") {
+		t.Fatalf("expected private need and aliased task to run, got: %s", output)
+	}
+}
@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"html/template"
 	"io"
+	"maps"
 	"os"
 	"path/filepath"
+	"runtime"
 	"slices"
 	"strings"
 	"time"
@@ -29,8 +31,13 @@ type RunTasksParams struct {
 	Args        []string
 	ProjectName string
 	Env         map[string]string
+	Vars        map[string]any
 	Stdout      io.Writer
 	Stderr      io.Writer
+
+	// requiredEnv holds the env-required declarations of the job running
+	// the tasks.
+	requiredEnv []requiredEnvScope
 }
 
 func findFallbackTask(uses string, projectDir string) (string, bool) {
@@ -126,6 +133,19 @@ func (p *Project) RunTask(params RunTasksParams) ([]*TaskResult, error) {
 		return nil, err
 	}
 
+	// tasks skipped on this platform never run, so their requirements
+	// cannot fail the run.
+	runnable := make([]types.Task, 0, len(taskList))
+	for _, task := range taskList {
+		if task.SupportsPlatform(runtime.GOOS, runtime.GOARCH) {
+			runnable = append(runnable, task)
+		}
+	}
+
+	if err := p.checkRequiredEnv(projectEnv, runnable, params.requiredEnv...); err != nil {
+		return nil, err
+	}
+
 	castEnv := projectEnv.Get("CAST_ENV")
 	castPath := projectEnv.Get("CAST_PATH")
 	castOutputs := projectEnv.Get("CAST_OUTPUTS")
@@ -155,6 +175,14 @@ func (p *Project) RunTask(params RunTasksParams) ([]*TaskResult, error) {
 
 	globalOutputs := map[string]interface{}{}
 
+	baseVars := map[string]any{}
+	if projectVars, ok := p.Scope.Get("vars"); ok {
+		if values, ok := projectVars.(map[string]any); ok {
+			maps.Copy(baseVars, values)
+		}
+	}
+	maps.Copy(baseVars, params.Vars)
+
 	hasFailed := false
 
 	for _, task := range taskList {
@@ -173,7 +201,11 @@ func (p *Project) RunTask(params RunTasksParams) ([]*TaskResult, error) {
 
 		hosts := []HostInfo{}
 		hostNames := []string{}
-		for _, hostId := range task.Hosts {
+		hostIds := task.Hosts
+		if len(hostIds) == 0 {
+			hostIds = p.contextHosts()
+		}
+		for _, hostId := range hostIds {
 			host, ok := p.Hosts[hostId]
 			if ok {
 				hosts = append(hosts, host)
@@ -211,6 +243,13 @@ func (p *Project) RunTask(params RunTasksParams) ([]*TaskResult, error) {
 			outWriter = os.Stdout
 		}
 
+		if !task.SupportsPlatform(runtime.GOOS, runtime.GOARCH) {
+			res.Status = runstatus.Skipped
+			results = append(results, res)
+			_, _ = fmt.Fprintf(outWriter, "\x1b[1m%s\x1b[22m (skipped: requires %s, running on %s/%s)\n", name, task.PlatformRequirement(), runtime.GOOS, runtime.GOARCH)
+			continue
+		}
+
 		if len(task.DotEnv) > 0 {
 			for _, envFile := range task.DotEnv {
 				optional := false
@@ -223,7 +262,7 @@ func (p *Project) RunTask(params RunTasksParams) ([]*TaskResult, error) {
 				}
 
 				if !filepath.IsAbs(envFile) {
-					absPath, err := paths.ResolvePath(p.Dir, envFile)
+					absPath, err := paths.ResolvePath(p.taskDir(task), envFile)
 					if err != nil {
 						_, _ = fmt.Fprintf(outWriter, "\n\x1b[1m%s\x1b[22m \x1b[31m(failed)\x1b[0m\n", name)
 						err = errors.Newf("failed to resolve dotenv file %s for task %s: %w", envFile, task.Name, err)
@@ -334,6 +373,9 @@ func (p *Project) RunTask(params RunTasksParams) ([]*TaskResult, error) {
 		m.Hosts = hosts
 		m.Args = params.Args
 		m.Cwd = ""
+		if task.Cwd != nil {
+			m.Cwd = *task.Cwd
+		}
 		m.Template = ""
 		if task.Template != nil {
 			m.Template = *task.Template
@@ -345,6 +387,19 @@ func (p *Project) RunTask(params RunTasksParams) ([]*TaskResult, error) {
 		scope.Set("args", m.Args)
 		scope.Set("success", !hasFailed)
 
+		vars, err := resolveVars(task.Vars, scope.ToMap(), baseVars)
+		if err != nil {
+			err := errors.Newf("failed to evaluate vars for task %s: %w", task.Name, err)
+			_, _ = fmt.Fprintf(outWriter, "\n\x1b[1m%s\x1b[22m \x1b[31m(failed)\x1b[0m\n", name)
+			_, _ = fmt.Fprintf(outWriter, "\x1b[31m%v\x1b[0m\n", err)
+			res.Fail(err)
+			hasFailed = true
+			results = append(results, res)
+			continue
+		}
+		scope.Set("vars", vars)
+		m.Vars = vars
+
 		for k, v := range globalOutputs {
 			// if string, ok := v.(string); ok {
 			if str, ok := v.(string); ok {
@@ -458,8 +513,12 @@ func (p *Project) RunTask(params RunTasksParams) ([]*TaskResult, error) {
 			m.Cwd = cwd
 		}
 
+		// a relative cwd is resolved against the file that defines the task,
+		// so module and included tasks keep their own layout.
 		if m.Cwd == "" {
 			m.Cwd = p.Dir
+		} else if !filepath.IsAbs(m.Cwd) {
+			m.Cwd = filepath.Join(p.taskDir(task), m.Cwd)
 		}
 
 		if task.Timeout != nil {
@@ -495,6 +554,17 @@ func (p *Project) RunTask(params RunTasksParams) ([]*TaskResult, error) {
 			continue
 		}
 
+		// values from outputs, command substitutions and generated dotenv
+		// files are only known now, so requirements are checked again.
+		if err := p.checkTaskRequiredEnv(task, m.Env, params.requiredEnv...); err != nil {
+			_, _ = fmt.Fprintf(outWriter, "\n\x1b[1m%s\x1b[22m \x1b[31m(failed)\x1b[0m\n", name)
+			_, _ = fmt.Fprintf(outWriter, "\x1b[31m%v\x1b[0m\n", err)
+			res.Fail(err)
+			hasFailed = true
+			results = append(results, res)
+			continue
+		}
+
 		handler, ok := GetTaskHandler(uses)
 		if !ok {
 			if IsRemoteTask(uses) {
@@ -512,60 +582,24 @@ func (p *Project) RunTask(params RunTasksParams) ([]*TaskResult, error) {
 			}
 		}
 
-		var taskCtx context.Context
-		var cancel context.CancelFunc
-		if timeout > 0 {
-			taskCtx, cancel = context.WithTimeout(params.Context, timeout)
-		} else {
-			taskCtx, cancel = context.WithCancel(params.Context)
-		}
-
-		ctx := &TaskContext{
-			Project:     p,
-			Context:     taskCtx,
-			Schema:      &task,
-			Task:        m,
-			Args:        task.Args,
-			ContextName: params.ContextName,
-			Outputs:     globalOutputs,
-			Stdout:      params.Stdout,
-			Stderr:      params.Stderr,
-		}
-
-		if ctx.Stdout == nil {
-			ctx.Stdout = os.Stdout
-		}
-		if ctx.Stderr == nil {
-			ctx.Stderr = os.Stderr
-		}
-
 		_, _ = fmt.Fprintf(outWriter, "\n\x1b[1m%s\x1b[22m\n", name)
 
-		// Run handler in a goroutine to support timeout
-		resultChan := make(chan *TaskResult, 1)
-		go func() {
-			result := handler(*ctx)
-			resultChan <- result
-		}()
+		// a failed or timed out task runs again up to retry more times,
+		// unless the whole run was cancelled.
+		retries := 0
+		if task.Retry != nil {
+			retries = *task.Retry
+		}
 
 		var r2 *TaskResult
-		if timeout > 0 {
-			select {
-			case r2 = <-resultChan:
-				// Task completed before timeout
-			case <-time.After(timeout):
-				// Task timed out
-				cancel()
-				r2 = NewTaskResult()
-				r2.Status = runstatus.Cancelled
-				r2.Err = errors.Newf("task %s timed out after %s", task.Name, timeout)
-				_, _ = fmt.Fprintf(outWriter, "\x1b[33m%s (timed out after %s)\x1b[0m\n", name, timeout)
+		for attempt := 0; ; attempt++ {
+			r2 = runTaskAttempt(params, p, &task, m, handler, timeout, globalOutputs, name, outWriter)
+			if r2.Status == runstatus.Ok || attempt >= retries || params.Context.Err() != nil {
+				break
 			}
-		} else {
-			// No timeout, wait indefinitely
-			r2 = <-resultChan
+
+			_, _ = fmt.Fprintf(outWriter, "\x1b[33m%s failed: %v (retry %d/%d)\x1b[0m\n", name, r2.Err, attempt+1, retries)
 		}
-		cancel()
 		r2.Task = m
 
 		if r2.Status == runstatus.Error || r2.Status == runstatus.Cancelled {
@@ -703,3 +737,60 @@ func NewCyclicalReferenceError(cycles []types.Task) error {
 		Cycles: cycles,
 	}
 }
+
+// runTaskAttempt runs handler once for task, cancelling it when timeout is
+// set and elapses first.
+func runTaskAttempt(params RunTasksParams, p *Project, task *types.Task, m *Task, handler TaskHandler, timeout time.Duration, outputs map[string]interface{}, name string, outWriter io.Writer) *TaskResult {
+	var taskCtx context.Context
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		taskCtx, cancel = context.WithTimeout(params.Context, timeout)
+	} else {
+		taskCtx, cancel = context.WithCancel(params.Context)
+	}
+	defer cancel()
+
+	ctx := &TaskContext{
+		Project:     p,
+		Context:     taskCtx,
+		Schema:      task,
+		Task:        m,
+		Args:        task.Args,
+		ContextName: params.ContextName,
+		Outputs:     outputs,
+		Stdout:      params.Stdout,
+		Stderr:      params.Stderr,
+	}
+
+	if ctx.Stdout == nil {
+		ctx.Stdout = os.Stdout
+	}
+	if ctx.Stderr == nil {
+		ctx.Stderr = os.Stderr
+	}
+
+	// Run handler in a goroutine to support timeout
+	resultChan := make(chan *TaskResult, 1)
+	go func() {
+		result := handler(*ctx)
+		resultChan <- result
+	}()
+
+	if timeout <= 0 {
+		return <-resultChan
+	}
+
+	select {
+	case r := <-resultChan:
+		// Task completed before timeout
+		return r
+	case <-time.After(timeout):
+		// Task timed out
+		cancel()
+		r := NewTaskResult()
+		r.Status = runstatus.Cancelled
+		r.Err = errors.Newf("task %s timed out after %s", task.Name, timeout)
+		_, _ = fmt.Fprintf(outWriter, "\x1b[33m%s (timed out after %s)\x1b[0m\n", name, timeout)
+		return r
+	}
+}
@@ -0,0 +1,144 @@
+package projects_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/frostyeti/cast/internal/projects"
+)
+
+func TestRunTask_ResolvesCwdAgainstTheDefiningFile(t *testing.T) {
+	projectDir := t.TempDir()
+	projectFile := filepath.Join(projectDir, "castfile.yaml")
+	moduleDir := filepath.Join(projectDir, "shared")
+
+	for _, dir := range []string{filepath.Join(projectDir, "app"), filepath.Join(moduleDir, "scripts")} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("failed to create %s: %v", dir, err)
+		}
+	}
+
+	moduleContent := `
+name: shared
+tasks:
+  module-pwd:
+    uses: bash
+    cwd: scripts
+    run: echo "module $PWD"
+`
+	if err := os.WriteFile(filepath.Join(moduleDir, "castfile.yaml"), []byte(moduleContent), 0o644); err != nil {
+		t.Fatalf("failed to write module castfile: %v", err)
+	}
+
+	content := `
+name: cwd-demo
+imports:
+  - from: ./shared
+tasks:
+  root-pwd:
+    uses: bash
+    run: echo "root $PWD"
+  app-pwd:
+    uses: bash
+    cwd: app
+    run: echo "app $PWD"
+`
+	if err := os.WriteFile(projectFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write castfile: %v", err)
+	}
+
+	proj := &projects.Project{}
+	if err := proj.LoadFromYaml(projectFile); err != nil {
+		t.Fatalf("failed to load project: %v", err)
+	}
+
+	var stdout bytes.Buffer
+	_, err := proj.RunTask(projects.RunTasksParams{
+		Targets:     []string{"root-pwd", "app-pwd", "module-pwd"},
+		Context:     context.Background(),
+		ContextName: "default",
+		Stdout:      &stdout,
+		Stderr:      &stdout,
+	})
+	if err != nil {
+		t.Fatalf("failed to run tasks: %v\nOutput: %s", err, stdout.String())
+	}
+
+	output := stdout.String()
+	for _, want := range []string{
+		"root " + projectDir + "\n",
+		"app " + filepath.Join(projectDir, "app") + "\n",
+		"module " + filepath.Join(moduleDir, "scripts") + "\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("expected %q in output, got: %s", want, output)
+		}
+	}
+}
+
+func TestRunTask_ResolvesDotenvAgainstTheDefiningFile(t *testing.T) {
+	projectDir := t.TempDir()
+	projectFile := filepath.Join(projectDir, "castfile.yaml")
+	includeDir := filepath.Join(projectDir, "tasks")
+
+	if err := os.MkdirAll(includeDir, 0o755); err != nil {
+		t.Fatalf("failed to create %s: %v", includeDir, err)
+	}
+
+	files := map[string]string{
+		filepath.Join(projectDir, "project.env"): "PROJECT_LEVEL=root\n",
+		filepath.Join(includeDir, "deploy.env"):  "DEPLOY_TARGET=staging\n",
+		filepath.Join(includeDir, "deploy.yaml"): `
+tasks:
+  deploy:
+    uses: bash
+    dotenv: [deploy.env]
+    run: echo "deploy $DEPLOY_TARGET $PROJECT_LEVEL"
+`,
+		projectFile: `
+name: dotenv-demo
+include: [tasks/*.yaml]
+defaults:
+  dotenv: [project.env]
+tasks:
+  root:
+    uses: bash
+    run: echo "root $PROJECT_LEVEL"
+`,
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+
+	proj := &projects.Project{}
+	if err := proj.LoadFromYaml(projectFile); err != nil {
+		t.Fatalf("failed to load project: %v", err)
+	}
+
+	var stdout bytes.Buffer
+	_, err := proj.RunTask(projects.RunTasksParams{
+		Targets:     []string{"root", "deploy"},
+		Context:     context.Background(),
+		ContextName: "default",
+		Stdout:      &stdout,
+		Stderr:      &stdout,
+	})
+	if err != nil {
+		t.Fatalf("failed to run tasks: %v\nOutput: %s", err, stdout.String())
+	}
+
+	// the included task reads its sibling file, while the project default
+	// still resolves against the project directory.
+	output := stdout.String()
+	for _, want := range []string{"root root\n", "deploy staging root\n"} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("expected %q in output, got: %s", want, output)
+		}
+	}
+}
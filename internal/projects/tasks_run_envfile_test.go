@@ -72,3 +72,50 @@ tasks:
 		t.Fatalf("expected CAST_ENV file to be cleared after run, got: %q", string(data))
 	}
 }
+
+func TestRunTask_SkipsTasksForOtherPlatforms(t *testing.T) {
+	projectDir := t.TempDir()
+	projectFile := filepath.Join(projectDir, "castfile.yaml")
+
+	content := `
+name: platform-skip
+tasks:
+  elsewhere:
+    os: [plan9]
+    uses: bash
+    run: echo should-not-run
+  here:
+    needs: [elsewhere]
+    uses: bash
+    run: echo here-ran
+`
+
+	if err := os.WriteFile(projectFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write castfile: %v", err)
+	}
+
+	proj := &projects.Project{}
+	if err := proj.LoadFromYaml(projectFile); err != nil {
+		t.Fatalf("failed to load project: %v", err)
+	}
+
+	var stdout bytes.Buffer
+	_, err := proj.RunTask(projects.RunTasksParams{
+		Targets:     []string{"here"},
+		Context:     context.Background(),
+		ContextName: "default",
+		Stdout:      &stdout,
+		Stderr:      &stdout,
+	})
+	if err != nil {
+		t.Fatalf("failed to run tasks: %v\nOutput: %s", err, stdout.String())
+	}
+
+	output := stdout.String()
+	if strings.Contains(output, "should-not-run") || !strings.Contains(output, "skipped: requires os plan9") {
+		t.Fatalf("expected platform skip message, got: %s", output)
+	}
+	if !strings.Contains(output, "here-ran") {
+		t.Fatalf("expected dependent task to still run, got: %s", output)
+	}
+}
@@ -0,0 +1,538 @@
+package projects
+
+import (
+	stderrors "errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/frostyeti/cast/internal/errors"
+	"github.com/frostyeti/cast/internal/types"
+	"github.com/robfig/cron/v3"
+	"go.yaml.in/yaml/v4"
+)
+
+// Diagnostic severities reported by Validate.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Diagnostic is a single problem found while validating a project.
+type Diagnostic struct {
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	location := d.File
+	if d.Line > 0 && d.Column > 0 {
+		location = fmt.Sprintf("%s:%d:%d", d.File, d.Line, d.Column)
+	} else if d.Line > 0 {
+		location = fmt.Sprintf("%s:%d", d.File, d.Line)
+	}
+
+	return fmt.Sprintf("%s: %s: %s (%s)", location, d.Severity, d.Message, d.Code)
+}
+
+// Diagnostics is a list of validation problems.
+type Diagnostics []Diagnostic
+
+// HasErrors reports whether any diagnostic has error severity.
+func (d Diagnostics) HasErrors() bool {
+	for _, diag := range d {
+		if diag.Severity == SeverityError {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidateFile loads a castfile and its imports and reports every problem it
+// can find without running anything. Problems that stop the project from
+// loading are returned as a single diagnostic.
+func ValidateFile(file, contextName string) Diagnostics {
+	p := &Project{}
+	if err := p.LoadFromYaml(file); err != nil {
+		return Diagnostics{diagnosticFromError(file, "load", err)}
+	}
+
+	p.ContextName = contextName
+	return p.Validate()
+}
+
+// Validate initializes the project and checks task handlers, needs, hooks,
+// hosts, timeouts, job steps, cron expressions, and dependency cycles.
+func (p *Project) Validate() Diagnostics {
+	if err := p.Init(); err != nil {
+		return Diagnostics{diagnosticFromError(p.File, "init", err)}
+	}
+
+	v := &projectValidator{p: p, docs: map[string]*yaml.Node{}}
+	v.validateTasks()
+	v.validateTaskCycles()
+	v.validateJobs()
+	v.validateSchedule()
+
+	sort.SliceStable(v.diags, func(i, j int) bool {
+		a, b := v.diags[i], v.diags[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+
+	return v.diags
+}
+
+func diagnosticFromError(file, code string, err error) Diagnostic {
+	diag := Diagnostic{File: file, Severity: SeverityError, Code: code, Message: err.Error()}
+
+	// errors from included files name the file they were raised in.
+	var fileErr *types.FileError
+	if stderrors.As(err, &fileErr) {
+		diag.File = fileErr.File
+		diag.Message = fileErr.Err.Error()
+		err = fileErr.Err
+	}
+
+	// Nested decode errors are wrapped with the position of each enclosing
+	// node, so the first position in the message is the most specific one.
+	if m := yamlPositionPattern.FindStringSubmatch(diag.Message); m != nil {
+		diag.Line, _ = strconv.Atoi(m[1])
+		diag.Column, _ = strconv.Atoi(m[2])
+		return diag
+	}
+
+	var yamlErr errors.YamlError
+	var loadErr *yaml.LoadError
+	switch {
+	case stderrors.As(err, &yamlErr):
+		diag.Line = yamlErr.Line()
+		diag.Column = yamlErr.Column()
+	case stderrors.As(err, &loadErr):
+		diag.Line = loadErr.Line
+		diag.Column = loadErr.Column
+	default:
+		// yaml syntax errors only carry their line in the message.
+		if m := yamlLinePattern.FindStringSubmatch(diag.Message); m != nil {
+			diag.Line, _ = strconv.Atoi(m[1])
+		}
+	}
+
+	return diag
+}
+
+var (
+	yamlPositionPattern = regexp.MustCompile(`line (\d+), column (\d+)`)
+	yamlLinePattern     = regexp.MustCompile(`line (\d+)`)
+)
+
+type projectValidator struct {
+	p     *Project
+	docs  map[string]*yaml.Node
+	diags Diagnostics
+}
+
+func (v *projectValidator) add(file string, node *yaml.Node, severity, code, format string, args ...any) {
+	diag := Diagnostic{
+		File:     file,
+		Severity: severity,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	if node != nil {
+		diag.Line = node.Line
+		diag.Column = node.Column
+	}
+
+	v.diags = append(v.diags, diag)
+}
+
+// root returns the top level mapping of file, parsing it once.
+func (v *projectValidator) root(file string) *yaml.Node {
+	if node, ok := v.docs[file]; ok {
+		return node
+	}
+
+	var doc yaml.Node
+	data, err := os.ReadFile(file)
+	if err == nil {
+		err = yaml.Unmarshal(data, &doc)
+	}
+
+	var root *yaml.Node
+	if err == nil && doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		root = doc.Content[0]
+	}
+
+	v.docs[file] = root
+	return root
+}
+
+// mappingEntry returns the key and value nodes for the first matching key.
+func mappingEntry(node *yaml.Node, keys ...string) (*yaml.Node, *yaml.Node) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if slices.Contains(keys, node.Content[i].Value) {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+
+	return nil, nil
+}
+
+// findScalar returns the first scalar under node with the given value.
+func findScalar(node *yaml.Node, value string) *yaml.Node {
+	if node == nil {
+		return nil
+	}
+
+	if node.Kind == yaml.ScalarNode {
+		if node.Value == value {
+			return node
+		}
+		return nil
+	}
+
+	for _, child := range node.Content {
+		if found := findScalar(child, value); found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
+
+// findScalarOr returns the scalar under node with the given value, falling
+// back to node itself.
+func findScalarOr(node *yaml.Node, value string) *yaml.Node {
+	if found := findScalar(node, value); found != nil {
+		return found
+	}
+
+	return node
+}
+
+// taskNodes returns the key and value nodes for a task using the position
+// recorded when it was parsed.
+func (v *projectValidator) taskNodes(task types.Task) (*yaml.Node, *yaml.Node) {
+	file := task.File
+	if file == "" {
+		file = v.p.File
+	}
+
+	_, tasks := mappingEntry(v.root(file), "tasks")
+	if tasks == nil || tasks.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	for i := 0; i+1 < len(tasks.Content); i += 2 {
+		key := tasks.Content[i]
+		if key.Line == task.Line && key.Column == task.Column {
+			return key, tasks.Content[i+1]
+		}
+	}
+
+	return nil, nil
+}
+
+// field returns the node for a task field, falling back to the task key.
+func (v *projectValidator) field(task types.Task, keys ...string) *yaml.Node {
+	key, value := v.taskNodes(task)
+	if _, field := mappingEntry(value, keys...); field != nil {
+		return field
+	}
+
+	return key
+}
+
+func (v *projectValidator) taskFile(task types.Task) string {
+	if task.File != "" {
+		return task.File
+	}
+
+	return v.p.File
+}
+
+// hasTask reports whether target resolves to a task directly or through a
+// context variant.
+func (v *projectValidator) hasTask(target string) bool {
+	if _, ok := v.p.Tasks.Get(target); ok {
+		return true
+	}
+
+	prefix := strings.ToLower(target) + ":"
+	for _, key := range v.p.Tasks.Keys() {
+		if strings.HasPrefix(strings.ToLower(key), prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (v *projectValidator) validateTasks() {
+	p := v.p
+	for _, task := range p.Tasks.Values() {
+		file := v.taskFile(task)
+
+		uses := ""
+		if task.Uses != nil {
+			uses = *task.Uses
+		}
+
+		if !strings.Contains(uses, "{{") {
+			_, builtin := GetTaskHandler(uses)
+			_, fallback := findFallbackTask(uses, p.Dir)
+			if !builtin && !fallback && !IsRemoteTask(uses) {
+				v.add(file, v.field(task, "uses"), SeverityError, "unknown-uses",
+					"task %s uses unknown handler %q", task.Name, uses)
+			}
+		}
+
+		if len(task.Needs) > 0 {
+			_, needsNode := mappingEntry(v.taskValue(task), "needs")
+			for _, need := range task.Needs.Names() {
+				if !v.hasTask(need) {
+					node := findScalarOr(needsNode, need)
+					if node == nil {
+						node = v.field(task, "needs")
+					}
+					v.add(file, node, SeverityError, "missing-need",
+						"task %s needs undefined task %s", task.Name, need)
+				}
+			}
+		}
+
+		if task.Hooks != nil {
+			hooks := append(append([]string{}, task.Hooks.Before...), task.Hooks.After...)
+			for _, suffix := range hooks {
+				hookName := task.Id + ":" + suffix
+				if _, ok := p.Tasks.Get(hookName); !ok {
+					v.add(file, findScalarOr(v.field(task, "hooks"), suffix), SeverityWarning, "missing-hook",
+						"task %s hook %s has no matching task %s", task.Name, suffix, hookName)
+				}
+			}
+		}
+
+		if len(task.Hosts) > 0 {
+			_, hostsNode := mappingEntry(v.taskValue(task), "hosts")
+			for _, target := range task.Hosts {
+				if strings.ContainsAny(target, "{$") || v.hasHost(target) {
+					continue
+				}
+				node := findScalarOr(hostsNode, target)
+				if node == nil {
+					node = v.field(task, "hosts")
+				}
+				v.add(file, node, SeverityError, "unknown-host",
+					"task %s targets %s which matches no inventory host or tag", task.Name, target)
+			}
+		}
+
+		if task.Timeout != nil && !strings.ContainsRune(*task.Timeout, '{') {
+			if _, err := time.ParseDuration(*task.Timeout); err != nil {
+				v.add(file, v.field(task, "timeout"), SeverityError, "invalid-timeout",
+					"task %s has invalid timeout %q", task.Name, *task.Timeout)
+			}
+		}
+	}
+}
+
+func (v *projectValidator) taskValue(task types.Task) *yaml.Node {
+	_, value := v.taskNodes(task)
+	return value
+}
+
+func (v *projectValidator) hasHost(target string) bool {
+	if _, ok := v.p.Hosts[target]; ok {
+		return true
+	}
+
+	for _, h := range v.p.Hosts {
+		if slices.Contains(h.Tags, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validateTaskCycles reports each cycle in the needs and extends graphs once.
+func (v *projectValidator) validateTaskCycles() {
+	p := v.p
+
+	edges := func(task types.Task, kind string) []string {
+		if kind == "extends" {
+			return task.Extends
+		}
+		return task.Needs.Names()
+	}
+
+	for _, kind := range []string{"needs", "extends"} {
+		state := map[string]int{}
+		reported := map[string]bool{}
+		var stack []string
+
+		var visit func(task types.Task)
+		visit = func(task types.Task) {
+			state[task.Id] = 1
+			stack = append(stack, task.Name)
+
+			for _, next := range edges(task, kind) {
+				dep, ok := p.Tasks.Get(next)
+				if !ok {
+					continue
+				}
+
+				switch state[dep.Id] {
+				case 0:
+					visit(dep)
+				case 1:
+					start := slices.Index(stack, dep.Name)
+					if start < 0 {
+						start = 0
+					}
+					cycle := append(append([]string{}, stack[start:]...), dep.Name)
+					if !reported[dep.Id] {
+						reported[dep.Id] = true
+						v.add(v.taskFile(task), v.field(task, kind), SeverityError, kind+"-cycle",
+							"%s cycle: %s", kind, strings.Join(cycle, " -> "))
+					}
+				}
+			}
+
+			stack = stack[:len(stack)-1]
+			state[task.Id] = 2
+		}
+
+		for _, task := range p.Tasks.Values() {
+			if state[task.Id] == 0 {
+				visit(task)
+			}
+		}
+	}
+}
+
+// jobFile returns the file a job is defined in.
+func (v *projectValidator) jobFile(job types.Job) string {
+	if job.File != "" {
+		return job.File
+	}
+
+	return v.p.File
+}
+
+// jobNodes returns the key and value nodes for a job in the file that
+// defines it.
+func (v *projectValidator) jobNodes(job types.Job) (*yaml.Node, *yaml.Node) {
+	_, jobs := mappingEntry(v.root(v.jobFile(job)), "jobs")
+	if jobs == nil || jobs.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	for i := 0; i+1 < len(jobs.Content); i += 2 {
+		if jobs.Content[i].Value == job.Name {
+			return jobs.Content[i], jobs.Content[i+1]
+		}
+	}
+
+	return nil, nil
+}
+
+func (v *projectValidator) validateJobs() {
+	p := v.p
+	if p.Schema.Jobs == nil {
+		return
+	}
+
+	for _, job := range p.Schema.Jobs.Values() {
+		file := v.jobFile(job)
+		key, value := v.jobNodes(job)
+		fieldOrKey := func(keys ...string) *yaml.Node {
+			if _, field := mappingEntry(value, keys...); field != nil {
+				return field
+			}
+			return key
+		}
+
+		_, stepsNode := mappingEntry(value, "steps")
+		for i, step := range job.Steps {
+			if step.TaskName == nil || v.hasTask(*step.TaskName) {
+				continue
+			}
+
+			node := key
+			if stepsNode != nil && i < len(stepsNode.Content) {
+				node = findScalarOr(stepsNode.Content[i], *step.TaskName)
+			}
+			v.add(file, node, SeverityError, "unknown-step-task",
+				"job %s step %d references undefined task %s", job.Name, i+1, *step.TaskName)
+		}
+
+		if job.Needs != nil {
+			needsNode := fieldOrKey("needs")
+			for _, need := range job.Needs.Names() {
+				if _, ok := p.Schema.Jobs.Get(need); !ok {
+					v.add(file, findScalarOr(needsNode, need), SeverityError, "missing-need",
+						"job %s needs undefined job %s", job.Name, need)
+				}
+			}
+		}
+
+		if job.Timeout != nil && !strings.ContainsRune(*job.Timeout, '{') {
+			if _, err := time.ParseDuration(*job.Timeout); err != nil {
+				v.add(file, fieldOrKey("timeout"), SeverityError, "invalid-timeout",
+					"job %s has invalid timeout %q", job.Name, *job.Timeout)
+			}
+		}
+
+		if job.Cron != nil && *job.Cron != "" {
+			if _, err := cron.ParseStandard(*job.Cron); err != nil {
+				v.add(file, fieldOrKey("cron"), SeverityError, "invalid-cron",
+					"job %s has invalid cron expression %q: %v", job.Name, *job.Cron, err)
+			}
+		}
+	}
+
+	for _, job := range p.Schema.Jobs.Values() {
+		if _, err := p.GetDownstreamJobs(job.Id); err != nil && strings.Contains(err.Error(), "cycle") {
+			key, _ := v.jobNodes(job)
+			v.add(v.jobFile(job), key, SeverityError, "needs-cycle", "job %s is part of a needs cycle", job.Name)
+			break
+		}
+	}
+}
+
+func (v *projectValidator) validateSchedule() {
+	p := v.p
+	if p.Schema.On == nil || p.Schema.On.Schedule == nil {
+		return
+	}
+
+	_, on := mappingEntry(v.root(p.File), "on")
+	_, schedule := mappingEntry(on, "schedule")
+	for _, expr := range p.Schema.On.Schedule.Crons {
+		if _, err := cron.ParseStandard(expr); err != nil {
+			v.add(p.File, findScalarOr(schedule, expr), SeverityError, "invalid-cron",
+				"invalid cron expression %q: %v", expr, err)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package projects_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/frostyeti/cast/internal/projects"
+)
+
+func TestValidateFile_ReportsProblemsWithPositions(t *testing.T) {
+	projectDir := t.TempDir()
+	projectFile := filepath.Join(projectDir, "castfile.yaml")
+	content := `name: demo
+tasks:
+  build:
+    uses: nope
+    needs: [missing, test]
+    timeout: 5 parsecs
+    hosts: [web]
+  test:
+    run: echo test
+    needs: [build]
+jobs:
+  ci:
+    cron: "bad"
+    steps:
+      - build
+      - ghost
+`
+	if err := os.WriteFile(projectFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write castfile: %v", err)
+	}
+
+	diags := projects.ValidateFile(projectFile, "")
+	if !diags.HasErrors() {
+		t.Fatalf("expected validation errors")
+	}
+
+	expected := map[string][2]int{
+		"unknown-uses":      {4, 11},
+		"missing-need":      {5, 13},
+		"invalid-timeout":   {6, 14},
+		"unknown-host":      {7, 13},
+		"needs-cycle":       {10, 12},
+		"invalid-cron":      {13, 11},
+		"unknown-step-task": {16, 9},
+	}
+
+	found := map[string]bool{}
+	for _, diag := range diags {
+		pos, ok := expected[diag.Code]
+		if !ok {
+			t.Fatalf("unexpected diagnostic: %s", diag)
+		}
+		if diag.File != projectFile || diag.Line != pos[0] || diag.Column != pos[1] {
+			t.Fatalf("expected %s at %d:%d, got %s", diag.Code, pos[0], pos[1], diag)
+		}
+		found[diag.Code] = true
+	}
+
+	for code := range expected {
+		if !found[code] {
+			t.Fatalf("expected a %s diagnostic, got %v", code, diags)
+		}
+	}
+}
+
+func TestValidateFile_ReportsLoadErrorPosition(t *testing.T) {
+	projectDir := t.TempDir()
+	projectFile := filepath.Join(projectDir, "castfile.yaml")
+	content := `name: demo
+tasks:
+  build:
+    env-required:
+      PORT: weird
+`
+	if err := os.WriteFile(projectFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write castfile: %v", err)
+	}
+
+	diags := projects.ValidateFile(projectFile, "")
+	if len(diags) != 1 || diags[0].Code != "load" {
+		t.Fatalf("expected a single load diagnostic, got %v", diags)
+	}
+	if diags[0].Line != 5 || diags[0].Column != 13 {
+		t.Fatalf("expected load error at 5:13, got %s", diags[0])
+	}
+}
+
+func TestValidateFile_ReportsIncludeErrorInIncludedFile(t *testing.T) {
+	projectDir := t.TempDir()
+	projectFile := filepath.Join(projectDir, "castfile.yaml")
+	includeFile := filepath.Join(projectDir, "jobs.yaml")
+	if err := os.WriteFile(projectFile, []byte("name: demo\ninclude: [\"*.yaml\"]\njobs:\n  ci:\n    steps: [build]\n"), 0o644); err != nil {
+		t.Fatalf("failed to write castfile: %v", err)
+	}
+	if err := os.WriteFile(includeFile, []byte("jobs:\n  nightly:\n    steps: [build]\n  ci:\n    steps: [test]\n"), 0o644); err != nil {
+		t.Fatalf("failed to write include: %v", err)
+	}
+
+	diags := projects.ValidateFile(projectFile, "")
+	if len(diags) != 1 || diags[0].Code != "load" {
+		t.Fatalf("expected a single load diagnostic, got %v", diags)
+	}
+	if diags[0].File != includeFile || diags[0].Line != 4 || diags[0].Column != 3 {
+		t.Fatalf("expected load error at %s:4:3, got %s", includeFile, diags[0])
+	}
+}
+
+func TestValidateFile_ReportsIncludedJobProblemsInIncludedFile(t *testing.T) {
+	projectDir := t.TempDir()
+	projectFile := filepath.Join(projectDir, "castfile.yaml")
+	includeFile := filepath.Join(projectDir, "jobs.yaml")
+	if err := os.WriteFile(projectFile, []byte("name: demo\ninclude: [\"jobs.yaml\"]\ntasks:\n  build: echo build\n"), 0o644); err != nil {
+		t.Fatalf("failed to write castfile: %v", err)
+	}
+	if err := os.WriteFile(includeFile, []byte("jobs:\n  nightly:\n    steps: [build, missing]\n"), 0o644); err != nil {
+		t.Fatalf("failed to write include: %v", err)
+	}
+
+	diags := projects.ValidateFile(projectFile, "")
+	if len(diags) != 1 || diags[0].Code != "unknown-step-task" {
+		t.Fatalf("expected a single unknown-step-task diagnostic, got %v", diags)
+	}
+	if diags[0].File != includeFile || diags[0].Line != 3 || diags[0].Column != 20 {
+		t.Fatalf("expected the diagnostic at %s:3:20, got %s", includeFile, diags[0])
+	}
+}
@@ -0,0 +1,80 @@
+package projects
+
+import (
+	"maps"
+	"strings"
+
+	"github.com/frostyeti/cast/internal/errors"
+	"github.com/frostyeti/cast/internal/eval"
+	"github.com/frostyeti/cast/internal/types"
+)
+
+// resolveVars evaluates vars in declaration order on top of base and returns
+// the combined values. Each var can reference anything in scope, including
+// `vars.*` values declared before it.
+func resolveVars(vars *types.Vars, scope map[string]any, base map[string]any) (map[string]any, error) {
+	resolved := maps.Clone(base)
+	if resolved == nil {
+		resolved = map[string]any{}
+	}
+
+	if vars.Len() == 0 {
+		return resolved, nil
+	}
+
+	local := maps.Clone(scope)
+	if local == nil {
+		local = map[string]any{}
+	}
+	local["vars"] = resolved
+
+	for _, key := range vars.Keys() {
+		value, _ := vars.Get(key)
+		v, err := evalVarValue(value, local)
+		if err != nil {
+			return nil, errors.Newf("failed to evaluate var %s: %w", key, err)
+		}
+		resolved[key] = v
+	}
+
+	return resolved, nil
+}
+
+// evalVarValue evaluates the expressions in a var value. A string that is a
+// single `${{ expr }}` keeps the type of the expression result so vars can
+// hold computed lists and numbers; other strings are interpolated.
+func evalVarValue(value any, scope map[string]any) (any, error) {
+	switch v := value.(type) {
+	case string:
+		trimmed := strings.TrimSpace(v)
+		if strings.HasPrefix(trimmed, "${{") && strings.HasSuffix(trimmed, "}}") && strings.Count(trimmed, "${{") == 1 {
+			return eval.Eval(trimmed[3:len(trimmed)-2], scope)
+		}
+		if strings.Contains(v, "${{") {
+			return eval.EvalTemplate(v, scope)
+		}
+		return v, nil
+	case []any:
+		items := make([]any, 0, len(v))
+		for _, item := range v {
+			evaled, err := evalVarValue(item, scope)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, evaled)
+		}
+		return items, nil
+	case map[string]any:
+		values := make(map[string]any, len(v))
+		for k, item := range v {
+			evaled, err := evalVarValue(item, scope)
+			if err != nil {
+				return nil, err
+			}
+			values[k] = evaled
+		}
+		return values, nil
+	}
+
+	return value, nil
+}
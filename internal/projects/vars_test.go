@@ -0,0 +1,66 @@
+package projects_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/frostyeti/cast/internal/projects"
+)
+
+func TestRunTask_ExposesTypedVarsWithoutExportingThem(t *testing.T) {
+	projectDir := t.TempDir()
+	projectFile := filepath.Join(projectDir, "castfile.yaml")
+
+	content := `
+name: vars-demo
+vars:
+  regions: [us-east, eu-west]
+  count: "${{ len(vars.regions) }}"
+  label: "regions=${{ vars.count }}"
+tasks:
+  show:
+    uses: bash
+    vars:
+      scaled: "${{ vars.count * 10 }}"
+    if: len(vars.regions) == 2
+    template: gotmpl
+    run: echo "{{ join "," .vars.regions }} {{ .vars.scaled }} {{ .vars.label }} leaked=${regions:-no}"
+  skipped:
+    uses: bash
+    if: vars.count > 5
+    run: echo should-not-run
+`
+
+	if err := os.WriteFile(projectFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write castfile: %v", err)
+	}
+
+	proj := &projects.Project{}
+	if err := proj.LoadFromYaml(projectFile); err != nil {
+		t.Fatalf("failed to load project: %v", err)
+	}
+
+	var stdout bytes.Buffer
+	_, err := proj.RunTask(projects.RunTasksParams{
+		Targets:     []string{"show", "skipped"},
+		Context:     context.Background(),
+		ContextName: "default",
+		Stdout:      &stdout,
+		Stderr:      &stdout,
+	})
+	if err != nil {
+		t.Fatalf("failed to run tasks: %v\nOutput: %s", err, stdout.String())
+	}
+
+	output := stdout.String()
+	if !strings.Contains(output, "us-east,eu-west 20 regions=2 leaked=no") {
+		t.Fatalf("expected vars in template output, got: %s", output)
+	}
+	if strings.Contains(output, "should-not-run") || !strings.Contains(output, "skipped") {
+		t.Fatalf("expected task guarded by vars to be skipped, got: %s", output)
+	}
+}
@@ -30,6 +30,7 @@ type CastTaskRuns struct {
 type CastTask struct {
 	Name        string                   `yaml:"name" json:"name"`
 	Description string                   `yaml:"description,omitempty" json:"description,omitempty"`
+	Requires    *Requires                `yaml:"requires,omitempty" json:"requires,omitempty"`
 	Inputs      map[string]CastTaskInput `yaml:"inputs,omitempty" json:"inputs,omitempty"`
 	Runs        CastTaskRuns             `yaml:"runs" json:"runs"`
 }
@@ -0,0 +1,108 @@
+package types
+
+import (
+	"strings"
+
+	"github.com/frostyeti/cast/internal/errors"
+	"go.yaml.in/yaml/v4"
+)
+
+// Context is an inline block from the castfile `contexts` map. When the
+// context is active its settings are layered over the project settings, after
+// the settings of the context it extends.
+type Context struct {
+	Name        string   `yaml:"-" json:"-"`
+	Extends     *string  `yaml:"extends,omitempty" json:"extends,omitempty"`
+	Env         *Env     `yaml:"env,omitempty" json:"env,omitempty"`
+	DotEnv      *DotEnvs `yaml:"dotenv,omitempty" json:"dotenv,omitempty"`
+	Vars        *Vars    `yaml:"vars,omitempty" json:"vars,omitempty"`
+	Inventories []string `yaml:"inventories,omitempty" json:"inventories,omitempty"`
+	Hosts       []string `yaml:"hosts,omitempty" json:"hosts,omitempty"`
+}
+
+// Contexts stores inline context blocks by name.
+type Contexts map[string]Context
+
+func (c *Context) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return errors.NewYamlError(node, "context must be a mapping node.")
+	}
+
+	for i := 0; i < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		valueNode := node.Content[i+1]
+
+		switch keyNode.Value {
+		case "extends":
+			if valueNode.Kind != yaml.ScalarNode {
+				return errors.NewYamlError(valueNode, "context extends must be a scalar.")
+			}
+			extends := strings.TrimSpace(valueNode.Value)
+			c.Extends = &extends
+		case "env":
+			c.Env = NewEnv()
+			if err := valueNode.Decode(c.Env); err != nil {
+				return errors.NewYamlError(valueNode, "failed to decode context env: "+err.Error())
+			}
+		case "dotenv":
+			c.DotEnv = &DotEnvs{}
+			if err := valueNode.Decode(c.DotEnv); err != nil {
+				return errors.NewYamlError(valueNode, "failed to decode context dotenv: "+err.Error())
+			}
+		case "vars":
+			c.Vars = NewVars()
+			if err := valueNode.Decode(c.Vars); err != nil {
+				return errors.NewYamlError(valueNode, "failed to decode context vars: "+err.Error())
+			}
+		case "inventories":
+			inventories, err := decodeStringOrStrings(valueNode, "inventories")
+			if err != nil {
+				return err
+			}
+			c.Inventories = inventories
+		case "hosts":
+			hosts, err := decodeStringOrStrings(valueNode, "hosts")
+			if err != nil {
+				return err
+			}
+			c.Hosts = hosts
+		default:
+			return errors.YamlErrorf(keyNode, "unexpected field '%s' in context", keyNode.Value)
+		}
+	}
+
+	return nil
+}
+
+// Chain returns the context named name preceded by the contexts it extends,
+// starting with the root of the chain. A name without an inline block
+// returns an empty chain so suffix-only contexts keep working.
+func (c Contexts) Chain(name string) ([]Context, error) {
+	chain := []Context{}
+	seen := []string{}
+	for name != "" {
+		ctx, ok := c[name]
+		if !ok {
+			if len(seen) == 0 {
+				return chain, nil
+			}
+			return nil, errors.Newf("context %s extends undefined context %s", seen[len(seen)-1], name)
+		}
+
+		for _, s := range seen {
+			if s == name {
+				return nil, errors.Newf("context %s has a circular extends chain: %s -> %s", seen[0], strings.Join(seen, " -> "), name)
+			}
+		}
+
+		seen = append(seen, name)
+		chain = append([]Context{ctx}, chain...)
+
+		name = ""
+		if ctx.Extends != nil {
+			name = *ctx.Extends
+		}
+	}
+
+	return chain, nil
+}
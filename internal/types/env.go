@@ -14,9 +14,11 @@ import (
 )
 
 // Env preserves ordered environment variables.
+// Keys declared with the secret form are tracked so callers can mask them.
 type Env struct {
-	Map  map[string]string
-	keys []string
+	Map     map[string]string
+	keys    []string
+	secrets []string
 }
 
 func (e *Env) MarshalYAML() (interface{}, error) {
@@ -142,6 +144,9 @@ func (e *Env) UnmarshalYAML(node *yaml.Node) error {
 			if !hasKey {
 				e.keys = append(e.keys, ev.Name)
 			}
+			if ev.IsSecret {
+				e.MarkSecret(ev.Name)
+			}
 		}
 		return nil
 	}
@@ -195,6 +200,9 @@ func (e *Env) UnmarshalYAML(node *yaml.Node) error {
 				if !hasKey {
 					e.keys = append(e.keys, ev.Name)
 				}
+				if ev.IsSecret {
+					e.MarkSecret(ev.Name)
+				}
 
 				continue
 			}
@@ -261,9 +269,30 @@ func (e *Env) Clone() *Env {
 		clone.Map[k] = v
 	}
 	clone.keys = append(clone.keys, e.keys...)
+	clone.secrets = append(clone.secrets, e.secrets...)
 	return clone
 }
 
+// MarkSecret flags key as holding a secret value.
+func (e *Env) MarkSecret(key string) {
+	if e == nil {
+		return
+	}
+
+	if !slices.Contains(e.secrets, key) {
+		e.secrets = append(e.secrets, key)
+	}
+}
+
+// IsSecret reports whether key was declared as a secret.
+func (e *Env) IsSecret(key string) bool {
+	if e == nil {
+		return false
+	}
+
+	return slices.Contains(e.secrets, key)
+}
+
 func (e *Env) ToMap() map[string]string {
 	if e == nil {
 		return map[string]string{}
@@ -337,6 +366,10 @@ func (e *Env) Merge(other *Env) {
 		if !hasKey {
 			e.keys = append(e.keys, k)
 		}
+
+		if other.IsSecret(k) {
+			e.MarkSecret(k)
+		}
 	}
 }
 
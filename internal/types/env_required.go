@@ -0,0 +1,240 @@
+package types
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/frostyeti/cast/internal/errors"
+	"go.yaml.in/yaml/v4"
+)
+
+// EnvRequirement declares a variable that must be present once all
+// dotenv and env layers have been applied.
+// Type may be string, int, number, bool, url, or path.
+type EnvRequirement struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type,omitempty"`
+	Pattern string   `json:"pattern,omitempty"`
+	Values  []string `json:"values,omitempty"`
+	Desc    string   `json:"desc,omitempty"`
+	Line    int      `json:"-"`
+	Column  int      `json:"-"`
+}
+
+// EnvRequirements is an ordered list of required variable declarations.
+type EnvRequirements []EnvRequirement
+
+var envRequirementTypes = []string{"string", "int", "number", "bool", "url", "path"}
+
+func (r *EnvRequirements) UnmarshalYAML(node *yaml.Node) error {
+	if r == nil {
+		r = &EnvRequirements{}
+	}
+
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	switch node.Kind {
+	case yaml.ScalarNode:
+		req := EnvRequirement{Name: strings.TrimSpace(node.Value), Line: node.Line, Column: node.Column}
+		if req.Name == "" {
+			return errors.NewYamlError(node, "required env variable name cannot be empty")
+		}
+		*r = append(*r, req)
+		return nil
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			var req EnvRequirement
+			if err := req.UnmarshalYAML(item); err != nil {
+				return err
+			}
+			*r = append(*r, req)
+		}
+		return nil
+	case yaml.MappingNode:
+		for i := 0; i < len(node.Content); i += 2 {
+			keyNode := node.Content[i]
+			valueNode := node.Content[i+1]
+
+			if keyNode.Kind != yaml.ScalarNode || strings.TrimSpace(keyNode.Value) == "" {
+				return errors.NewYamlError(keyNode, "expected yaml scalar for required env variable name")
+			}
+
+			req := EnvRequirement{Name: strings.TrimSpace(keyNode.Value)}
+			switch valueNode.Kind {
+			case yaml.ScalarNode:
+				if err := req.setType(valueNode); err != nil {
+					return err
+				}
+			case yaml.MappingNode:
+				if err := req.UnmarshalYAML(valueNode); err != nil {
+					return err
+				}
+				req.Name = strings.TrimSpace(keyNode.Value)
+			default:
+				return errors.NewYamlError(valueNode, "expected yaml scalar or mapping for required env variable")
+			}
+
+			req.Line = keyNode.Line
+			req.Column = keyNode.Column
+			*r = append(*r, req)
+		}
+		return nil
+	}
+
+	return errors.NewYamlError(node, "expected yaml scalar, sequence, or mapping for env-required")
+}
+
+func (er *EnvRequirement) UnmarshalYAML(node *yaml.Node) error {
+	if er == nil {
+		er = &EnvRequirement{}
+	}
+
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	er.Line = node.Line
+	er.Column = node.Column
+
+	if node.Kind == yaml.ScalarNode {
+		er.Name = strings.TrimSpace(node.Value)
+		if er.Name == "" {
+			return errors.NewYamlError(node, "required env variable name cannot be empty")
+		}
+		return nil
+	}
+
+	if node.Kind != yaml.MappingNode {
+		return errors.NewYamlError(node, "expected yaml scalar or mapping for required env variable")
+	}
+
+	for i := 0; i < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		valueNode := node.Content[i+1]
+
+		switch keyNode.Value {
+		case "name":
+			if valueNode.Kind != yaml.ScalarNode {
+				return errors.NewYamlError(valueNode, "expected yaml scalar for 'name' field")
+			}
+			er.Name = strings.TrimSpace(valueNode.Value)
+		case "type":
+			if err := er.setType(valueNode); err != nil {
+				return err
+			}
+		case "pattern", "regex", "match":
+			if valueNode.Kind != yaml.ScalarNode {
+				return errors.NewYamlError(valueNode, "expected yaml scalar for 'pattern' field")
+			}
+			if _, err := regexp.Compile(valueNode.Value); err != nil {
+				return errors.YamlErrorf(valueNode, "invalid pattern for required env variable: %v", err)
+			}
+			er.Pattern = valueNode.Value
+		case "values", "enum", "allowed":
+			if valueNode.Kind != yaml.SequenceNode {
+				return errors.NewYamlError(valueNode, "expected yaml sequence for 'values' field")
+			}
+			er.Values = make([]string, 0, len(valueNode.Content))
+			for _, item := range valueNode.Content {
+				if item.Kind != yaml.ScalarNode {
+					return errors.NewYamlError(item, "expected yaml scalar in 'values' list")
+				}
+				er.Values = append(er.Values, item.Value)
+			}
+		case "desc", "description":
+			if valueNode.Kind != yaml.ScalarNode {
+				return errors.NewYamlError(valueNode, "expected yaml scalar for 'desc' field")
+			}
+			er.Desc = valueNode.Value
+		default:
+			return errors.YamlErrorf(keyNode, "unexpected field '%s' in required env variable", keyNode.Value)
+		}
+	}
+
+	return nil
+}
+
+func (er *EnvRequirement) setType(node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode {
+		return errors.NewYamlError(node, "expected yaml scalar for 'type' field")
+	}
+
+	t := strings.ToLower(strings.TrimSpace(node.Value))
+	switch t {
+	case "", "true", "required":
+		er.Type = ""
+		return nil
+	case "integer":
+		t = "int"
+	case "float":
+		t = "number"
+	case "boolean":
+		t = "bool"
+	case "uri":
+		t = "url"
+	}
+
+	if !slices.Contains(envRequirementTypes, t) {
+		return errors.YamlErrorf(node, "unknown required env type '%s', expected one of %s", node.Value, strings.Join(envRequirementTypes, ", "))
+	}
+
+	er.Type = t
+	return nil
+}
+
+// Check validates a value against the requirement and returns a short reason
+// when the value is missing or invalid. Relative paths resolve against baseDir.
+// The value itself is never included in the reason so secrets are not leaked.
+func (er *EnvRequirement) Check(value string, ok bool, baseDir string) string {
+	if !ok || value == "" {
+		return "is not set"
+	}
+
+	switch er.Type {
+	case "int":
+		if _, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64); err != nil {
+			return "is not a valid int"
+		}
+	case "number":
+		if _, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err != nil {
+			return "is not a valid number"
+		}
+	case "bool":
+		if _, err := strconv.ParseBool(strings.TrimSpace(value)); err != nil {
+			return "is not a valid bool"
+		}
+	case "url":
+		u, err := url.Parse(value)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return "is not a valid url"
+		}
+	case "path":
+		p := value
+		if !filepath.IsAbs(p) && baseDir != "" {
+			p = filepath.Join(baseDir, p)
+		}
+		if _, err := os.Stat(p); err != nil {
+			return "does not point to an existing path"
+		}
+	}
+
+	if er.Pattern != "" {
+		re, err := regexp.Compile(er.Pattern)
+		if err != nil || !re.MatchString(value) {
+			return "does not match pattern " + er.Pattern
+		}
+	}
+
+	if len(er.Values) > 0 && !slices.Contains(er.Values, value) {
+		return "must be one of " + strings.Join(er.Values, ", ")
+	}
+
+	return ""
+}
@@ -0,0 +1,47 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.yaml.in/yaml/v4"
+)
+
+func TestEnvRequirementsAcceptsListAndMapping(t *testing.T) {
+	var task Task
+	require.NoError(t, yaml.Unmarshal([]byte("env-required:\n  - REGISTRY_URL\n  - name: MODE\n    values: [dev, prod]\n"), &task))
+	require.Len(t, task.EnvRequired, 2)
+	require.Equal(t, "REGISTRY_URL", task.EnvRequired[0].Name)
+	require.Equal(t, []string{"dev", "prod"}, task.EnvRequired[1].Values)
+
+	var project Project
+	require.NoError(t, yaml.Unmarshal([]byte("env-required:\n  PORT: int\n  TAG:\n    pattern: ^v[0-9]+$\n"), &project))
+	require.Len(t, project.EnvRequired, 2)
+	require.Equal(t, "int", project.EnvRequired[0].Type)
+	require.Equal(t, 2, project.EnvRequired[0].Line)
+	require.Equal(t, "^v[0-9]+$", project.EnvRequired[1].Pattern)
+
+	var bad Task
+	require.Error(t, yaml.Unmarshal([]byte("env-required:\n  PORT: integerish\n"), &bad))
+}
+
+func TestEnvRequirementCheck(t *testing.T) {
+	req := EnvRequirement{Name: "URL", Type: "url"}
+	require.Equal(t, "is not set", req.Check("", false, ""))
+	require.Equal(t, "is not a valid url", req.Check("not a url", true, ""))
+	require.Empty(t, req.Check("https://registry.example.com", true, ""))
+
+	port := EnvRequirement{Name: "PORT", Type: "int"}
+	require.Equal(t, "is not a valid int", port.Check("abc", true, ""))
+	require.Empty(t, port.Check("8080", true, ""))
+
+	mode := EnvRequirement{Name: "MODE", Values: []string{"dev", "prod"}}
+	require.Equal(t, "must be one of dev, prod", mode.Check("qa", true, ""))
+
+	tag := EnvRequirement{Name: "TAG", Pattern: "^v[0-9]+$"}
+	require.Contains(t, tag.Check("latest", true, ""), "does not match pattern")
+
+	dir := EnvRequirement{Name: "DIR", Type: "path"}
+	require.Empty(t, dir.Check(".", true, t.TempDir()))
+	require.Equal(t, "does not point to an existing path", dir.Check("missing", true, t.TempDir()))
+}
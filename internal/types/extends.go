@@ -0,0 +1,67 @@
+package types
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/frostyeti/cast/internal/errors"
+	"go.yaml.in/yaml/v4"
+)
+
+// Merge strategies for list fields inherited through `extends`.
+const (
+	MergeAppend  = "append"
+	MergeReplace = "replace"
+)
+
+// taskMergeFields lists the task fields whose merge strategy can be set and
+// the strategy used when the task does not set one.
+var taskMergeFields = map[string]string{
+	"args":   MergeReplace,
+	"needs":  MergeReplace,
+	"hosts":  MergeAppend,
+	"dotenv": MergeAppend,
+}
+
+// MergeStrategies maps an inherited list field to how its values combine with
+// the values of the extended tasks.
+type MergeStrategies map[string]string
+
+// TaskMergeStrategy returns the strategy a task uses for field.
+func (m MergeStrategies) TaskMergeStrategy(field string) string {
+	if strategy, ok := m[field]; ok {
+		return strategy
+	}
+
+	return taskMergeFields[field]
+}
+
+func decodeMergeStrategies(node *yaml.Node, fields map[string]string) (MergeStrategies, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, errors.NewYamlError(node, "expected yaml mapping for 'merge' field")
+	}
+
+	strategies := MergeStrategies{}
+	for i := 0; i < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		valueNode := node.Content[i+1]
+
+		if _, ok := fields[keyNode.Value]; !ok {
+			names := make([]string, 0, len(fields))
+			for name := range fields {
+				names = append(names, name)
+			}
+			slices.Sort(names)
+			return nil, errors.YamlErrorf(keyNode, "unknown merge field %q, expected one of %s", keyNode.Value, strings.Join(names, ", "))
+		}
+
+		strategy := strings.ToLower(strings.TrimSpace(valueNode.Value))
+		if valueNode.Kind != yaml.ScalarNode || (strategy != MergeAppend && strategy != MergeReplace) {
+			return nil, errors.YamlErrorf(valueNode, "merge strategy for %q must be %s or %s", keyNode.Value, MergeAppend, MergeReplace)
+		}
+
+		strategies[keyNode.Value] = strategy
+	}
+
+	return strategies, nil
+}
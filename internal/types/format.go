@@ -0,0 +1,215 @@
+package types
+
+import (
+	"bytes"
+	"slices"
+
+	"github.com/frostyeti/cast/internal/errors"
+	"go.yaml.in/yaml/v4"
+)
+
+// keyLayout describes the canonical key order of a mapping in a cast file and
+// the aliases the parser accepts for each key. It is derived from the same
+// field declarations the JSON schema uses.
+type keyLayout struct {
+	order   []string
+	aliases map[string]string
+}
+
+func newKeyLayout(fields []schemaField) keyLayout {
+	layout := keyLayout{aliases: map[string]string{}}
+	for _, f := range fields {
+		layout.order = append(layout.order, f.name)
+		for _, alias := range f.aliases {
+			layout.aliases[alias] = f.name
+		}
+	}
+
+	return layout
+}
+
+func (l keyLayout) canonical(key string) string {
+	if name, ok := l.aliases[key]; ok {
+		return name
+	}
+
+	return key
+}
+
+func (l keyLayout) index(key string) int {
+	return slices.Index(l.order, key)
+}
+
+// Format rewrites a castfile, module or inventory file into the canonical
+// layout: keys are sorted into the order cast documents them, aliases such as
+// `deps` and `description` are renamed to their canonical keys and everything
+// is indented with two spaces. Comments are preserved because the document is
+// rewritten as a yaml node tree.
+func Format(data []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	if doc.Kind == 0 {
+		return data, nil
+	}
+
+	root := &doc
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+
+	if root.Kind != yaml.MappingNode {
+		return nil, errors.NewYamlError(root, "expected a mapping at the root of the document")
+	}
+
+	// a comment directly above the first key is the file header, so it stays
+	// at the top instead of moving with its key.
+	header := ""
+	if len(root.Content) > 0 {
+		header = root.Content[0].HeadComment
+		root.Content[0].HeadComment = ""
+	}
+
+	if isInventoryRoot(root) {
+		formatInventory(root)
+	} else {
+		formatProject(root)
+	}
+
+	if header != "" {
+		first := root.Content[0]
+		if first.HeadComment != "" {
+			header += "\n" + first.HeadComment
+		}
+		first.HeadComment = header
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// isInventoryRoot reports whether a root mapping only holds inventory keys,
+// which is the shape of a standalone inventory file.
+func isInventoryRoot(root *yaml.Node) bool {
+	layout := newKeyLayout(inventoryFields())
+	hasHosts := false
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key := root.Content[i].Value
+		if layout.index(key) < 0 {
+			return false
+		}
+		if key == "hosts" {
+			hasHosts = true
+		}
+	}
+
+	return hasHosts
+}
+
+func formatProject(node *yaml.Node) {
+	formatMapping(node, newKeyLayout(projectFields()))
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		value := node.Content[i+1]
+		switch node.Content[i].Value {
+		case "tasks":
+			formatEntries(value, newKeyLayout(taskFields()))
+		case "jobs":
+			formatEntries(value, newKeyLayout(jobFields()))
+		case "inventory":
+			formatInventory(value)
+		}
+	}
+}
+
+func formatInventory(node *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+
+	formatMapping(node, newKeyLayout(inventoryFields()))
+	hosts := newKeyLayout(hostFields())
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		value := node.Content[i+1]
+		switch node.Content[i].Value {
+		case "defaults":
+			formatEntries(value, hosts)
+		case "hosts":
+			if value.Kind == yaml.SequenceNode {
+				for _, item := range value.Content {
+					formatMapping(item, hosts)
+				}
+				continue
+			}
+			formatEntries(value, hosts)
+		}
+	}
+}
+
+// formatEntries formats every mapping value of a named collection such as
+// `tasks` or `jobs`. Scalar shorthands are left untouched.
+func formatEntries(node *yaml.Node, layout keyLayout) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+
+	for i := 1; i < len(node.Content); i += 2 {
+		formatMapping(node.Content[i], layout)
+	}
+}
+
+// formatMapping renames alias keys and sorts the pairs of a mapping node into
+// the layout order. Unknown keys keep their relative order after known keys.
+func formatMapping(node *yaml.Node, layout keyLayout) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+
+	type pair struct {
+		key   *yaml.Node
+		value *yaml.Node
+	}
+
+	pairs := make([]pair, 0, len(node.Content)/2)
+	present := map[string]bool{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		pairs = append(pairs, pair{key: node.Content[i], value: node.Content[i+1]})
+		present[node.Content[i].Value] = true
+	}
+
+	for _, p := range pairs {
+		name := layout.canonical(p.key.Value)
+		if name != p.key.Value && !present[name] {
+			present[name] = true
+			p.key.Value = name
+		}
+	}
+
+	slices.SortStableFunc(pairs, func(a, b pair) int {
+		ai, bi := layout.index(a.key.Value), layout.index(b.key.Value)
+		if ai < 0 {
+			ai = len(layout.order)
+		}
+		if bi < 0 {
+			bi = len(layout.order)
+		}
+		return ai - bi
+	})
+
+	node.Content = node.Content[:0]
+	for _, p := range pairs {
+		node.Content = append(node.Content, p.key, p.value)
+	}
+}
@@ -0,0 +1,128 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatCanonicalizesCastfile(t *testing.T) {
+	input := `# project header
+
+name: demo
+tasks:
+    # compiles the project
+    build:
+        run: go build ./...   # keep me
+        deps: [lint]
+        description: Build it
+        uses: bash
+    lint: echo lint
+env:
+  A: "1"
+id: demo
+inventory:
+  hosts:
+    web:
+      port: 22
+      host: web.local
+      x-custom: true
+`
+
+	want := `# project header
+
+id: demo
+name: demo
+inventory:
+  hosts:
+    web:
+      host: web.local
+      port: 22
+      x-custom: true
+env:
+  A: "1"
+tasks:
+  # compiles the project
+  build:
+    desc: Build it
+    uses: bash
+    needs: [lint]
+    run: go build ./... # keep me
+  lint: echo lint
+`
+
+	out, err := Format([]byte(input))
+	require.NoError(t, err)
+	require.Equal(t, want, string(out))
+
+	again, err := Format(out)
+	require.NoError(t, err)
+	require.Equal(t, string(out), string(again))
+}
+
+func TestFormatKeepsHeaderCommentAtTop(t *testing.T) {
+	input := `# top comment
+name: demo
+env:
+  A: "1"
+# the project id
+id: demo
+`
+
+	want := `# top comment
+# the project id
+id: demo
+name: demo
+env:
+  A: "1"
+`
+
+	out, err := Format([]byte(input))
+	require.NoError(t, err)
+	require.Equal(t, want, string(out))
+
+	again, err := Format(out)
+	require.NoError(t, err)
+	require.Equal(t, string(out), string(again))
+}
+
+func TestFormatKeepsAliasWhenCanonicalKeyExists(t *testing.T) {
+	input := `tasks:
+  build:
+    needs: [a]
+    deps: [b]
+`
+
+	out, err := Format([]byte(input))
+	require.NoError(t, err)
+	require.Contains(t, string(out), "deps: [b]")
+}
+
+func TestFormatInventoryFile(t *testing.T) {
+	input := `hosts:
+  - port: 2222
+    host: db.local
+defaults:
+  base:
+    tags: [prod]
+    user: deploy
+`
+
+	want := `defaults:
+  base:
+    user: deploy
+    tags: [prod]
+hosts:
+  - host: db.local
+    port: 2222
+`
+
+	out, err := Format([]byte(input))
+	require.NoError(t, err)
+	require.Equal(t, want, string(out))
+}
+
+func TestFormatRejectsNonMappingRoot(t *testing.T) {
+	_, err := Format([]byte("- a\n- b\n"))
+	require.Error(t, err)
+}
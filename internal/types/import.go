@@ -1,15 +1,26 @@
 package types
 
 import (
+	"strconv"
+	"strings"
+
 	"github.com/frostyeti/cast/internal/errors"
 	"go.yaml.in/yaml/v4"
 )
 
 // Import describes a module or task source to load into a project.
+// Tasks is an allowlist and Exclude a denylist of module task names; Rename
+// maps module task names to the names they are imported as. Override true
+// lets the importing project redefine imported tasks and call them through
+// `super`, false forbids redefining them, and nil lets project tasks shadow
+// imported tasks of the same name.
 type Import struct {
-	From      string   `json:"from,omitempty"`
-	Namespace string   `json:"namespace,omitempty"`
-	Tasks     []string `json:"tasks,omitempty"`
+	From      string            `json:"from,omitempty"`
+	Namespace string            `json:"namespace,omitempty"`
+	Tasks     []string          `json:"tasks,omitempty"`
+	Exclude   []string          `json:"exclude,omitempty"`
+	Rename    map[string]string `json:"rename,omitempty"`
+	Override  *bool             `json:"override,omitempty"`
 }
 
 // Imports is an ordered list of import definitions.
@@ -85,6 +96,34 @@ func (i *Import) UnmarshalYAML(node *yaml.Node) error {
 					tasks = append(tasks, taskNode.Value)
 				}
 				i.Tasks = tasks
+			case "exclude":
+				exclude, err := decodeStringOrStrings(valueNode, "exclude")
+				if err != nil {
+					return err
+				}
+				i.Exclude = exclude
+			case "rename":
+				if valueNode.Kind != yaml.MappingNode {
+					return errors.NewYamlError(valueNode, "expected yaml mapping for 'rename' field")
+				}
+				i.Rename = map[string]string{}
+				for k := 0; k < len(valueNode.Content); k += 2 {
+					oldNode := valueNode.Content[k]
+					newNode := valueNode.Content[k+1]
+					if newNode.Kind != yaml.ScalarNode || strings.TrimSpace(newNode.Value) == "" {
+						return errors.NewYamlError(newNode, "expected a task name for 'rename' entry "+oldNode.Value)
+					}
+					i.Rename[oldNode.Value] = strings.TrimSpace(newNode.Value)
+				}
+			case "override":
+				if valueNode.Kind != yaml.ScalarNode {
+					return errors.NewYamlError(valueNode, "expected yaml scalar for 'override' field")
+				}
+				override, err := strconv.ParseBool(valueNode.Value)
+				if err != nil {
+					return errors.NewYamlError(valueNode, "expected boolean for 'override' field")
+				}
+				i.Override = &override
 			default:
 				return errors.YamlErrorf(keyNode, "unexpected field '%s' in Import", key)
 			}
@@ -0,0 +1,281 @@
+package types
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/frostyeti/cast/internal/errors"
+	"go.yaml.in/yaml/v4"
+)
+
+// FileError is an error raised while reading a file other than the one being
+// loaded, such as a project include. Nested yaml errors keep their line and
+// column, so the pair identifies the exact position of the problem.
+type FileError struct {
+	File string
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	return e.File + ": " + e.Err.Error()
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// loadIncludes expands the project include globs relative to the castfile
+// and merges the tasks, jobs, env and inventory of every matched file into
+// the project. Entries that are already defined are reported as errors.
+func (p *Project) loadIncludes() error {
+	if len(p.Include) == 0 {
+		return nil
+	}
+
+	dir := filepath.Dir(p.File)
+	for _, pattern := range p.Include {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return errors.Newf("invalid include pattern %q: %v", pattern, err)
+		}
+
+		for _, file := range matches {
+			if file == p.File || slices.Contains(p.IncludedFiles, file) {
+				continue
+			}
+			if stat, err := os.Stat(file); err != nil || stat.IsDir() {
+				continue
+			}
+
+			if err := p.mergeInclude(file); err != nil {
+				return &FileError{File: file, Err: err}
+			}
+			p.IncludedFiles = append(p.IncludedFiles, file)
+		}
+	}
+
+	return nil
+}
+
+func (p *Project) mergeInclude(file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	node := &doc
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	// empty include files are allowed so a glob can match placeholders.
+	if node.Kind == 0 || node.Kind == yaml.DocumentNode {
+		return nil
+	}
+
+	if node.Kind != yaml.MappingNode {
+		return errors.NewYamlError(node, "include file must be a mapping node.")
+	}
+
+	for i := 0; i < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		valueNode := node.Content[i+1]
+
+		var err error
+		switch keyNode.Value {
+		case "tasks":
+			err = p.includeTasks(file, valueNode)
+		case "jobs":
+			err = p.includeJobs(file, valueNode)
+		case "env":
+			err = p.includeEnv(valueNode)
+		case "inventory":
+			err = p.includeInventory(valueNode)
+		default:
+			err = errors.YamlErrorf(keyNode, "include files may only define tasks, jobs, env and inventory, found %q", keyNode.Value)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// singleEntry returns a mapping node holding only the entry at index i of a
+// mapping so entries can be decoded and merged one at a time.
+func singleEntry(node *yaml.Node, i int) *yaml.Node {
+	return &yaml.Node{
+		Kind:    yaml.MappingNode,
+		Tag:     node.Tag,
+		Line:    node.Line,
+		Column:  node.Column,
+		Content: node.Content[i : i+2],
+	}
+}
+
+func (p *Project) includeTasks(file string, node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return errors.NewYamlError(node, "include tasks must be a mapping node.")
+	}
+
+	if p.Tasks == nil {
+		p.Tasks = NewTaskMap()
+	}
+
+	for i := 0; i < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		tasks := NewTaskMap()
+		if err := singleEntry(node, i).Decode(tasks); err != nil {
+			return errors.NewYamlError(node, "failed to decode include tasks: "+err.Error())
+		}
+		tasks.SetFile(file)
+
+		for _, task := range tasks.Values() {
+			if existing, ok := p.Tasks.Get(task.Id); ok {
+				return errors.YamlErrorf(keyNode, "task %q is already defined in %s", task.Name, existing.Location())
+			}
+			if err := p.Tasks.CheckAliases(&task); err != nil {
+				return errors.WithYamlNode(err, keyNode)
+			}
+			p.Tasks.Add(&task)
+		}
+	}
+
+	return nil
+}
+
+func (p *Project) includeJobs(file string, node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return errors.NewYamlError(node, "include jobs must be a mapping node.")
+	}
+
+	if p.Jobs == nil {
+		p.Jobs = NewJobMap()
+	}
+
+	for i := 0; i < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		jobs := NewJobMap()
+		if err := singleEntry(node, i).Decode(jobs); err != nil {
+			return errors.NewYamlError(node, "failed to decode include jobs: "+err.Error())
+		}
+		jobs.SetFile(file)
+
+		for _, job := range jobs.Values() {
+			if !p.Jobs.Add(&job) {
+				return errors.YamlErrorf(keyNode, "job %q is already defined", job.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p *Project) includeEnv(node *yaml.Node) error {
+	env := NewEnv()
+	if err := node.Decode(env); err != nil {
+		return errors.NewYamlError(node, "failed to decode include env: "+err.Error())
+	}
+
+	if p.Env == nil {
+		p.Env = NewEnv()
+	}
+
+	for _, key := range env.Keys() {
+		if p.Env.Has(key) {
+			return errors.YamlErrorf(mappingKey(node, key), "env %q is already defined", key)
+		}
+	}
+
+	p.Env.Merge(env)
+	return nil
+}
+
+func (p *Project) includeInventory(node *yaml.Node) error {
+	inventory := &Inventory{}
+	if err := node.Decode(inventory); err != nil {
+		return errors.NewYamlError(node, "failed to decode include inventory: "+err.Error())
+	}
+
+	if p.Inventory == nil {
+		p.Inventory = &Inventory{
+			Defaults: InventoryDefaults{},
+			Hosts:    map[string]HostInfo{},
+		}
+	}
+
+	for name, defaults := range inventory.Defaults {
+		if _, ok := p.Inventory.Defaults[name]; ok {
+			return errors.YamlErrorf(mappingKey(mappingValue(node, "defaults"), name), "inventory defaults %q is already defined", name)
+		}
+		p.Inventory.Defaults[name] = defaults
+	}
+
+	for _, name := range inventory.HostOrder {
+		if _, ok := p.Inventory.Hosts[name]; ok {
+			return errors.YamlErrorf(inventoryHostNode(mappingValue(node, "hosts"), name), "inventory host %q is already defined", name)
+		}
+		p.Inventory.Hosts[name] = inventory.Hosts[name]
+		p.Inventory.HostOrder = append(p.Inventory.HostOrder, name)
+	}
+
+	return nil
+}
+
+// mappingKey returns the key node named key inside a mapping, or the mapping
+// itself when the key cannot be found.
+func mappingKey(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				return node.Content[i]
+			}
+		}
+	}
+
+	return node
+}
+
+// mappingValue returns the value node of key inside a mapping, or the mapping
+// itself when the key cannot be found.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				return node.Content[i+1]
+			}
+		}
+	}
+
+	return node
+}
+
+// inventoryHostNode returns the node that names host in an inventory hosts
+// mapping or sequence, or hosts itself when it cannot be found.
+func inventoryHostNode(hosts *yaml.Node, host string) *yaml.Node {
+	if hosts.Kind != yaml.SequenceNode {
+		return mappingKey(hosts, host)
+	}
+
+	for _, item := range hosts.Content {
+		if item.Kind == yaml.MappingNode {
+			if value := mappingValue(item, "host"); value != item && value.Value == host {
+				return value
+			}
+		}
+	}
+
+	return hosts
+}
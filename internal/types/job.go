@@ -7,18 +7,21 @@ import (
 
 // Job is an experimental pipeline definition made of ordered steps.
 type Job struct {
-	Id      string   `json:"id"`
-	Name    string   `json:"name,omitempty"`
-	Desc    string   `json:"desc,omitempty"`
-	Needs   *Needs   `json:"needs,omitempty"`
-	Steps   []Step   `json:"steps,omitempty"`
-	Env     *Env     `json:"env,omitempty"`
-	DotEnv  *DotEnvs `json:"dotenv,omitempty"`
-	If      *string  `json:"if,omitempty"`
-	Timeout *string  `json:"timeout,omitempty"`
-	Cwd     *string  `json:"cwd,omitempty"`
-	Extends *string  `json:"extends,omitempty" yaml:"extends,omitempty"`
-	Cron    *string  `json:"cron,omitempty"`
+	Id          string          `json:"id"`
+	Name        string          `json:"name,omitempty"`
+	Desc        string          `json:"desc,omitempty"`
+	Needs       *Needs          `json:"needs,omitempty"`
+	Steps       []Step          `json:"steps,omitempty"`
+	Env         *Env            `json:"env,omitempty"`
+	DotEnv      *DotEnvs        `json:"dotenv,omitempty"`
+	EnvRequired EnvRequirements `json:"env-required,omitempty"`
+	Vars        *Vars           `json:"vars,omitempty"`
+	If          *string         `json:"if,omitempty"`
+	Timeout     *string         `json:"timeout,omitempty"`
+	Cwd         *string         `json:"cwd,omitempty"`
+	Extends     []string        `json:"extends,omitempty" yaml:"extends,omitempty"`
+	Cron        *string         `json:"cron,omitempty"`
+	File        string          `yaml:"-" json:"-"`
 }
 
 // NewJob returns an empty job.
@@ -76,6 +79,18 @@ func (j *Job) UnmarshalYAML(node *yaml.Node) error {
 			if err != nil {
 				return errors.YamlErrorf(valueNode, "failed to decode job dotenv: %v", err)
 			}
+		case "env-required", "env_required", "envRequired":
+			j.EnvRequired = EnvRequirements{}
+			err := valueNode.Decode(&j.EnvRequired)
+			if err != nil {
+				return err
+			}
+		case "vars":
+			j.Vars = NewVars()
+			err := valueNode.Decode(j.Vars)
+			if err != nil {
+				return err
+			}
 		case "if":
 			ifStr := valueNode.Value
 			j.If = &ifStr
@@ -86,8 +101,11 @@ func (j *Job) UnmarshalYAML(node *yaml.Node) error {
 			cwdStr := valueNode.Value
 			j.Cwd = &cwdStr
 		case "extends":
-			extendsStr := valueNode.Value
-			j.Extends = &extendsStr
+			extends, err := decodeStringOrStrings(valueNode, "extends")
+			if err != nil {
+				return err
+			}
+			j.Extends = extends
 		case "cron":
 			cronStr := valueNode.Value
 			j.Cron = &cronStr
@@ -183,6 +183,21 @@ func (t *JobMap) Add(entry *Job) bool {
 	return true
 }
 
+// SetFile records file as the definition source of every job that does not
+// already have one.
+func (t *JobMap) SetFile(file string) {
+	if t == nil || t.values == nil {
+		return
+	}
+
+	for k, job := range t.values {
+		if job.File == "" {
+			job.File = file
+			t.values[k] = job
+		}
+	}
+}
+
 func (t *JobMap) Get(name string) (Job, bool) {
 	if t == nil {
 		t = NewJobMap()
@@ -11,21 +11,26 @@ import (
 // Module is an experimental reusable Cast module definition.
 // Modules may be imported into a project or another module.
 type Module struct {
-	Id        string     `yaml:"id,omitempty" json:"id,omitempty"`
-	Name      string     `yaml:"name,omitempty" json:"name,omitempty"`
-	Version   string     `yaml:"version,omitempty" json:"version,omitempty"`
-	Desc      string     `yaml:"description,omitempty" json:"description,omitempty"`
-	Imports   *Imports   `yaml:"imports,omitempty" json:"imports,omitempty"`
-	Env       *Env       `yaml:"env,omitempty" json:"env,omitempty"`
-	DotEnv    *DotEnvs   `yaml:"dotenv,omitempty" json:"dotenv,omitempty"`
-	Paths     *Paths     `yaml:"paths,omitempty" json:"paths,omitempty"`
-	Meta      *Meta      `yaml:"meta,omitempty" json:"meta,omitempty"`
-	Tasks     *TaskMap   `yaml:"tasks,omitempty" json:"tasks,omitempty"`
-	Inventory *Inventory `yaml:"inventory,omitempty" json:"inventory,omitempty"`
-	File      string     `yaml:"-" json:"-"`
-	Dir       string     `yaml:"-" json:"-"`
-	TaskNames []string   `yaml:"-" json:"-"`
-	Namespace string     `yaml:"-" json:"-"`
+	Id        string            `yaml:"id,omitempty" json:"id,omitempty"`
+	Name      string            `yaml:"name,omitempty" json:"name,omitempty"`
+	Version   string            `yaml:"version,omitempty" json:"version,omitempty"`
+	Desc      string            `yaml:"description,omitempty" json:"description,omitempty"`
+	Requires  *Requires         `yaml:"requires,omitempty" json:"requires,omitempty"`
+	Imports   *Imports          `yaml:"imports,omitempty" json:"imports,omitempty"`
+	Env       *Env              `yaml:"env,omitempty" json:"env,omitempty"`
+	DotEnv    *DotEnvs          `yaml:"dotenv,omitempty" json:"dotenv,omitempty"`
+	Paths     *Paths            `yaml:"paths,omitempty" json:"paths,omitempty"`
+	Meta      *Meta             `yaml:"meta,omitempty" json:"meta,omitempty"`
+	Tasks     *TaskMap          `yaml:"tasks,omitempty" json:"tasks,omitempty"`
+	Inventory *Inventory        `yaml:"inventory,omitempty" json:"inventory,omitempty"`
+	File      string            `yaml:"-" json:"-"`
+	Dir       string            `yaml:"-" json:"-"`
+	TaskNames []string          `yaml:"-" json:"-"`
+	Exclude   []string          `yaml:"-" json:"-"`
+	Rename    map[string]string `yaml:"-" json:"-"`
+	Override  *bool             `yaml:"-" json:"-"`
+	Namespace string            `yaml:"-" json:"-"`
+	From      string            `yaml:"-" json:"-"`
 }
 
 func (m *Module) UnmarshalYAML(node *yaml.Node) error {
@@ -67,6 +72,11 @@ func (m *Module) UnmarshalYAML(node *yaml.Node) error {
 				return errors.NewYamlError(valueNode, "module description must be a scalar.")
 			}
 			m.Desc = valueNode.Value
+		case "requires":
+			m.Requires = &Requires{}
+			if err := valueNode.Decode(m.Requires); err != nil {
+				return errors.NewYamlError(valueNode, "failed to decode module requires: "+err.Error())
+			}
 		case "imports":
 			imports := &Imports{}
 			err := imports.UnmarshalYAML(valueNode)
@@ -144,6 +154,7 @@ func (m *Module) ReadFromYaml(file string) error {
 
 	m.File = file
 	m.Dir = filepath.Dir(file)
+	m.Tasks.SetFile(file)
 
 	return nil
 }
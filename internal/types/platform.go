@@ -0,0 +1,125 @@
+package types
+
+import (
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// platformOS and platformArch are the platform tasks are resolved for. They
+// are variables so tests can simulate other platforms.
+var (
+	platformOS   = runtime.GOOS
+	platformArch = runtime.GOARCH
+)
+
+// platformSuffixes returns the task name suffixes that select a variant for
+// the current platform, most specific first.
+func platformSuffixes() []string {
+	return []string{platformOS + "-" + platformArch, platformOS, platformArch}
+}
+
+// NormalizeOS maps common operating system aliases to GOOS values.
+func NormalizeOS(value string) string {
+	switch v := strings.ToLower(strings.TrimSpace(value)); v {
+	case "win", "win32", "win64":
+		return "windows"
+	case "mac", "macos", "osx":
+		return "darwin"
+	default:
+		return v
+	}
+}
+
+// NormalizeArch maps common architecture aliases to GOARCH values.
+func NormalizeArch(value string) string {
+	switch v := strings.ToLower(strings.TrimSpace(value)); v {
+	case "x64", "x86_64", "x86-64":
+		return "amd64"
+	case "x86", "i386", "i686":
+		return "386"
+	case "aarch64":
+		return "arm64"
+	default:
+		return v
+	}
+}
+
+// platformOSes and platformArches are the GOOS and GOARCH values a task name
+// suffix can select. js, wasip1 and wasm are left out: cast does not run there,
+// and names such as test:js are common task names.
+var (
+	platformOSes   = []string{"aix", "android", "darwin", "dragonfly", "freebsd", "illumos", "ios", "linux", "netbsd", "openbsd", "plan9", "solaris", "windows"}
+	platformArches = []string{"386", "amd64", "arm", "arm64", "loong64", "mips", "mips64", "mips64le", "mipsle", "ppc64", "ppc64le", "riscv64", "s390x"}
+)
+
+// parsePlatformSuffix returns the os and arch a variant suffix such as
+// linux-arm64, windows or amd64 selects.
+func parsePlatformSuffix(suffix string) (goos, goarch string, ok bool) {
+	if slices.Contains(platformOSes, suffix) {
+		return suffix, "", true
+	}
+	if slices.Contains(platformArches, suffix) {
+		return "", suffix, true
+	}
+
+	goos, goarch, found := strings.Cut(suffix, "-")
+	if found && slices.Contains(platformOSes, goos) && slices.Contains(platformArches, goarch) {
+		return goos, goarch, true
+	}
+
+	return "", "", false
+}
+
+// platformSelectors returns the os and arch selectors of the task. A variant
+// such as build:windows without selectors of its own only applies to the
+// platform its name selects.
+func (t Task) platformSelectors() (oses, arches []string) {
+	if len(t.OS) > 0 || len(t.Arch) > 0 {
+		return t.OS, t.Arch
+	}
+
+	name := t.Name
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		if goos, goarch, ok := parsePlatformSuffix(name[i+1:]); ok {
+			if goos != "" {
+				oses = []string{goos}
+			}
+			if goarch != "" {
+				arches = []string{goarch}
+			}
+		}
+	}
+
+	return oses, arches
+}
+
+// SupportsPlatform reports whether the task's os and arch selectors allow it
+// to run on goos/goarch. Tasks without selectors run everywhere.
+func (t Task) SupportsPlatform(goos, goarch string) bool {
+	oses, arches := t.platformSelectors()
+	if len(oses) > 0 && !slices.Contains(oses, goos) {
+		return false
+	}
+
+	if len(arches) > 0 && !slices.Contains(arches, goarch) {
+		return false
+	}
+
+	return true
+}
+
+// PlatformRequirement describes the task's os and arch selectors, such as
+// "os windows" or "os linux, darwin; arch arm64".
+func (t Task) PlatformRequirement() string {
+	oses, arches := t.platformSelectors()
+	parts := []string{}
+	if len(oses) > 0 {
+		parts = append(parts, "os "+strings.Join(oses, ", "))
+	}
+	if len(arches) > 0 {
+		parts = append(parts, "arch "+strings.Join(arches, ", "))
+	}
+
+	return strings.Join(parts, "; ")
+}
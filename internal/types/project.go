@@ -9,6 +9,14 @@ import (
 	"go.yaml.in/yaml/v4"
 )
 
+// Ways remote git tasks and modules are fetched. FetchAuto uses git when it
+// is installed and falls back to the hosting provider's archive downloads.
+const (
+	FetchAuto    = "auto"
+	FetchGit     = "git"
+	FetchArchive = "archive"
+)
+
 // Project is the parsed root Castfile configuration.
 // It accepts both `description` and `desc`.
 type Project struct {
@@ -16,10 +24,15 @@ type Project struct {
 	Name           string           `yaml:"name,omitempty" json:"name,omitempty"`
 	Version        string           `yaml:"version,omitempty" json:"version,omitempty"`
 	Desc           string           `yaml:"description,omitempty" json:"description,omitempty"`
+	Requires       *Requires        `yaml:"requires,omitempty" json:"requires,omitempty"`
 	Subcmds        []string         `yaml:"subcmds,omitempty" json:"subcmds,omitempty"`
 	Imports        *Imports         `yaml:"imports,omitempty" json:"imports,omitempty"`
+	Include        []string         `yaml:"include,omitempty" json:"include,omitempty"`
 	Env            *Env             `yaml:"env,omitempty" json:"env,omitempty"`
 	DotEnv         *DotEnvs         `yaml:"dotenv,omitempty" json:"dotenv,omitempty"`
+	EnvRequired    EnvRequirements  `yaml:"env-required,omitempty" json:"env-required,omitempty"`
+	Vars           *Vars            `yaml:"vars,omitempty" json:"vars,omitempty"`
+	Contexts       Contexts         `yaml:"contexts,omitempty" json:"contexts,omitempty"`
 	Paths          *Paths           `yaml:"paths,omitempty" json:"paths,omitempty"`
 	Defaults       *ProjectDefaults `yaml:"defaults,omitempty" json:"defaults,omitempty"`
 	Config         *ProjectConfig   `yaml:"config,omitempty" json:"config,omitempty"`
@@ -31,8 +44,12 @@ type Project struct {
 	Inventory      *Inventory       `yaml:"inventory,omitempty" json:"inventory,omitempty"`
 	Inventories    []string         `yaml:"inventories,omitempty" json:"inventories,omitempty"`
 	TrustedSources []string         `yaml:"trusted_sources,omitempty" json:"trusted_sources,omitempty"`
+	SignedSources  []TrustedSource  `yaml:"-" json:"-"`
+	Registry       string           `yaml:"registry,omitempty" json:"registry,omitempty"`
+	Fetch          string           `yaml:"fetch,omitempty" json:"fetch,omitempty"`
 	Modules        []Module         `yaml:"-" json:"-"`
 	File           string           `yaml:"-" json:"-"`
+	IncludedFiles  []string         `yaml:"-" json:"-"`
 }
 
 // NewProject returns an empty project.
@@ -78,6 +95,11 @@ func (p *Project) UnmarshalYAML(node *yaml.Node) error {
 				return errors.NewYamlError(valueNode, "project description must be a scalar.")
 			}
 			p.Desc = valueNode.Value
+		case "requires":
+			p.Requires = &Requires{}
+			if err := valueNode.Decode(p.Requires); err != nil {
+				return errors.NewYamlError(valueNode, "failed to decode project requires: "+err.Error())
+			}
 		case "subcmds", "subcommands":
 			if valueNode.Kind != yaml.SequenceNode {
 				return errors.NewYamlError(valueNode, "project subcmds must be a sequence.")
@@ -131,6 +153,32 @@ func (p *Project) UnmarshalYAML(node *yaml.Node) error {
 			if err != nil {
 				return errors.NewYamlError(valueNode, "failed to decode project dotenv: "+err.Error())
 			}
+		case "env-required", "env_required", "envRequired":
+			p.EnvRequired = EnvRequirements{}
+			err := valueNode.Decode(&p.EnvRequired)
+			if err != nil {
+				return errors.NewYamlError(valueNode, "failed to decode project env-required: "+err.Error())
+			}
+		case "vars":
+			p.Vars = NewVars()
+			err := valueNode.Decode(p.Vars)
+			if err != nil {
+				return errors.NewYamlError(valueNode, "failed to decode project vars: "+err.Error())
+			}
+		case "contexts":
+			if valueNode.Kind != yaml.MappingNode {
+				return errors.NewYamlError(valueNode, "project contexts must be a mapping node.")
+			}
+			p.Contexts = Contexts{}
+			for j := 0; j < len(valueNode.Content); j += 2 {
+				name := valueNode.Content[j].Value
+				ctx := Context{}
+				if err := valueNode.Content[j+1].Decode(&ctx); err != nil {
+					return errors.NewYamlError(valueNode.Content[j+1], "failed to decode project context "+name+": "+err.Error())
+				}
+				ctx.Name = name
+				p.Contexts[name] = ctx
+			}
 		case "paths":
 			p.Paths = &Paths{}
 			err := valueNode.Decode(p.Paths)
@@ -186,6 +234,20 @@ func (p *Project) UnmarshalYAML(node *yaml.Node) error {
 			if err != nil {
 				return errors.NewYamlError(valueNode, "failed to decode project inventory: "+err.Error())
 			}
+		case "include", "includes":
+			switch valueNode.Kind {
+			case yaml.ScalarNode:
+				p.Include = append(p.Include, valueNode.Value)
+			case yaml.SequenceNode:
+				for _, item := range valueNode.Content {
+					if item.Kind != yaml.ScalarNode {
+						return errors.NewYamlError(item, "project include entries must be scalar strings.")
+					}
+					p.Include = append(p.Include, item.Value)
+				}
+			default:
+				return errors.NewYamlError(valueNode, "project include must be a scalar or sequence.")
+			}
 		case "inventories":
 			if valueNode.Kind != yaml.SequenceNode {
 				return errors.NewYamlError(valueNode, "project inventories must be a sequence.")
@@ -198,8 +260,31 @@ func (p *Project) UnmarshalYAML(node *yaml.Node) error {
 				return errors.NewYamlError(valueNode, "project trusted_sources must be a sequence.")
 			}
 			for _, item := range valueNode.Content {
-				p.TrustedSources = append(p.TrustedSources, item.Value)
+				source := TrustedSource{}
+				if err := item.Decode(&source); err != nil {
+					return err
+				}
+				// every entry allows its pattern; entries with keys also
+				// require signatures from sources that match it.
+				p.TrustedSources = append(p.TrustedSources, source.Source)
+				if len(source.Keys) > 0 {
+					p.SignedSources = append(p.SignedSources, source)
+				}
+			}
+		case "registry":
+			if valueNode.Kind != yaml.ScalarNode {
+				return errors.NewYamlError(valueNode, "project registry must be a scalar.")
+			}
+			p.Registry = strings.TrimSpace(valueNode.Value)
+		case "fetch":
+			if valueNode.Kind != yaml.ScalarNode {
+				return errors.NewYamlError(valueNode, "project fetch must be a scalar.")
 			}
+			fetch := strings.ToLower(strings.TrimSpace(valueNode.Value))
+			if fetch != FetchAuto && fetch != FetchGit && fetch != FetchArchive {
+				return errors.YamlErrorf(valueNode, "project fetch must be %s, %s or %s", FetchAuto, FetchGit, FetchArchive)
+			}
+			p.Fetch = fetch
 		default:
 			continue
 		}
@@ -229,6 +314,8 @@ func (p *Project) ReadFromYaml(file string) error {
 	}
 
 	p.File = file
+	p.Tasks.SetFile(file)
+	p.Jobs.SetFile(file)
 
-	return nil
+	return p.loadIncludes()
 }
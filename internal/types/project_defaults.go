@@ -1,6 +1,216 @@
 package types
 
-// ProjectDefaults holds project-wide defaults.
+import (
+	"slices"
+	"strings"
+
+	"github.com/frostyeti/cast/internal/errors"
+	"go.yaml.in/yaml/v4"
+)
+
+// ProjectDefaults holds project-wide defaults. The task fields apply to every
+// project task that does not set them; Handlers holds additional defaults for
+// tasks whose `uses` matches the handler name, such as `docker` or `ssh`.
 type ProjectDefaults struct {
-	Shell *string `yaml:"shell,omitempty" json:"shell,omitempty"`
+	Shell        *string `yaml:"shell,omitempty" json:"shell,omitempty"`
+	TaskDefaults `yaml:",inline"`
+	Handlers     map[string]TaskDefaults `yaml:"handlers,omitempty" json:"handlers,omitempty"`
+}
+
+// TaskDefaults holds the task fields that can be defaulted project-wide.
+type TaskDefaults struct {
+	Uses     *string  `yaml:"uses,omitempty" json:"uses,omitempty"`
+	Cwd      *string  `yaml:"cwd,omitempty" json:"cwd,omitempty"`
+	Timeout  *string  `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	Retry    *int     `yaml:"retry,omitempty" json:"retry,omitempty"`
+	Template *string  `yaml:"template,omitempty" json:"template,omitempty"`
+	Env      *Env     `yaml:"env,omitempty" json:"env,omitempty"`
+	DotEnv   []string `yaml:"dotenv,omitempty" json:"dotenv,omitempty"`
+	Hosts    []string `yaml:"hosts,omitempty" json:"hosts,omitempty"`
+	With     *With    `yaml:"with,omitempty" json:"with,omitempty"`
+}
+
+func (p *ProjectDefaults) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		return errors.NewYamlError(value, "expected yaml mapping for 'defaults'")
+	}
+
+	// the task fields sit next to shell and handlers, so everything else is
+	// decoded, and checked for unknown keys, as task defaults.
+	taskFields := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: value.Line, Column: value.Column}
+	for i := 0; i < len(value.Content); i += 2 {
+		keyNode := value.Content[i]
+		valueNode := value.Content[i+1]
+
+		switch keyNode.Value {
+		case "shell":
+			if valueNode.Kind != yaml.ScalarNode {
+				return errors.NewYamlError(valueNode, "expected yaml scalar for 'shell' field")
+			}
+			p.Shell = &valueNode.Value
+		case "handlers":
+			if valueNode.Kind != yaml.MappingNode {
+				return errors.NewYamlError(valueNode, "expected yaml mapping for 'handlers' field")
+			}
+			p.Handlers = map[string]TaskDefaults{}
+			for j := 0; j < len(valueNode.Content); j += 2 {
+				handler := TaskDefaults{}
+				if err := handler.UnmarshalYAML(valueNode.Content[j+1]); err != nil {
+					return err
+				}
+				p.Handlers[valueNode.Content[j].Value] = handler
+			}
+		default:
+			taskFields.Content = append(taskFields.Content, keyNode, valueNode)
+		}
+	}
+
+	return p.TaskDefaults.UnmarshalYAML(taskFields)
+}
+
+func (d *TaskDefaults) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		return errors.NewYamlError(value, "expected yaml mapping for task defaults")
+	}
+
+	for i := 0; i < len(value.Content); i += 2 {
+		keyNode := value.Content[i]
+		valueNode := value.Content[i+1]
+
+		key := keyNode.Value
+		switch key {
+		case "uses":
+			if valueNode.Kind != yaml.ScalarNode {
+				return errors.NewYamlError(valueNode, "expected yaml scalar for 'uses' field")
+			}
+			d.Uses = &valueNode.Value
+		case "cwd":
+			if valueNode.Kind != yaml.ScalarNode {
+				return errors.NewYamlError(valueNode, "expected yaml scalar for 'cwd' field")
+			}
+			d.Cwd = &valueNode.Value
+		case "timeout":
+			if valueNode.Kind != yaml.ScalarNode {
+				return errors.NewYamlError(valueNode, "expected yaml scalar for 'timeout' field")
+			}
+			d.Timeout = &valueNode.Value
+		case "retry":
+			retry, err := decodeRetry(valueNode)
+			if err != nil {
+				return err
+			}
+			d.Retry = &retry
+		case "template":
+			if valueNode.Kind != yaml.ScalarNode {
+				return errors.NewYamlError(valueNode, "expected yaml scalar for 'template' field")
+			}
+			v := strings.TrimSpace(valueNode.Value)
+			if strings.EqualFold(v, "true") || v == "1" {
+				v = "gotmpl"
+			}
+			d.Template = &v
+		case "env":
+			env := Env{}
+			if err := valueNode.Decode(&env); err != nil {
+				return err
+			}
+			d.Env = &env
+		case "dotenv":
+			dotenv, err := decodeDefaultsList(valueNode, key)
+			if err != nil {
+				return err
+			}
+			d.DotEnv = dotenv
+		case "hosts":
+			hosts, err := decodeDefaultsList(valueNode, key)
+			if err != nil {
+				return err
+			}
+			d.Hosts = hosts
+		case "with":
+			with := NewWith()
+			if err := valueNode.Decode(with); err != nil {
+				return err
+			}
+			d.With = with
+		default:
+			return errors.YamlErrorf(keyNode, "unexpected field '%s' in defaults", key)
+		}
+	}
+
+	return nil
+}
+
+func decodeDefaultsList(node *yaml.Node, key string) ([]string, error) {
+	if node.Kind != yaml.SequenceNode {
+		return nil, errors.YamlErrorf(node, "expected yaml sequence for '%s' field", key)
+	}
+
+	items := make([]string, 0, len(node.Content))
+	for _, item := range node.Content {
+		if item.Kind != yaml.ScalarNode {
+			return nil, errors.YamlErrorf(item, "expected yaml scalar in '%s' list", key)
+		}
+		items = append(items, item.Value)
+	}
+
+	return items, nil
+}
+
+// Apply fills the fields task leaves unset from d. Env and with values from
+// d are merged under the task's own values and dotenv files are loaded
+// before the task's files.
+func (d TaskDefaults) Apply(task *Task) {
+	if (task.Uses == nil || *task.Uses == "") && d.Uses != nil {
+		uses := *d.Uses
+		task.Uses = &uses
+	}
+
+	if (task.Cwd == nil || *task.Cwd == "") && d.Cwd != nil {
+		cwd := *d.Cwd
+		task.Cwd = &cwd
+	}
+
+	if (task.Timeout == nil || *task.Timeout == "") && d.Timeout != nil {
+		timeout := *d.Timeout
+		task.Timeout = &timeout
+	}
+
+	if task.Retry == nil && d.Retry != nil {
+		retry := *d.Retry
+		task.Retry = &retry
+	}
+
+	if (task.Template == nil || *task.Template == "") && d.Template != nil {
+		template := *d.Template
+		task.Template = &template
+	}
+
+	if len(task.Hosts) == 0 && len(d.Hosts) > 0 {
+		task.Hosts = append([]string{}, d.Hosts...)
+	}
+
+	if len(d.DotEnv) > 0 {
+		dotenv := append([]string{}, d.DotEnv...)
+		for _, de := range task.DotEnv {
+			if !slices.Contains(dotenv, de) {
+				dotenv = append(dotenv, de)
+			}
+		}
+		task.DotEnv = dotenv
+	}
+
+	if d.Env != nil {
+		e := d.Env.Clone()
+		e.Merge(task.Env)
+		task.Env = e
+	}
+
+	if d.With != nil && d.With.Len() > 0 {
+		with := d.With.Clone()
+		if task.With != nil {
+			with.Merge(task.With)
+		}
+		task.With = with
+	}
 }
@@ -1,8 +1,10 @@
 package types_test
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/frostyeti/cast/internal/types"
@@ -47,6 +49,80 @@ tasks:
 	}
 }
 
+func TestProjectTrustedSourcesWithKeys(t *testing.T) {
+	yamlData := `
+trusted_sources:
+  - jsr:*
+  - source: gh:our-org/*
+    keys:
+      - ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl release
+  - pattern: reg:*
+    key: ed25519:4yqqeRXOubRJ0bpQ6ioouxpuAfkL2iRaLR2HaX0Yomk=
+`
+	var p types.Project
+	if err := yaml.Unmarshal([]byte(yamlData), &p); err != nil {
+		t.Fatalf("failed to unmarshal project: %v", err)
+	}
+
+	if len(p.TrustedSources) != 3 || p.TrustedSources[1] != "gh:our-org/*" {
+		t.Fatalf("expected every entry to be an allowed pattern, got %v", p.TrustedSources)
+	}
+	if len(p.SignedSources) != 2 || p.SignedSources[0].Source != "gh:our-org/*" || len(p.SignedSources[1].Keys) != 1 {
+		t.Fatalf("expected the keyed entries to require signatures, got %+v", p.SignedSources)
+	}
+
+	if err := yaml.Unmarshal([]byte("trusted_sources:\n  - keys: [abc]\n"), &p); err == nil {
+		t.Fatal("expected an entry without a source to be rejected")
+	}
+
+	err := yaml.Unmarshal([]byte("trusted_sources:\n  - source: gh:our-org/*\n    kyes: [abc]\n"), &p)
+	if err == nil || !strings.Contains(err.Error(), "unexpected field 'kyes'") {
+		t.Fatalf("expected a misspelled keys field to be rejected, got %v", err)
+	}
+}
+
+func TestProjectFetch(t *testing.T) {
+	var p types.Project
+	if err := yaml.Unmarshal([]byte("fetch: Archive\n"), &p); err != nil {
+		t.Fatalf("failed to unmarshal project: %v", err)
+	}
+	if p.Fetch != types.FetchArchive {
+		t.Fatalf("expected fetch to be %q, got %q", types.FetchArchive, p.Fetch)
+	}
+
+	if err := yaml.Unmarshal([]byte("fetch: svn\n"), &p); err == nil {
+		t.Fatal("expected an unknown fetch mode to be rejected")
+	}
+}
+
+func TestProjectDefaultsRejectUnknownFields(t *testing.T) {
+	var p types.Project
+	content := "defaults:\n  timeout: 10m\n  retry: 2\n  handlers:\n    docker:\n      with:\n        volumes: [\"./cache:/cache\"]\n"
+	if err := yaml.Unmarshal([]byte(content), &p); err != nil {
+		t.Fatalf("failed to unmarshal project: %v", err)
+	}
+	if p.Defaults.Timeout == nil || *p.Defaults.Timeout != "10m" {
+		t.Fatalf("expected default timeout, got %v", p.Defaults.Timeout)
+	}
+	if p.Defaults.Retry == nil || *p.Defaults.Retry != 2 {
+		t.Fatalf("expected default retry, got %v", p.Defaults.Retry)
+	}
+	if _, ok := p.Defaults.Handlers["docker"]; !ok {
+		t.Fatal("expected docker handler defaults")
+	}
+
+	for _, content := range []string{
+		"defaults:\n  timout: 10m\n",
+		"defaults:\n  retires: 3\n",
+		"defaults:\n  handlers:\n    ssh:\n      max-parallel: 4\n",
+	} {
+		err := yaml.Unmarshal([]byte(content), &p)
+		if err == nil || !strings.Contains(err.Error(), "unexpected field") {
+			t.Fatalf("expected unknown defaults field to be rejected for %q, got %v", content, err)
+		}
+	}
+}
+
 func TestProjectReadFromYaml(t *testing.T) {
 	tmpDir := t.TempDir()
 	yamlFile := filepath.Join(tmpDir, "castfile.yaml")
@@ -130,3 +206,205 @@ tasks:
 		t.Fatalf("unexpected subcmds: %#v", p.Subcmds)
 	}
 }
+
+func TestProjectReadFromYamlMergesIncludes(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tmpDir, "tasks"), 0o755); err != nil {
+		t.Fatalf("failed to create tasks dir: %v", err)
+	}
+
+	files := map[string]string{
+		"castfile.yaml": `name: demo
+include:
+  - tasks/*.yaml
+env:
+  A: "1"
+tasks:
+  build:
+    run: echo build
+`,
+		"tasks/test.yaml": `env:
+  B: "2"
+tasks:
+  test:
+    needs: [build]
+    run: echo test
+jobs:
+  ci:
+    steps: [build, test]
+inventory:
+  hosts:
+    web:
+      host: web.local
+`,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(data), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	p := types.NewProject()
+	if err := p.ReadFromYaml(filepath.Join(tmpDir, "castfile.yaml")); err != nil {
+		t.Fatalf("failed to read from yaml: %v", err)
+	}
+
+	task, ok := p.Tasks.Get("test")
+	if !ok {
+		t.Fatalf("expected included task 'test'")
+	}
+	includeFile := filepath.Join(tmpDir, "tasks", "test.yaml")
+	if task.File != includeFile || task.Line != 4 {
+		t.Errorf("expected task defined at %s:4, got %s", includeFile, task.Location())
+	}
+	if keys := p.Tasks.Keys(); len(keys) != 2 || keys[0] != "build" {
+		t.Errorf("expected castfile tasks before included tasks, got %v", keys)
+	}
+	if _, ok := p.Jobs.Get("ci"); !ok {
+		t.Errorf("expected included job 'ci'")
+	}
+	if p.Env.Get("A") != "1" || p.Env.Get("B") != "2" {
+		t.Errorf("expected merged env, got %v", p.Env.ToMap())
+	}
+	if _, ok := p.Inventory.Hosts["web"]; !ok {
+		t.Errorf("expected included inventory host 'web'")
+	}
+	if len(p.IncludedFiles) != 1 || p.IncludedFiles[0] != includeFile {
+		t.Errorf("expected included files [%s], got %v", includeFile, p.IncludedFiles)
+	}
+}
+
+func TestProjectReadFromYamlRejectsDuplicateIncludedTask(t *testing.T) {
+	tmpDir := t.TempDir()
+	castfile := filepath.Join(tmpDir, "castfile.yaml")
+	include := filepath.Join(tmpDir, "more.yaml")
+	if err := os.WriteFile(castfile, []byte("include: more.yaml\ntasks:\n  build:\n    run: echo one\n"), 0o644); err != nil {
+		t.Fatalf("failed to write castfile: %v", err)
+	}
+	if err := os.WriteFile(include, []byte("tasks:\n  lint: echo lint\n  Build:\n    run: echo two\n"), 0o644); err != nil {
+		t.Fatalf("failed to write include: %v", err)
+	}
+
+	err := types.NewProject().ReadFromYaml(castfile)
+	if err == nil {
+		t.Fatalf("expected duplicate task error")
+	}
+
+	var fileErr *types.FileError
+	if !errors.As(err, &fileErr) || fileErr.File != include {
+		t.Fatalf("expected error for %s, got %v", include, err)
+	}
+	want := `task "Build" is already defined in ` + castfile + ":3:3 on line 3, column 3"
+	if fileErr.Err.Error() != want {
+		t.Errorf("expected %q, got %q", want, fileErr.Err.Error())
+	}
+}
+
+func TestProjectReadFromYamlRejectsDuplicateIncludedHostAtItsKey(t *testing.T) {
+	tmpDir := t.TempDir()
+	castfile := filepath.Join(tmpDir, "castfile.yaml")
+	include := filepath.Join(tmpDir, "more.yaml")
+	if err := os.WriteFile(castfile, []byte("include: more.yaml\ninventory:\n  hosts:\n    web:\n      host: web.local\n"), 0o644); err != nil {
+		t.Fatalf("failed to write castfile: %v", err)
+	}
+	if err := os.WriteFile(include, []byte("inventory:\n  hosts:\n    db:\n      host: db.local\n    web:\n      host: other.local\n"), 0o644); err != nil {
+		t.Fatalf("failed to write include: %v", err)
+	}
+
+	err := types.NewProject().ReadFromYaml(castfile)
+	var fileErr *types.FileError
+	if !errors.As(err, &fileErr) || fileErr.File != include {
+		t.Fatalf("expected error for %s, got %v", include, err)
+	}
+	want := `inventory host "web" is already defined on line 5, column 5`
+	if fileErr.Err.Error() != want {
+		t.Errorf("expected %q, got %q", want, fileErr.Err.Error())
+	}
+}
+
+func TestProjectReadFromYamlRecordsIncludedJobFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	castfile := filepath.Join(tmpDir, "castfile.yaml")
+	include := filepath.Join(tmpDir, "jobs.yaml")
+	if err := os.WriteFile(castfile, []byte("include: jobs.yaml\njobs:\n  ci:\n    steps: [build]\n"), 0o644); err != nil {
+		t.Fatalf("failed to write castfile: %v", err)
+	}
+	if err := os.WriteFile(include, []byte("jobs:\n  nightly:\n    steps: [build]\n"), 0o644); err != nil {
+		t.Fatalf("failed to write include: %v", err)
+	}
+
+	p := types.NewProject()
+	if err := p.ReadFromYaml(castfile); err != nil {
+		t.Fatalf("failed to read from yaml: %v", err)
+	}
+
+	for name, file := range map[string]string{"ci": castfile, "nightly": include} {
+		job, ok := p.Jobs.Get(name)
+		if !ok || job.File != file {
+			t.Errorf("expected job %s defined in %s, got %q", name, file, job.File)
+		}
+	}
+}
+
+func TestProjectContextsRejectUnknownFields(t *testing.T) {
+	var p types.Project
+	err := yaml.Unmarshal([]byte("contexts:\n  prod:\n    envs:\n      TIER: prod\n"), &p)
+	if err == nil || !strings.Contains(err.Error(), "unexpected field 'envs'") {
+		t.Fatalf("expected an unknown context field to be rejected, got %v", err)
+	}
+}
+
+func TestProjectContextsResolveExtendsChain(t *testing.T) {
+	yamlData := `
+contexts:
+  base:
+    env:
+      REGION: us-east
+    hosts: web
+  staging:
+    extends: base
+    vars:
+      replicas: 1
+  prod:
+    extends: staging
+    inventories: [./prod.yaml]
+  loop-a:
+    extends: loop-b
+  loop-b:
+    extends: loop-a
+  orphan:
+    extends: missing
+`
+	var p types.Project
+	if err := yaml.Unmarshal([]byte(yamlData), &p); err != nil {
+		t.Fatalf("failed to unmarshal project: %v", err)
+	}
+
+	chain, err := p.Contexts.Chain("prod")
+	if err != nil {
+		t.Fatalf("failed to resolve chain: %v", err)
+	}
+	names := []string{}
+	for _, ctx := range chain {
+		names = append(names, ctx.Name)
+	}
+	if len(names) != 3 || names[0] != "base" || names[1] != "staging" || names[2] != "prod" {
+		t.Fatalf("expected base -> staging -> prod, got %v", names)
+	}
+	if len(chain[0].Hosts) != 1 || chain[0].Hosts[0] != "web" {
+		t.Errorf("expected scalar hosts to decode, got %v", chain[0].Hosts)
+	}
+
+	chain, err = p.Contexts.Chain("dev")
+	if err != nil || len(chain) != 0 {
+		t.Errorf("expected an empty chain for a context without a block, got %v, %v", chain, err)
+	}
+
+	if _, err := p.Contexts.Chain("loop-a"); err == nil {
+		t.Errorf("expected an error for a circular extends chain")
+	}
+
+	if _, err := p.Contexts.Chain("orphan"); err == nil {
+		t.Errorf("expected an error for an undefined parent context")
+	}
+}
@@ -0,0 +1,92 @@
+package types
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/frostyeti/cast/internal/errors"
+	"go.yaml.in/yaml/v4"
+)
+
+// SchemaVersion is the newest castfile schema version this build of cast
+// understands. Files that require a newer schema fail to load.
+const SchemaVersion = 1
+
+// Requires declares the minimum cast release and schema version a castfile,
+// module or cast.task file needs. A scalar value is shorthand for `cast`.
+type Requires struct {
+	Cast   string `yaml:"cast,omitempty" json:"cast,omitempty"`
+	Schema int    `yaml:"schema,omitempty" json:"schema,omitempty"`
+}
+
+func (r *Requires) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		r.Cast = strings.TrimSpace(node.Value)
+		return nil
+	}
+
+	if node.Kind != yaml.MappingNode {
+		return errors.NewYamlError(node, "requires must be a scalar or mapping node.")
+	}
+
+	for i := 0; i < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		valueNode := node.Content[i+1]
+
+		switch keyNode.Value {
+		case "cast":
+			if valueNode.Kind != yaml.ScalarNode {
+				return errors.NewYamlError(valueNode, "requires cast must be a scalar.")
+			}
+			r.Cast = strings.TrimSpace(valueNode.Value)
+		case "schema":
+			if valueNode.Kind != yaml.ScalarNode {
+				return errors.NewYamlError(valueNode, "requires schema must be a scalar.")
+			}
+			schema, err := strconv.Atoi(strings.TrimSpace(valueNode.Value))
+			if err != nil || schema < 1 {
+				return errors.YamlErrorf(valueNode, "requires schema must be a positive integer, got %q", valueNode.Value)
+			}
+			r.Schema = schema
+		}
+	}
+
+	return nil
+}
+
+// ReadRequires returns the `requires` block of the castfile, module or
+// cast.task file at file without decoding anything else, so a file written
+// for a newer cast can be rejected with an upgrade message before unknown
+// fields fail the strict decode. It returns nil when the file sets none or
+// cannot be parsed; the full decode reports those errors.
+func ReadRequires(file string) (*Requires, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(data, doc); err != nil || len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "requires" {
+			continue
+		}
+
+		requires := &Requires{}
+		if err := root.Content[i+1].Decode(requires); err != nil {
+			return nil, err
+		}
+		return requires, nil
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,648 @@
+package types
+
+import (
+	"strings"
+
+	"github.com/frostyeti/cast/internal/errors"
+)
+
+// Schema is the subset of JSON Schema draft-07 used to describe cast files.
+type Schema struct {
+	SchemaURI            string             `json:"$schema,omitempty"`
+	ID                   string             `json:"$id,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	Minimum              *int               `json:"minimum,omitempty"`
+	Maximum              *int               `json:"maximum,omitempty"`
+	Default              any                `json:"default,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	PatternProperties    map[string]*Schema `json:"patternProperties,omitempty"`
+	AdditionalProperties any                `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Definitions          map[string]*Schema `json:"definitions,omitempty"`
+}
+
+// SchemaKinds lists the documents JSONSchema can describe.
+var SchemaKinds = []string{"castfile", "task", "module", "inventory"}
+
+// SchemaFile returns the file name under schemas/ for a schema kind.
+func SchemaFile(kind string) string {
+	switch kind {
+	case "castfile":
+		return "castfile.schema.json"
+	case "task":
+		return "cast.spell.schema.json"
+	case "module":
+		return "cast.module.schema.json"
+	case "inventory":
+		return "cast.inventory.schema.json"
+	}
+
+	return ""
+}
+
+// schemaField declares a mapping key along with every alias the decoder
+// accepts for it. Aliases are emitted as their own properties so editors
+// validate exactly what cast reads.
+type schemaField struct {
+	name    string
+	aliases []string
+	schema  *Schema
+}
+
+func field(name string, schema *Schema, aliases ...string) schemaField {
+	return schemaField{name: name, aliases: aliases, schema: schema}
+}
+
+func schemaObject(desc string, additional any, fields ...schemaField) *Schema {
+	s := &Schema{
+		Type:                 "object",
+		Description:          desc,
+		Properties:           map[string]*Schema{},
+		AdditionalProperties: additional,
+	}
+
+	for _, f := range fields {
+		s.Properties[f.name] = f.schema
+		for _, alias := range f.aliases {
+			aliasSchema := *f.schema
+			aliasSchema.Description = "Alias for `" + f.name + "`."
+			s.Properties[alias] = &aliasSchema
+		}
+	}
+
+	return s
+}
+
+func schemaString(desc string) *Schema {
+	return &Schema{Type: "string", Description: desc}
+}
+
+func schemaBool(desc string) *Schema {
+	return &Schema{Type: "boolean", Description: desc}
+}
+
+func schemaPort() *Schema {
+	minimum, maximum := 1, 65535
+	return &Schema{Type: "integer", Minimum: &minimum, Maximum: &maximum}
+}
+
+func schemaRef(name string) *Schema {
+	return &Schema{Ref: "#/definitions/" + name}
+}
+
+func schemaArray(desc string, items *Schema) *Schema {
+	return &Schema{Type: "array", Description: desc, Items: items}
+}
+
+func schemaStrings(desc string) *Schema {
+	return schemaArray(desc, &Schema{Type: "string"})
+}
+
+func schemaAnyOf(desc string, options ...*Schema) *Schema {
+	return &Schema{Description: desc, AnyOf: options}
+}
+
+// schemaStringOrStrings describes fields that accept a scalar shorthand for a
+// single entry or a list.
+func schemaStringOrStrings(desc string) *Schema {
+	return schemaAnyOf(desc, &Schema{Type: "string"}, schemaStrings(""))
+}
+
+func schemaBoolOrString(desc string) *Schema {
+	return schemaAnyOf(desc, &Schema{Type: "boolean"}, &Schema{Type: "string"})
+}
+
+func schemaMapOf(desc string, values any) *Schema {
+	return &Schema{Type: "object", Description: desc, AdditionalProperties: values}
+}
+
+const (
+	schemaIDPattern       = "^[a-z0-9-]+$"
+	schemaSubcmdPattern   = "^[a-zA-Z0-9_\\-:.]+$"
+	schemaTaskNamePattern = "^[a-zA-Z0-9_\\-:.@/]+$"
+)
+
+func schemaTaskHandlers() []string {
+	return []string{"docker", "bash", "pwsh", "python", "powershell", "sh", "node", "bun", "deno", "ruby", "ssh", "scp", "tmpl", "shell", "go", "dotnet", "csharp", "golang", "cast"}
+}
+
+// schemaDefinitions returns every named definition shared by the cast file
+// schemas. The field and alias lists mirror the UnmarshalYAML methods in this
+// package; schema_test.go fails when they drift apart.
+func schemaDefinitions() map[string]*Schema {
+	host := schemaObject("", false, hostFields()...)
+	host.Required = []string{"host"}
+
+	requirement := schemaObject("", false,
+		field("name", schemaString("Variable name.")),
+		field("type", schemaRef("env-requirement-type")),
+		field("pattern", schemaString("Regular expression the value must match."), "regex", "match"),
+		field("values", schemaStrings("Allowed values."), "enum", "allowed"),
+		field("desc", schemaString("Why the variable is needed."), "description"),
+	)
+
+	envVar := schemaObject("", false,
+		field("name", schemaString("Variable name.")),
+		field("value", schemaString("Variable value.")),
+		field("secret", &Schema{Type: "boolean", Default: false, Description: "Mask the value in output."}),
+	)
+
+	dotenv := schemaObject("", false,
+		field("path", schemaString("Path to the dotenv file.")),
+		field("os", schemaString("Optional target OS filter.")),
+		field("contexts", schemaStringOrStrings("Contexts the file applies to.")),
+	)
+	dotenv.Required = []string{"path"}
+
+	path := schemaObject("", false,
+		field("value", schemaString("Path entry."), "path"),
+		field("windows", schemaString("Path entry used on Windows."), "win", "win32"),
+		field("linux", schemaString("Path entry used on Linux."), "unix"),
+		field("darwin", schemaString("Path entry used on macOS."), "mac", "macos", "osx"),
+		field("os", &Schema{Type: "string", Enum: []string{"windows", "linux", "darwin"}}),
+		field("append", &Schema{Type: "boolean", Default: false, Description: "Append instead of prepend to PATH."}),
+	)
+
+	need := schemaObject("", false,
+		field("id", &Schema{Type: "string", Pattern: schemaTaskNamePattern}),
+		field("parallel", schemaBool("Run alongside the previous dependency.")),
+	)
+	need.Required = []string{"id"}
+
+	webhook := schemaObject("Experimental webhook trigger.", false,
+		field("id", schemaString("")),
+		field("job", schemaString("Job to run.")),
+		field("task", schemaString("Task to run.")),
+		field("secret", schemaString("Shared secret used to verify payload signatures.")),
+		field("token", schemaString("Token callers must send.")),
+	)
+
+	task := schemaObject("Task definition.", false, taskFields()...)
+
+	step := schemaObject("", true,
+		field("task", schemaString("Task to run.")),
+		field("id", schemaString("")),
+		field("name", schemaString("")),
+		field("run", schemaString("")),
+		field("uses", schemaString("")),
+		field("with", schemaMapOf("", true)),
+		field("env", schemaRef("env")),
+		field("cwd", schemaString("")),
+		field("desc", schemaString("")),
+		field("force", schemaBoolOrString("")),
+	)
+
+	job := schemaObject("Experimental job definition.", false, jobFields()...)
+
+	return map[string]*Schema{
+		"project-config": schemaObject("Parser/runtime configuration for the project.", true,
+			field("context", schemaString("Default task context name used for context-specific lookups.")),
+			field("contexts", schemaArray("Available context names for this project. Used for discoverability and shell completion.", &Schema{Type: "string", MinLength: intPtr(1)})),
+			field("substitution", &Schema{Type: "boolean", Default: true, Description: "Enable or disable environment substitution while evaluating task values."}),
+			field("shell", schemaString("Default task `uses` value when a task omits `uses` or sets it to an empty string. Falls back to `CAST_DEFAULT_SHELL`, then `shell`.")),
+		),
+		"project-defaults": schemaObject("Project-wide defaults. Task fields apply to every project task that does not set them.", false,
+			append([]schemaField{field("shell", schemaString("Default shell to use for shell-like tasks."))},
+				append(taskDefaultsFields(),
+					field("handlers", schemaMapOf("Task defaults keyed by the `uses` handler, such as `docker` or `ssh`. Handler defaults take precedence over project-wide task defaults.", schemaRef("task-defaults"))),
+				)...)...,
+		),
+		"task-defaults": schemaObject("Task defaults.", false, taskDefaultsFields()...),
+		"workspace": schemaAnyOf("Workspace discovery config. Accepts a boolean toggle or a mapping.",
+			schemaBool("`true` enables workspace discovery, `false` disables it."),
+			schemaObject("", false,
+				field("include", schemaStringOrStrings("Glob patterns to include while scanning for nested projects.")),
+				field("exclude", schemaStringOrStrings("Glob patterns to exclude while scanning for nested projects.")),
+				field("aliases", schemaMapOf("Named workspace aliases mapped to paths. Aliases must be valid project aliases.", &Schema{Type: "string"})),
+			),
+		),
+		"imports": schemaAnyOf("Import reusable modules or task packs.",
+			schemaString("Single import source."),
+			schemaArray("", schemaRef("import")),
+		),
+		"requires": schemaAnyOf("Minimum cast release and schema version needed to load the file. A string is shorthand for `cast`.",
+			schemaString("Cast version constraint such as `>=0.9`."),
+			schemaObject("", false,
+				field("cast", schemaString("Cast version constraint such as `>=0.9` or `>=0.9, <2`. A bare version means at least that version.")),
+				field("schema", &Schema{Type: "integer", Minimum: intPtr(1), Description: "Castfile schema version the file is written against."}),
+			),
+		),
+		"import": schemaAnyOf("Import shorthand or object form.",
+			schemaString("Source path or URI."),
+			schemaObject("", false,
+				field("from", schemaString("Source path or URI.")),
+				field("namespace", schemaString("Namespace prefix to apply to imported task names."), "ns"),
+				field("tasks", schemaStrings("Subset of tasks to import from the module.")),
+				field("exclude", schemaStringOrStrings("Module tasks to leave out.")),
+				field("rename", schemaMapOf("Module task names mapped to the names they are imported as, before the namespace is applied.", &Schema{Type: "string", Pattern: schemaTaskNamePattern})),
+				field("override", schemaBool("`true` keeps imported tasks the project redefines available as `<task>:base`, referenced as `super` in `extends` and `needs`; `false` makes redefining them an error. Unset, project tasks shadow imported tasks of the same name.")),
+			),
+		),
+		"env": schemaAnyOf("Environment variables in mapping or ordered list form.",
+			schemaMapOf("", schemaAnyOf("", &Schema{Type: "string"}, envVar)),
+			schemaArray("", schemaAnyOf("",
+				schemaString("`NAME=VALUE` or `NAME:VALUE` for secret entries."),
+				envVar,
+			)),
+		),
+		"env-required": schemaAnyOf("Variables that must be present after all dotenv/env layering. Accepts a name, a list of names or objects, or a map of names to a type or object.",
+			schemaString("Variable name."),
+			schemaArray("", schemaRef("env-requirement")),
+			schemaMapOf("", schemaAnyOf("", schemaRef("env-requirement-type"), requirement)),
+		),
+		"env-requirement": schemaAnyOf("", schemaString("Variable name."), requirement),
+		"env-requirement-type": {
+			Type: "string",
+			Enum: []string{"string", "int", "integer", "number", "float", "bool", "boolean", "url", "uri", "path", "required", "true", ""},
+		},
+		"dotenvs": schemaAnyOf("Ordered dotenv file list. Prefix or suffix a path with `?` to make it optional.",
+			schemaString("Single dotenv file path."),
+			schemaArray("", schemaRef("dotenv")),
+		),
+		"dotenv": schemaAnyOf("Dotenv shorthand or mapping form.",
+			schemaString("Path to a dotenv file. `?path` or `path?` makes it optional."),
+			dotenv,
+		),
+		"paths": schemaAnyOf("Ordered PATH entries.",
+			schemaString("Single path entry."),
+			schemaArray("", schemaRef("path")),
+		),
+		"path": schemaAnyOf("PATH shorthand or mapping form.",
+			schemaString("Path entry string."),
+			path,
+		),
+		"trusted-source": schemaAnyOf("Trusted source pattern, or a pattern with the keys its sources must be signed with.",
+			schemaString("Pattern matched against remote `uses` values, such as `gh:org/*`."),
+			schemaObject("", false,
+				field("source", schemaString("Pattern matched against remote `uses` values, such as `gh:org/*`."), "pattern"),
+				field("keys", schemaStringOrStrings("ed25519 public keys, as `ssh-ed25519 AAAA...` or `ed25519:<base64>`. Matching sources must carry a cast.sig signed by one of them."), "key"),
+			),
+		),
+		"inventory":     schemaObject("Inventory host definitions and named defaults.", false, inventoryFields()...),
+		"host-defaults": schemaObject("Reusable host defaults.", false, hostDefaultsFields()...),
+		"host": schemaAnyOf("Host shorthand or mapping form.",
+			schemaString("`user@host:port`, `host:port`, or `host` shorthand."),
+			host,
+		),
+		"os-info": schemaAnyOf("Operating system information. A scalar sets the platform.",
+			schemaString("Platform name."),
+			schemaObject("", false,
+				field("platform", schemaString("`windows`, `linux`, or `darwin`; `win`, `mac`, `macos`, and `osx` are accepted.")),
+				field("arch", schemaString("")),
+				field("variant", schemaString("")),
+				field("family", schemaString("")),
+				field("codename", schemaString("")),
+				field("version", schemaString("")),
+				field("buildVersion", schemaString(""), "build_version", "build-version"),
+			),
+		),
+		"tasks": {
+			Type:              "object",
+			Description:       "Map of task names to task definitions. A scalar value is shorthand for `run`.",
+			PatternProperties: map[string]*Schema{schemaTaskNamePattern: schemaRef("task")},
+		},
+		"task": schemaAnyOf("Task definition or a `run` shorthand string.",
+			schemaString("Shorthand for `run`."),
+			task,
+		),
+		"needs": schemaAnyOf("Dependency list. Accepts a single string/object or an array of them.",
+			schemaString("Single dependency id."),
+			schemaRef("need"),
+			schemaArray("", schemaAnyOf("", schemaString("Dependency id."), schemaRef("need"))),
+		),
+		"need": need,
+		"jobs": {
+			Type:              "object",
+			Description:       "Experimental job map.",
+			PatternProperties: map[string]*Schema{schemaTaskNamePattern: schemaRef("job")},
+		},
+		"job":  job,
+		"step": schemaAnyOf("Job step. A scalar becomes a task reference.", schemaString("Task name."), step),
+		"hooks": schemaAnyOf("Before/after hook task name suffixes. `true` enables `before` and `after`.",
+			&Schema{Type: "boolean"},
+			schemaObject("", true,
+				field("before", schemaStringOrStrings("")),
+				field("after", schemaStringOrStrings("")),
+			),
+		),
+		"merge-strategy": {
+			Type:        "string",
+			Enum:        []string{MergeAppend, MergeReplace},
+			Description: "`append` adds the task's values after the inherited values; `replace` uses the task's values when set and the inherited values otherwise.",
+		},
+		"meta": schemaMapOf("Free-form metadata.", true),
+		"vars": schemaMapOf("Typed values such as lists, maps and numbers exposed as `vars.*` in `if`, `force`, `timeout`, `cwd` and gotmpl templates. Strings that are a single `${{ expr }}` evaluate to the expression result; other strings interpolate `${{ }}` expressions. Vars are never exported to the process environment.", true),
+		"contexts": {
+			Type:              "object",
+			Description:       "Inline context blocks. The settings of the active context, and of the contexts it extends, are layered over the project settings.",
+			PatternProperties: map[string]*Schema{schemaSubcmdPattern: schemaRef("context")},
+		},
+		"context": schemaObject("Context settings.", false, contextFields()...),
+		"on": schemaObject("Project triggers such as schedules and webhooks.", false,
+			field("schedule", schemaRef("schedule")),
+			field("webhooks", schemaRef("webhooks")),
+		),
+		"schedule": schemaObject("", true,
+			field("crons", schemaStringOrStrings("One or more POSIX cron expressions."), "cron"),
+			field("timezone", schemaString("Optional IANA timezone name.")),
+		),
+		"webhooks": schemaMapOf("", schemaRef("webhook")),
+		"webhook":  webhook,
+	}
+}
+
+func taskFields() []schemaField {
+	with := schemaObject("Runner-specific options. For SSH tasks, `send-env: true` forwards task env variables with SSH `SendEnv`; otherwise env values are only available to templates such as `template: gotmpl`.", true,
+		field("send-env", schemaBool("SSH only. When true, send task env values to the remote session with SSH `SendEnv`. Default is false, and many SSH servers reject this unless `AcceptEnv` is configured.")),
+	)
+
+	return []schemaField{
+		field("id", &Schema{Type: "string", Pattern: schemaIDPattern, Description: "Stable task id. Prefer lowercase hyphenated ids."}),
+		field("name", schemaString("Display name; task key is used when omitted.")),
+		field("desc", schemaString("Short description shown in task lists."), "description"),
+		field("help", schemaString("Longer help text shown by `--help`.")),
+		field("aliases", schemaStringOrStrings("Other names the task can be invoked or referenced by. Aliases must not collide with task ids or other aliases."), "alias"),
+		field("os", schemaStringOrStrings("Operating systems the task runs on, such as `linux`, `darwin` or `windows`. On other platforms the task is skipped.")),
+		field("arch", schemaStringOrStrings("Architectures the task runs on, such as `amd64` or `arm64`. On other platforms the task is skipped.")),
+		field("private", schemaBool("Hide the task from `cast list`, subcommands and completion and reject direct CLI invocation. Private tasks still run as needs, hooks and job steps."), "internal"),
+		field("extends", schemaStringOrStrings("Tasks to inherit unset fields from, applied in order. Module tasks are referenced by their namespaced name, for example `shared:base`.")),
+		field("merge", schemaObject("How inherited list fields combine with the task's own values.", false,
+			field("args", schemaRef("merge-strategy")),
+			field("needs", schemaRef("merge-strategy")),
+			field("hosts", schemaRef("merge-strategy")),
+			field("dotenv", schemaRef("merge-strategy")),
+		)),
+		field("uses", schemaAnyOf("Built-in task runner or remote task/module URI.",
+			&Schema{Type: "string", Enum: schemaTaskHandlers()},
+			&Schema{Type: "string", Pattern: "^(github\\.com/|https?://|jsr:|npm:|@|\\./|\\.\\./|/).+"},
+		), "use"),
+		field("needs", schemaRef("needs"), "deps", "dependencies"),
+		field("hooks", schemaRef("hooks")),
+		field("if", schemaBoolOrString("Condition that must be true for the task to run."), "predicate"),
+		field("force", schemaBoolOrString("Run even when an earlier task failed.")),
+		field("hosts", schemaArray("Inventory hosts or tags to target.", &Schema{Type: "string", Pattern: schemaSubcmdPattern})),
+		field("cwd", schemaString("Working directory for the task.")),
+		field("timeout", schemaTimeout()),
+		field("retry", schemaRetry()),
+		field("dotenv", schemaRef("dotenvs"), "envfile", "env-file"),
+		field("env", schemaRef("env")),
+		field("env-required", schemaRef("env-required"), "env_required", "envRequired"),
+		field("vars", schemaRef("vars")),
+		field("with", with, "input", "inputs"),
+		field("args", schemaStrings("Arguments passed to the runner.")),
+		field("template", schemaAnyOf("", &Schema{Type: "boolean"}, &Schema{Type: "string", Enum: []string{"gotmpl"}})),
+		field("run", schemaString("Script or command to run.")),
+	}
+}
+
+func jobFields() []schemaField {
+	return []schemaField{
+		field("id", &Schema{Type: "string", Pattern: schemaIDPattern}),
+		field("name", schemaString("")),
+		field("desc", schemaString("")),
+		field("extends", schemaStringOrStrings("Jobs to inherit unset fields from, applied in order.")),
+		field("needs", schemaRef("needs")),
+		field("cron", schemaString("Legacy single-cron field; prefer `on.schedule.crons` for project-level cron triggers.")),
+		field("if", schemaString("")),
+		field("timeout", &Schema{Type: "string", Pattern: schemaTimeout().Pattern}),
+		field("cwd", schemaString("")),
+		field("dotenv", schemaRef("dotenvs")),
+		field("env", schemaRef("env")),
+		field("env-required", schemaRef("env-required"), "env_required", "envRequired"),
+		field("vars", schemaRef("vars")),
+		field("steps", schemaArray("", schemaRef("step"))),
+	}
+}
+
+func schemaTimeout() *Schema {
+	return &Schema{Type: "string", Pattern: "^[0-9]+(s|m|h)?$", Description: "Duration string such as `30s`, `5m`, or `1h`."}
+}
+
+func schemaRetry() *Schema {
+	return &Schema{Type: "integer", Minimum: intPtr(0), Description: "Number of times a failed or timed out task is run again."}
+}
+
+func hostDefaultsFields() []schemaField {
+	return []schemaField{
+		field("user", schemaString("SSH user.")),
+		field("port", schemaPort()),
+		field("identity", schemaString("SSH identity file path. `~` and environment expansion are supported."), "identityFile", "identity_file", "identity-file"),
+		field("password", schemaString("SSH password value. Environment expansion and optional command substitution are supported when project config enables substitution."), "password-variable", "passwordVariable", "password_variable"),
+		field("agent", schemaBool("Force use of the SSH agent. Cast errors clearly when the agent is required but unavailable.")),
+		field("tags", schemaStrings("Host tags used for targeting and filtering."), "groups"),
+		field("os", schemaRef("os-info")),
+		field("meta", schemaRef("meta")),
+	}
+}
+
+func hostFields() []schemaField {
+	return append([]schemaField{
+		field("host", schemaString("Host name or address.")),
+		field("defaults", schemaString("Name of an inventory `defaults` entry to inherit from.")),
+	}, hostDefaultsFields()...)
+}
+
+func inventoryFields() []schemaField {
+	return []schemaField{
+		field("defaults", &Schema{
+			Type:              "object",
+			Description:       "Named default host definitions.",
+			PatternProperties: map[string]*Schema{schemaSubcmdPattern: schemaRef("host-defaults")},
+		}),
+		field("hosts", schemaAnyOf("Hosts may be a mapping or a sequence of scalar/mapping host entries.",
+			schemaArray("", schemaRef("host")),
+			schemaMapOf("", schemaRef("host")),
+		)),
+	}
+}
+
+func taskDefaultsFields() []schemaField {
+	return []schemaField{
+		field("uses", schemaString("Default task handler.")),
+		field("cwd", schemaString("Default working directory.")),
+		field("timeout", schemaTimeout()),
+		field("retry", schemaRetry()),
+		field("template", schemaString("Default template engine for `run`.")),
+		field("hosts", schemaArray("Default inventory hosts or tags. Ignored when the active context sets `hosts`.", &Schema{Type: "string", Pattern: schemaSubcmdPattern})),
+		field("dotenv", schemaStrings("Dotenv files loaded before the task's own files.")),
+		field("env", schemaRef("env")),
+		field("with", schemaMapOf("Inputs merged under the task's own `with` values.", true)),
+	}
+}
+
+func contextFields() []schemaField {
+	return []schemaField{
+		field("extends", schemaString("Parent context whose settings are applied first.")),
+		field("inventories", schemaStringOrStrings("Inventory files merged after the project inventories.")),
+		field("hosts", schemaStringOrStrings("Default hosts or host tags for tasks that do not set `hosts`.")),
+		field("dotenv", schemaRef("dotenvs")),
+		field("env", schemaRef("env")),
+		field("vars", schemaRef("vars")),
+	}
+}
+
+func projectFields() []schemaField {
+	return []schemaField{
+		field("id", &Schema{Type: "string", Pattern: schemaIDPattern, Description: "Unique project id. Cast sanitizes and converts the value for server mode, so prefer lowercase hyphenated ids."}),
+		field("name", &Schema{Type: "string", MinLength: intPtr(1), Description: "Display name for the project. Used to derive ids when one is not provided."}),
+		field("version", schemaString("Project version string.")),
+		field("description", schemaString("Project description."), "desc"),
+		field("requires", schemaRef("requires")),
+		field("config", schemaRef("project-config")),
+		field("defaults", schemaRef("project-defaults")),
+		field("workspace", schemaRef("workspace")),
+		field("subcmds", schemaArray("List of task namespace prefixes to expose as CLI subcommands (for example `dn` or `dn:nuget`).", &Schema{Type: "string", Pattern: schemaSubcmdPattern}), "subcommands"),
+		field("trusted_sources", schemaArray("Allowlist of remote task/module sources. Each entry is matched against remote `uses` values before download.", schemaRef("trusted-source")), "trustedSources", "trusted-sources"),
+		field("registry", schemaString("URL of a spell registry: an `index.json` file or the directory that serves one. `reg:<name>@<version>` task refs resolve through it.")),
+		field("fetch", &Schema{Type: "string", Enum: []string{FetchAuto, FetchGit, FetchArchive}, Description: "How remote git tasks and modules are downloaded. `auto` (the default) clones with git when it is installed and otherwise downloads GitHub and GitLab refs as tarballs; `archive` always downloads tarballs."}),
+		field("imports", schemaRef("imports"), "import", "modules"),
+		field("include", schemaStringOrStrings("Globs, relative to the castfile, of files whose `tasks`, `jobs`, `env` and `inventory` are merged into the project without a namespace."), "includes"),
+		field("inventories", schemaStrings("Additional standalone inventory files to merge.")),
+		field("inventory", schemaRef("inventory")),
+		field("paths", schemaRef("paths")),
+		field("dotenv", schemaRef("dotenvs")),
+		field("env", schemaRef("env")),
+		field("env-required", schemaRef("env-required"), "env_required", "envRequired"),
+		field("vars", schemaRef("vars")),
+		field("contexts", schemaRef("contexts")),
+		field("on", schemaRef("on")),
+		field("meta", schemaRef("meta")),
+		field("tasks", schemaRef("tasks")),
+		field("jobs", schemaRef("jobs")),
+	}
+}
+
+func moduleFields() []schemaField {
+	return []schemaField{
+		field("id", &Schema{Type: "string", Pattern: schemaIDPattern, Description: "Module id. Prefer lowercase hyphenated ids."}),
+		field("name", schemaString("Module display name.")),
+		field("version", schemaString("Module version.")),
+		field("description", schemaString("Module description."), "desc"),
+		field("requires", schemaRef("requires")),
+		field("imports", schemaRef("imports")),
+		field("inventory", schemaRef("inventory")),
+		field("paths", schemaRef("paths")),
+		field("dotenv", schemaRef("dotenvs")),
+		field("env", schemaRef("env")),
+		field("meta", schemaRef("meta")),
+		field("tasks", schemaRef("tasks")),
+	}
+}
+
+func projectSchema() *Schema {
+	return schemaObject("", true, projectFields()...)
+}
+
+func moduleSchema() *Schema {
+	return schemaObject("Experimental reusable module format.", true, moduleFields()...)
+}
+
+func castTaskSchema() *Schema {
+	input := schemaObject("", false,
+		field("description", schemaString("Description of the input parameter.")),
+		field("default", schemaString("Default value if the input is not provided.")),
+		field("required", schemaBool("Whether this input must be provided by the caller.")),
+	)
+
+	runs := schemaObject("Defines how the task is executed.", false,
+		field("using", schemaAnyOf("The execution engine to use. Common values are `docker`, `deno`, `bun`, and `composite`; custom strings are allowed for experimental runners.",
+			&Schema{Type: "string", Enum: []string{"docker", "deno", "bun", "composite", "bash", "sh"}},
+			&Schema{Type: "string", MinLength: intPtr(1)},
+		)),
+		field("image", schemaString("The Docker image to use when `using: docker`.")),
+		field("args", schemaStrings("Arguments to pass to the execution engine.")),
+		field("main", schemaString("The main script file to execute when `using: deno` or `using: bun`.")),
+		field("steps", schemaArray("Steps to execute when `using: composite`.", schemaRef("task"))),
+	)
+	runs.Required = []string{"using"}
+
+	s := schemaObject("Experimental schema for a standalone cast.task remote definition.", false,
+		field("name", schemaString("The name of the task.")),
+		field("description", schemaString("A description of what the task does.")),
+		field("requires", schemaRef("requires")),
+		field("inputs", &Schema{
+			Type:                 "object",
+			Description:          "Inputs required or optional for the task to execute.",
+			PatternProperties:    map[string]*Schema{"^[a-zA-Z0-9_-]+$": input},
+			AdditionalProperties: false,
+		}),
+		field("runs", runs),
+	)
+	s.Required = []string{"name", "runs"}
+	return s
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+// JSONSchema returns the JSON schema for a cast file kind: castfile, task
+// (cast.task), module, or inventory. Only the definitions the document
+// references are included so each schema is self-contained.
+func JSONSchema(kind string) (*Schema, error) {
+	var root *Schema
+	title := ""
+	switch kind {
+	case "castfile":
+		root, title = projectSchema(), "Castfile Schema"
+	case "task":
+		root, title = castTaskSchema(), "Cast Task Schema"
+	case "module":
+		root, title = moduleSchema(), "Cast Module Schema"
+	case "inventory":
+		root, title = schemaDefinitions()["inventory"], "Cast Inventory Schema"
+	default:
+		return nil, errors.Newf("unknown schema kind %q, expected one of %s", kind, strings.Join(SchemaKinds, ", "))
+	}
+
+	root.SchemaURI = "https://json-schema.org/draft-07/schema"
+	root.ID = SchemaFile(kind)
+	root.Title = title
+	root.Definitions = referencedDefinitions(root, schemaDefinitions())
+	return root, nil
+}
+
+// referencedDefinitions collects the definitions reachable from root.
+func referencedDefinitions(root *Schema, all map[string]*Schema) map[string]*Schema {
+	used := map[string]*Schema{}
+	var walk func(s *Schema)
+	walk = func(s *Schema) {
+		if s == nil {
+			return
+		}
+
+		if name, ok := strings.CutPrefix(s.Ref, "#/definitions/"); ok {
+			if _, seen := used[name]; !seen {
+				if def, ok := all[name]; ok {
+					used[name] = def
+					walk(def)
+				}
+			}
+		}
+
+		walk(s.Items)
+		for _, child := range s.AnyOf {
+			walk(child)
+		}
+		for _, child := range s.Properties {
+			walk(child)
+		}
+		for _, child := range s.PatternProperties {
+			walk(child)
+		}
+		if additional, ok := s.AdditionalProperties.(*Schema); ok {
+			walk(additional)
+		}
+	}
+
+	walk(root)
+	return used
+}
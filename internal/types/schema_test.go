@@ -0,0 +1,188 @@
+package types
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// objectSchema returns the mapping form of a definition that may also accept
+// scalar shorthands.
+func objectSchema(s *Schema) *Schema {
+	if s == nil {
+		return nil
+	}
+
+	if s.Properties != nil {
+		return s
+	}
+
+	for _, option := range s.AnyOf {
+		if found := objectSchema(option); found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
+
+// decodedKeys returns the mapping keys each UnmarshalYAML method switches on,
+// keyed by receiver type.
+func decodedKeys(t *testing.T) map[string][]string {
+	t.Helper()
+
+	fset := token.NewFileSet()
+	files, err := filepath.Glob("*.go")
+	require.NoError(t, err)
+
+	keys := map[string][]string{}
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+
+		parsed, err := parser.ParseFile(fset, file, nil, 0)
+		require.NoError(t, err)
+
+		for _, decl := range parsed.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Name.Name != "UnmarshalYAML" || fn.Recv == nil || fn.Body == nil {
+				continue
+			}
+
+			recv := fn.Recv.List[0].Type
+			if star, ok := recv.(*ast.StarExpr); ok {
+				recv = star.X
+			}
+			typeName := recv.(*ast.Ident).Name
+
+			ast.Inspect(fn.Body, func(n ast.Node) bool {
+				sw, ok := n.(*ast.SwitchStmt)
+				if !ok || !isKeySwitch(sw.Tag) {
+					return true
+				}
+
+				for _, stmt := range sw.Body.List {
+					for _, expr := range stmt.(*ast.CaseClause).List {
+						lit, ok := expr.(*ast.BasicLit)
+						if !ok || lit.Kind != token.STRING {
+							continue
+						}
+						value, err := strconv.Unquote(lit.Value)
+						require.NoError(t, err)
+						keys[typeName] = append(keys[typeName], value)
+					}
+				}
+				return true
+			})
+		}
+	}
+
+	return keys
+}
+
+func isKeySwitch(tag ast.Expr) bool {
+	switch tag := tag.(type) {
+	case *ast.Ident:
+		return tag.Name == "key"
+	case *ast.SelectorExpr:
+		ident, ok := tag.X.(*ast.Ident)
+		return ok && ident.Name == "keyNode" && tag.Sel.Name == "Value"
+	}
+
+	return false
+}
+
+func TestSchemaCoversEveryDecodedKey(t *testing.T) {
+	defs := schemaDefinitions()
+	schemas := map[string]*Schema{
+		"Project":         projectSchema(),
+		"Module":          moduleSchema(),
+		"Task":            objectSchema(defs["task"]),
+		"Job":             defs["job"],
+		"Context":         defs["context"],
+		"HostInfo":        objectSchema(defs["host"]),
+		"HostDefaults":    defs["host-defaults"],
+		"Import":          objectSchema(defs["import"]),
+		"Requires":        objectSchema(defs["requires"]),
+		"Inventory":       defs["inventory"],
+		"Need":            defs["need"],
+		"On":              defs["on"],
+		"OsInfo":          objectSchema(defs["os-info"]),
+		"EnvPath":         objectSchema(defs["path"]),
+		"DotEnv":          objectSchema(defs["dotenv"]),
+		"EnvVarsVariable": objectSchema(defs["env"].AnyOf[1].Items),
+		"EnvRequirement":  objectSchema(defs["env-requirement"]),
+		"Hooks":           objectSchema(defs["hooks"]),
+		"ProjectConfig":   defs["project-config"],
+		"Schedule":        defs["schedule"],
+		"Webhook":         defs["webhook"],
+		"Workspace":       objectSchema(defs["workspace"]),
+		"TrustedSource":   objectSchema(defs["trusted-source"]),
+		"ProjectDefaults": defs["project-defaults"],
+		"TaskDefaults":    defs["task-defaults"],
+	}
+
+	for typeName, keys := range decodedKeys(t) {
+		schema, ok := schemas[typeName]
+		require.Truef(t, ok, "%s.UnmarshalYAML decodes keys %v but has no schema mapping in this test", typeName, keys)
+		require.NotNil(t, schema, typeName)
+
+		for _, key := range keys {
+			require.Containsf(t, schema.Properties, key, "schema for %s is missing key %q accepted by UnmarshalYAML", typeName, key)
+		}
+	}
+}
+
+func TestSchemaCoversTaggedStructFields(t *testing.T) {
+	castTask := castTaskSchema()
+	runs := castTask.Properties["runs"]
+	input := castTask.Properties["inputs"].PatternProperties["^[a-zA-Z0-9_-]+$"]
+
+	cases := map[reflect.Type]*Schema{
+		reflect.TypeFor[CastTask]():        castTask,
+		reflect.TypeFor[CastTaskRuns]():    runs,
+		reflect.TypeFor[CastTaskInput]():   input,
+		reflect.TypeFor[ProjectDefaults](): schemaDefinitions()["project-defaults"],
+		reflect.TypeFor[TaskDefaults]():    schemaDefinitions()["task-defaults"],
+	}
+
+	for typ, schema := range cases {
+		for i := range typ.NumField() {
+			name, _, _ := strings.Cut(typ.Field(i).Tag.Get("yaml"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			require.Containsf(t, schema.Properties, name, "schema for %s is missing key %q", typ.Name(), name)
+		}
+	}
+}
+
+func TestSchemaFilesMatchGeneratedSchemas(t *testing.T) {
+	for _, kind := range SchemaKinds {
+		schema, err := JSONSchema(kind)
+		require.NoError(t, err)
+
+		want, err := json.MarshalIndent(schema, "", "  ")
+		require.NoError(t, err)
+
+		file := filepath.Join("..", "..", "schemas", SchemaFile(kind))
+		got, err := os.ReadFile(file)
+		require.NoError(t, err)
+		require.Equalf(t, string(want)+"\n", string(got), "%s is out of date; regenerate it with `cast schema --write schemas`", file)
+	}
+}
+
+func TestJSONSchemaRejectsUnknownKind(t *testing.T) {
+	_, err := JSONSchema("nope")
+	require.Error(t, err)
+}
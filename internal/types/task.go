@@ -1,6 +1,8 @@
 package types
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/frostyeti/cast/internal/errors"
@@ -9,26 +11,37 @@ import (
 
 // Task is a runnable Cast task definition.
 type Task struct {
-	Id       string   `yaml:"id,omitempty" json:"id,omitempty"`
-	Name     string   `yaml:"name,omitempty" json:"name,omitempty"`
-	Slug     string   `yaml:"slug,omitempty" json:"slug,omitempty"`
-	Desc     *string  `yaml:"desc,omitempty" json:"desc,omitempty"`
-	Help     *string  `yaml:"help,omitempty" json:"help,omitempty"`
-	Env      *Env     `yaml:"env,omitempty" json:"env,omitempty"`
-	DotEnv   []string `yaml:"dotenv,omitempty" json:"dotenv,omitempty"`
-	Cwd      *string  `yaml:"cwd,omitempty" json:"cwd,omitempty"`
-	Timeout  *string  `yaml:"timeout,omitempty" json:"timeout,omitempty"`
-	Run      *string  `yaml:"run,omitempty" json:"run,omitempty"`
-	Uses     *string  `yaml:"uses,omitempty" json:"uses,omitempty"`
-	Args     []string `yaml:"args,omitempty" json:"args,omitempty"`
-	Needs    Needs    `yaml:"needs,omitempty" json:"needs,omitempty"`
-	With     *With    `yaml:"with,omitempty" json:"with,omitempty"`
-	Hosts    []string `yaml:"hosts,omitempty" json:"hosts,omitempty"`
-	If       *string  `yaml:"if,omitempty" json:"if,omitempty"`
-	Hooks    *Hooks   `yaml:"hooks,omitempty" json:"hooks,omitempty"`
-	Force    *string  `yaml:"force,omitempty" json:"force,omitempty"`
-	Extends  *string  `yaml:"extends,omitempty" json:"extends,omitempty"`
-	Template *string  `yaml:"template,omitempty" json:"template,omitempty"`
+	Id          string          `yaml:"id,omitempty" json:"id,omitempty"`
+	Name        string          `yaml:"name,omitempty" json:"name,omitempty"`
+	Slug        string          `yaml:"slug,omitempty" json:"slug,omitempty"`
+	Aliases     []string        `yaml:"aliases,omitempty" json:"aliases,omitempty"`
+	Private     bool            `yaml:"private,omitempty" json:"private,omitempty"`
+	OS          []string        `yaml:"os,omitempty" json:"os,omitempty"`
+	Arch        []string        `yaml:"arch,omitempty" json:"arch,omitempty"`
+	Desc        *string         `yaml:"desc,omitempty" json:"desc,omitempty"`
+	Help        *string         `yaml:"help,omitempty" json:"help,omitempty"`
+	Env         *Env            `yaml:"env,omitempty" json:"env,omitempty"`
+	DotEnv      []string        `yaml:"dotenv,omitempty" json:"dotenv,omitempty"`
+	EnvRequired EnvRequirements `yaml:"env-required,omitempty" json:"env-required,omitempty"`
+	Vars        *Vars           `yaml:"vars,omitempty" json:"vars,omitempty"`
+	Cwd         *string         `yaml:"cwd,omitempty" json:"cwd,omitempty"`
+	Timeout     *string         `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	Retry       *int            `yaml:"retry,omitempty" json:"retry,omitempty"`
+	Run         *string         `yaml:"run,omitempty" json:"run,omitempty"`
+	Uses        *string         `yaml:"uses,omitempty" json:"uses,omitempty"`
+	Args        []string        `yaml:"args,omitempty" json:"args,omitempty"`
+	Needs       Needs           `yaml:"needs,omitempty" json:"needs,omitempty"`
+	With        *With           `yaml:"with,omitempty" json:"with,omitempty"`
+	Hosts       []string        `yaml:"hosts,omitempty" json:"hosts,omitempty"`
+	If          *string         `yaml:"if,omitempty" json:"if,omitempty"`
+	Hooks       *Hooks          `yaml:"hooks,omitempty" json:"hooks,omitempty"`
+	Force       *string         `yaml:"force,omitempty" json:"force,omitempty"`
+	Extends     []string        `yaml:"extends,omitempty" json:"extends,omitempty"`
+	Merge       MergeStrategies `yaml:"merge,omitempty" json:"merge,omitempty"`
+	Template    *string         `yaml:"template,omitempty" json:"template,omitempty"`
+	File        string          `yaml:"-" json:"-"`
+	Line        int             `yaml:"-" json:"-"`
+	Column      int             `yaml:"-" json:"-"`
 }
 
 func (t *Task) UnmarshalYAML(value *yaml.Node) error {
@@ -105,10 +118,17 @@ func (t *Task) UnmarshalYAML(value *yaml.Node) error {
 			}
 			t.Force = &valueNode.Value
 		case "extends":
-			if valueNode.Kind != yaml.ScalarNode {
-				return errors.NewYamlError(valueNode, "expected yaml scalar for 'extends' field")
+			extends, err := decodeStringOrStrings(valueNode, "extends")
+			if err != nil {
+				return err
 			}
-			t.Extends = &valueNode.Value
+			t.Extends = extends
+		case "merge":
+			merge, err := decodeMergeStrategies(valueNode, taskMergeFields)
+			if err != nil {
+				return err
+			}
+			t.Merge = merge
 		case "template":
 			if valueNode.Kind != yaml.ScalarNode {
 				return errors.NewYamlError(valueNode, "expected yaml scalar for 'template' field")
@@ -143,6 +163,17 @@ func (t *Task) UnmarshalYAML(value *yaml.Node) error {
 				}
 				t.DotEnv = append(t.DotEnv, item.Value)
 			}
+		case "env-required", "env_required", "envRequired":
+			var required EnvRequirements
+			if err := valueNode.Decode(&required); err != nil {
+				return err
+			}
+			t.EnvRequired = required
+		case "vars":
+			t.Vars = NewVars()
+			if err := valueNode.Decode(t.Vars); err != nil {
+				return err
+			}
 		case "cwd":
 			if valueNode.Kind != yaml.ScalarNode {
 				return errors.NewYamlError(valueNode, "expected yaml scalar for 'cwd' field")
@@ -153,6 +184,12 @@ func (t *Task) UnmarshalYAML(value *yaml.Node) error {
 				return errors.NewYamlError(valueNode, "expected yaml scalar for 'timeout' field")
 			}
 			t.Timeout = &valueNode.Value
+		case "retry":
+			retry, err := decodeRetry(valueNode)
+			if err != nil {
+				return err
+			}
+			t.Retry = &retry
 		case "run":
 			if valueNode.Kind != yaml.ScalarNode {
 				return errors.NewYamlError(valueNode, "expected yaml scalar for 'run' field")
@@ -187,6 +224,39 @@ func (t *Task) UnmarshalYAML(value *yaml.Node) error {
 				return err
 			}
 			t.With = with
+		case "aliases", "alias":
+			aliases, err := decodeStringOrStrings(valueNode, "aliases")
+			if err != nil {
+				return err
+			}
+			t.Aliases = aliases
+		case "os":
+			values, err := decodeStringOrStrings(valueNode, "os")
+			if err != nil {
+				return err
+			}
+			t.OS = make([]string, 0, len(values))
+			for _, v := range values {
+				t.OS = append(t.OS, NormalizeOS(v))
+			}
+		case "arch":
+			values, err := decodeStringOrStrings(valueNode, "arch")
+			if err != nil {
+				return err
+			}
+			t.Arch = make([]string, 0, len(values))
+			for _, v := range values {
+				t.Arch = append(t.Arch, NormalizeArch(v))
+			}
+		case "private", "internal":
+			if valueNode.Kind != yaml.ScalarNode {
+				return errors.NewYamlError(valueNode, "expected yaml scalar for 'private' field")
+			}
+			private, err := strconv.ParseBool(valueNode.Value)
+			if err != nil {
+				return errors.NewYamlError(valueNode, "expected boolean for 'private' field")
+			}
+			t.Private = private
 		case "hosts":
 			if valueNode.Kind != yaml.SequenceNode {
 				return errors.NewYamlError(valueNode, "expected yaml sequence for 'hosts' field")
@@ -210,3 +280,50 @@ func (t *Task) UnmarshalYAML(value *yaml.Node) error {
 
 	return nil
 }
+
+// Location returns the file:line:column where the task is defined, or an
+// empty string when the task was not read from a file.
+func (t Task) Location() string {
+	if t.File == "" {
+		return ""
+	}
+
+	if t.Line == 0 {
+		return t.File
+	}
+
+	return fmt.Sprintf("%s:%d:%d", t.File, t.Line, t.Column)
+}
+
+// decodeStringOrStrings decodes a scalar or a sequence of scalars.
+func decodeStringOrStrings(node *yaml.Node, field string) ([]string, error) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return []string{node.Value}, nil
+	case yaml.SequenceNode:
+		values := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return nil, errors.YamlErrorf(item, "expected yaml scalar in '%s' list", field)
+			}
+			values = append(values, item.Value)
+		}
+		return values, nil
+	}
+
+	return nil, errors.YamlErrorf(node, "expected yaml scalar or sequence for '%s' field", field)
+}
+
+// decodeRetry reads the number of times a failed task is run again.
+func decodeRetry(node *yaml.Node) (int, error) {
+	if node.Kind != yaml.ScalarNode {
+		return 0, errors.NewYamlError(node, "expected yaml scalar for 'retry' field")
+	}
+
+	retry, err := strconv.Atoi(strings.TrimSpace(node.Value))
+	if err != nil || retry < 0 {
+		return 0, errors.YamlErrorf(node, "expected a non-negative integer for 'retry' field, got '%s'", node.Value)
+	}
+
+	return retry, nil
+}
@@ -3,8 +3,10 @@ package types
 import (
 	"encoding/json"
 	"errors"
+	"slices"
 	"strings"
 
+	castErrors "github.com/frostyeti/cast/internal/errors"
 	"github.com/frostyeti/cast/internal/id"
 	"go.yaml.in/yaml/v4"
 )
@@ -82,10 +84,16 @@ func (t *TaskMap) UnmarshalYAML(node *yaml.Node) error {
 		}
 
 		task.Name = key
+		task.Line = keyNode.Line
+		task.Column = keyNode.Column
 		if task.Id == "" {
 			task.Id = id.Convert(task.Name)
 		}
 
+		if err := t.CheckAliases(&task); err != nil {
+			return castErrors.WithYamlNode(err, keyNode)
+		}
+
 		t.Add(&task)
 	}
 
@@ -183,6 +191,9 @@ func (t *TaskMap) Add(entry *Task) bool {
 	return true
 }
 
+// Get returns the task whose id, name or alias matches name. Ids are matched
+// exactly first and then case-insensitively; names let namespaced module
+// tasks be found as `ns:task` even though their id is `ns-task`.
 func (t *TaskMap) Get(name string) (Task, bool) {
 	if t == nil {
 		t = NewTaskMap()
@@ -204,9 +215,75 @@ func (t *TaskMap) Get(name string) (Task, bool) {
 		}
 	}
 
+	for _, k := range t.keys {
+		entry := t.values[k]
+		if strings.EqualFold(entry.Name, name) {
+			return entry, true
+		}
+	}
+
+	for _, k := range t.keys {
+		entry := t.values[k]
+		for _, alias := range entry.Aliases {
+			if strings.EqualFold(alias, name) {
+				return entry, true
+			}
+		}
+	}
+
 	return Task{}, false
 }
 
+// CheckAliases reports an error when entry's id or aliases collide with the
+// id or aliases of another task in the map.
+func (t *TaskMap) CheckAliases(entry *Task) error {
+	if t == nil || entry == nil {
+		return nil
+	}
+
+	for _, k := range t.keys {
+		other := t.values[k]
+		if strings.EqualFold(other.Id, entry.Id) {
+			continue
+		}
+
+		for _, alias := range entry.Aliases {
+			if strings.EqualFold(alias, other.Id) || strings.EqualFold(alias, other.Name) {
+				return castErrors.Newf("alias %q of task %s collides with task %s", alias, entry.Name, other.Name)
+			}
+			for _, otherAlias := range other.Aliases {
+				if strings.EqualFold(alias, otherAlias) {
+					return castErrors.Newf("alias %q of task %s is already an alias of task %s", alias, entry.Name, other.Name)
+				}
+			}
+		}
+
+		for _, otherAlias := range other.Aliases {
+			if strings.EqualFold(otherAlias, entry.Id) || strings.EqualFold(otherAlias, entry.Name) {
+				return castErrors.Newf("task %s collides with alias %q of task %s", entry.Name, otherAlias, other.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Validate checks every task in the map for alias collisions.
+func (t *TaskMap) Validate() error {
+	if t == nil {
+		return nil
+	}
+
+	for _, k := range t.keys {
+		entry := t.values[k]
+		if err := t.CheckAliases(&entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (t *TaskMap) GetById(idValue string) (Task, bool) {
 	if t == nil {
 		t = NewTaskMap()
@@ -243,6 +320,21 @@ func (t *TaskMap) Set(entry *Task) bool {
 	return true
 }
 
+// SetFile records file as the definition source of every task that does not
+// already have one.
+func (t *TaskMap) SetFile(file string) {
+	if t == nil || t.values == nil {
+		return
+	}
+
+	for k, task := range t.values {
+		if task.File == "" {
+			task.File = file
+			t.values[k] = task
+		}
+	}
+}
+
 func (t *TaskMap) TryGetSlice(key ...string) ([]Task, bool) {
 	if t == nil || t.values == nil {
 		return nil, false
@@ -259,6 +351,56 @@ func (t *TaskMap) TryGetSlice(key ...string) ([]Task, bool) {
 	return results, len(results) > 0
 }
 
+// Resolve returns the task a target runs in the given context. Variants are
+// preferred in this order: target:context:platform, target:context,
+// target:platform and target, where platform is the current os-arch pair, os,
+// or arch, such as build:linux-arm64, build:windows or build:amd64.
+func (t *TaskMap) Resolve(target, context string) (Task, bool) {
+	bases := []string{target}
+	if context != "" {
+		bases = []string{target + ":" + context, target}
+	}
+
+	for _, base := range bases {
+		for _, suffix := range platformSuffixes() {
+			if task, ok := t.Get(base + ":" + suffix); ok && task.SupportsPlatform(platformOS, platformArch) {
+				return task, true
+			}
+		}
+
+		if task, ok := t.Get(base); ok {
+			return task, true
+		}
+	}
+
+	return Task{}, false
+}
+
+// platformVariants returns the names of the platform variants of target, such
+// as build:linux and build:windows, in the given context.
+func (t *TaskMap) platformVariants(target, context string) []string {
+	prefixes := []string{target + ":"}
+	if context != "" {
+		prefixes = []string{target + ":" + context + ":", target + ":"}
+	}
+
+	variants := []string{}
+	for _, key := range t.Keys() {
+		for _, prefix := range prefixes {
+			suffix, ok := strings.CutPrefix(key, prefix)
+			if !ok {
+				continue
+			}
+			if _, _, ok := parsePlatformSuffix(suffix); ok {
+				variants = append(variants, key)
+			}
+		}
+	}
+	slices.Sort(variants)
+
+	return variants
+}
+
 func (t *TaskMap) FlattenTasks(targets []string, context string) ([]Task, error) {
 	return FlattenTasks(targets, *t, []Task{}, context)
 }
@@ -266,26 +408,12 @@ func (t *TaskMap) FlattenTasks(targets []string, context string) ([]Task, error)
 func FlattenTasks(targets []string, tasks TaskMap, set []Task, context string) ([]Task, error) {
 
 	for _, target := range targets {
-		var task Task
-		found := false
-
-		// prefer context-specific task if context is provided and it is found.
-		if context != "" {
-			t := target + ":" + context
-			task2, ok := tasks.Get(t)
-			if ok {
-				task = task2
-				found = true
-			}
-		}
-
-		if !found {
-			task2, ok := tasks.Get(target)
-			if !ok {
-				return nil, errors.New("Task not found: " + target + " or " + target)
+		task, ok := tasks.Resolve(target, context)
+		if !ok {
+			if variants := tasks.platformVariants(target, context); len(variants) > 0 {
+				return nil, castErrors.Newf("no variant of %s for %s/%s; found %s", target, platformOS, platformArch, strings.Join(variants, ", "))
 			}
-
-			task = task2
+			return nil, errors.New("Task not found: " + target)
 		}
 
 		// ensure dependencies are added first
@@ -20,3 +20,112 @@ func TestTaskNeedsAcceptsStringAndObject(t *testing.T) {
 	require.Equal(t, "test", taskList.Needs[1].Id)
 	require.True(t, taskList.Needs[1].Parallel)
 }
+
+func TestTaskVarsKeepTypesAndOrder(t *testing.T) {
+	var task Task
+	require.NoError(t, yaml.Unmarshal([]byte("vars:\n  regions: [a, b]\n  count: 2\n  opts:\n    debug: true\n"), &task))
+	require.Equal(t, []string{"regions", "count", "opts"}, task.Vars.Keys())
+
+	regions, _ := task.Vars.Get("regions")
+	require.Equal(t, []any{"a", "b"}, regions)
+	count, _ := task.Vars.Get("count")
+	require.Equal(t, 2, count)
+	opts, _ := task.Vars.Get("opts")
+	require.Equal(t, map[string]any{"debug": true}, opts)
+}
+
+func TestTaskMapResolvesAliases(t *testing.T) {
+	var project Project
+	require.NoError(t, yaml.Unmarshal([]byte("tasks:\n  build:\n    aliases: [b, bld]\n    run: go build\n  helper:\n    private: true\n    run: echo help\n"), &project))
+
+	task, ok := project.Tasks.Get("BLD")
+	require.True(t, ok)
+	require.Equal(t, "build", task.Name)
+
+	helper, ok := project.Tasks.Get("helper")
+	require.True(t, ok)
+	require.True(t, helper.Private)
+}
+
+func TestTaskMapRejectsAliasCollisions(t *testing.T) {
+	var project Project
+	err := yaml.Unmarshal([]byte("tasks:\n  build:\n    aliases: [b]\n    run: go build\n  bench:\n    aliases: b\n    run: go test -bench .\n"), &project)
+	require.ErrorContains(t, err, `alias "b" of task bench is already an alias of task build on line 5, column 3`)
+
+	err = yaml.Unmarshal([]byte("tasks:\n  build:\n    aliases: [test]\n    run: go build\n  test:\n    run: go test\n"), &project)
+	require.ErrorContains(t, err, `task test collides with alias "test" of task build`)
+}
+
+func TestTaskMapResolvesPlatformVariants(t *testing.T) {
+	oldOS, oldArch := platformOS, platformArch
+	platformOS, platformArch = "windows", "arm64"
+	defer func() { platformOS, platformArch = oldOS, oldArch }()
+
+	var project Project
+	require.NoError(t, yaml.Unmarshal([]byte(`tasks:
+  build: make
+  build:linux: make linux
+  build:windows: make windows
+  build:windows-arm64:
+    arch: [x64]
+    run: make windows-x64
+  build:prod: make prod
+  build:prod:windows: make prod-windows
+`), &project))
+
+	task, ok := project.Tasks.Resolve("build", "")
+	require.True(t, ok)
+	require.Equal(t, "build:windows", task.Name, "variants whose selectors do not match are ignored")
+
+	task, ok = project.Tasks.Resolve("build", "prod")
+	require.True(t, ok)
+	require.Equal(t, "build:prod:windows", task.Name)
+
+	platformOS = "darwin"
+	task, ok = project.Tasks.Resolve("build", "")
+	require.True(t, ok)
+	require.Equal(t, "build", task.Name)
+
+	task, ok = project.Tasks.Resolve("build", "prod")
+	require.True(t, ok)
+	require.Equal(t, "build:prod", task.Name)
+}
+
+func TestTaskMapReportsMissingPlatformVariant(t *testing.T) {
+	oldOS, oldArch := platformOS, platformArch
+	platformOS, platformArch = "darwin", "arm64"
+	defer func() { platformOS, platformArch = oldOS, oldArch }()
+
+	var project Project
+	require.NoError(t, yaml.Unmarshal([]byte(`tasks:
+  build:linux: make linux
+  build:windows: make windows
+  test:js: npm test
+`), &project))
+
+	_, err := project.Tasks.FlattenTasks([]string{"build"}, "")
+	require.EqualError(t, err, "no variant of build for darwin/arm64; found build:linux, build:windows")
+
+	_, err = project.Tasks.FlattenTasks([]string{"deploy"}, "")
+	require.EqualError(t, err, "Task not found: deploy")
+
+	task, ok := project.Tasks.Resolve("build:windows", "")
+	require.True(t, ok)
+	require.False(t, task.SupportsPlatform(platformOS, platformArch), "a variant invoked directly keeps the platform its name selects")
+	require.Equal(t, "os windows", task.PlatformRequirement())
+
+	task, ok = project.Tasks.Resolve("test:js", "")
+	require.True(t, ok)
+	require.True(t, task.SupportsPlatform(platformOS, platformArch))
+}
+
+func TestTaskPlatformSelectorsNormalizeAliases(t *testing.T) {
+	var task Task
+	require.NoError(t, yaml.Unmarshal([]byte("os: [macos, win]\narch: x86_64\nrun: echo\n"), &task))
+	require.Equal(t, []string{"darwin", "windows"}, task.OS)
+	require.Equal(t, []string{"amd64"}, task.Arch)
+	require.True(t, task.SupportsPlatform("darwin", "amd64"))
+	require.False(t, task.SupportsPlatform("linux", "amd64"))
+	require.False(t, task.SupportsPlatform("windows", "arm64"))
+	require.Equal(t, "os darwin, windows; arch amd64", task.PlatformRequirement())
+}
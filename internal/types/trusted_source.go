@@ -0,0 +1,63 @@
+package types
+
+import (
+	"strings"
+
+	"github.com/frostyeti/cast/internal/errors"
+	"go.yaml.in/yaml/v4"
+)
+
+// TrustedSource is a trusted_sources entry. A scalar value is shorthand for
+// `source`. Remote refs that match Source must carry a signature made by one
+// of Keys when any are listed.
+type TrustedSource struct {
+	Source string   `yaml:"source" json:"source"`
+	Keys   []string `yaml:"keys,omitempty" json:"keys,omitempty"`
+}
+
+func (t *TrustedSource) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		t.Source = strings.TrimSpace(node.Value)
+		return nil
+	}
+
+	if node.Kind != yaml.MappingNode {
+		return errors.NewYamlError(node, "trusted source must be a scalar or mapping node.")
+	}
+
+	for i := 0; i < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		valueNode := node.Content[i+1]
+
+		switch keyNode.Value {
+		case "source", "pattern":
+			if valueNode.Kind != yaml.ScalarNode {
+				return errors.NewYamlError(valueNode, "trusted source source must be a scalar.")
+			}
+			t.Source = strings.TrimSpace(valueNode.Value)
+		case "keys", "key":
+			switch valueNode.Kind {
+			case yaml.ScalarNode:
+				t.Keys = append(t.Keys, strings.TrimSpace(valueNode.Value))
+			case yaml.SequenceNode:
+				for _, item := range valueNode.Content {
+					if item.Kind != yaml.ScalarNode {
+						return errors.NewYamlError(item, "trusted source keys must be scalars.")
+					}
+					t.Keys = append(t.Keys, strings.TrimSpace(item.Value))
+				}
+			default:
+				return errors.NewYamlError(valueNode, "trusted source keys must be a scalar or sequence.")
+			}
+		default:
+			// a misspelled `keys` would silently turn signature checks off.
+			return errors.YamlErrorf(keyNode, "unexpected field '%s' in trusted source", keyNode.Value)
+		}
+	}
+
+	if t.Source == "" {
+		return errors.NewYamlError(node, "trusted source requires a source pattern.")
+	}
+
+	return nil
+}
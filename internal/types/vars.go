@@ -0,0 +1,129 @@
+package types
+
+import (
+	"encoding/json"
+	"maps"
+
+	"github.com/frostyeti/cast/internal/errors"
+	"go.yaml.in/yaml/v4"
+)
+
+// Vars stores typed values such as lists, maps and numbers that are exposed
+// to expressions and templates as `vars.*`. Unlike env, vars are never
+// exported to the process environment. Values are kept in declaration order
+// so later vars can reference earlier ones.
+type Vars struct {
+	values map[string]any
+	keys   []string
+}
+
+// NewVars returns an initialized vars map.
+func NewVars() *Vars {
+	return &Vars{
+		values: map[string]any{},
+		keys:   []string{},
+	}
+}
+
+func (v *Vars) MarshalJSON() ([]byte, error) {
+	if v == nil || len(v.values) == 0 {
+		return json.Marshal(nil)
+	}
+	return json.Marshal(v.values)
+}
+
+func (v *Vars) MarshalYAML() (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return v.values, nil
+}
+
+func (v *Vars) UnmarshalYAML(node *yaml.Node) error {
+	if v == nil {
+		v = NewVars()
+	}
+
+	if v.values == nil {
+		v.values = map[string]any{}
+	}
+
+	if node.Kind != yaml.MappingNode {
+		return errors.NewYamlError(node, "expected yaml mapping for 'vars' field")
+	}
+
+	for i := 0; i < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		valueNode := node.Content[i+1]
+
+		var val any
+		if err := valueNode.Decode(&val); err != nil {
+			return errors.NewYamlError(valueNode, "failed to decode 'vars' field value: "+err.Error())
+		}
+
+		v.Set(keyNode.Value, val)
+	}
+
+	return nil
+}
+
+func (v *Vars) Len() int {
+	if v == nil {
+		return 0
+	}
+	return len(v.keys)
+}
+
+func (v *Vars) Keys() []string {
+	if v == nil {
+		return []string{}
+	}
+	return append([]string{}, v.keys...)
+}
+
+func (v *Vars) Get(key string) (any, bool) {
+	if v == nil {
+		return nil, false
+	}
+	val, ok := v.values[key]
+	return val, ok
+}
+
+func (v *Vars) Set(key string, value any) {
+	if _, exists := v.values[key]; !exists {
+		v.keys = append(v.keys, key)
+	}
+	v.values[key] = value
+}
+
+func (v *Vars) ToMap() map[string]any {
+	m := map[string]any{}
+	if v != nil {
+		maps.Copy(m, v.values)
+	}
+	return m
+}
+
+// Clone returns a copy of the vars map. Nested lists and maps are shared.
+func (v *Vars) Clone() *Vars {
+	clone := NewVars()
+	if v == nil {
+		return clone
+	}
+
+	maps.Copy(clone.values, v.values)
+	clone.keys = append(clone.keys, v.keys...)
+	return clone
+}
+
+// Merge sets every value of other on v, keeping the declaration order of keys
+// v already has.
+func (v *Vars) Merge(other *Vars) {
+	if other == nil {
+		return
+	}
+
+	for _, k := range other.keys {
+		v.Set(k, other.values[k])
+	}
+}
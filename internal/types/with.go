@@ -21,10 +21,18 @@ func (w *With) MarshalJSON() ([]byte, error) {
 	return json.Marshal(w.values)
 }
 
+func (w *With) MarshalYAML() (interface{}, error) {
+	if w == nil {
+		return nil, nil
+	}
+	return w.values, nil
+}
+
 func (w *With) UnmarshalYAML(value *yaml.Node) error {
 	if w == nil {
 		w = NewWith()
 	}
+	w.init()
 
 	if value.Kind != yaml.MappingNode {
 		return errors.NewYamlError(value, "expected yaml mapping for 'with' field")